@@ -5,16 +5,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/Azure/azure-provider-external-dns-e2e/logger"
@@ -61,6 +68,21 @@ var PrivateClusterOpt = McOpt{
 	},
 }
 
+// WorkloadIdentityOpt enables the oidc issuer so identities can federate with service accounts of the cluster
+var WorkloadIdentityOpt = McOpt{
+	Name: "workload identity",
+	fn: func(mc *armcontainerservice.ManagedCluster) error {
+		if mc.Properties == nil {
+			mc.Properties = &armcontainerservice.ManagedClusterProperties{}
+		}
+
+		mc.Properties.OidcIssuerProfile = &armcontainerservice.ManagedClusterOIDCIssuerProfile{
+			Enabled: to.Ptr(true),
+		}
+		return nil
+	},
+}
+
 // Retrieves objects from infastructure file to create aks instance
 func LoadAks(id azure.Resource, dnsServiceIp, location, principalId, clientId string, options map[string]struct{}) *aks {
 	return &aks{
@@ -76,6 +98,41 @@ func LoadAks(id azure.Resource, dnsServiceIp, location, principalId, clientId st
 	}
 }
 
+// Loads an existing cluster that wasn't created by NewAks, looking up the properties LoadAks needs
+func LoadExistingAks(ctx context.Context, id azure.Resource) (*aks, error) {
+	lgr := logger.FromContext(ctx).With("name", id.ResourceName, "resourceGroup", id.ResourceGroup)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to load existing aks")
+	defer lgr.Info("finished loading existing aks")
+
+	mc, err := LoadAks(id, "", "", "", "", nil).GetCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if mc.Location == nil {
+		return nil, fmt.Errorf("managed cluster location is nil")
+	}
+	if mc.Properties == nil || mc.Properties.NetworkProfile == nil || mc.Properties.NetworkProfile.DNSServiceIP == nil {
+		return nil, fmt.Errorf("dns service ip is nil")
+	}
+
+	identity, ok := mc.Properties.IdentityProfile["kubeletidentity"]
+	if !ok || identity.ObjectID == nil || identity.ClientID == nil {
+		return nil, fmt.Errorf("kubelet identity not found")
+	}
+
+	options := make(map[string]struct{})
+	if access := mc.Properties.APIServerAccessProfile; access != nil && access.EnablePrivateCluster != nil && *access.EnablePrivateCluster {
+		options[PrivateClusterOpt.Name] = struct{}{}
+	}
+	if oidc := mc.Properties.OidcIssuerProfile; oidc != nil && oidc.Enabled != nil && *oidc.Enabled {
+		options[WorkloadIdentityOpt.Name] = struct{}{}
+	}
+
+	return LoadAks(id, *mc.Properties.NetworkProfile.DNSServiceIP, *mc.Location, *identity.ObjectID, *identity.ClientID, options), nil
+}
+
 // Creates a new public or private cluster based on mcOpt provided, saves properties in aks struct
 func NewAks(ctx context.Context, subscriptionId, resourceGroup, name, location string, subnetId string, mcOpts ...McOpt) (*aks, error) {
 	lgr := logger.FromContext(ctx).With("name", name, "resourceGroup", resourceGroup, "location", location)
@@ -88,7 +145,7 @@ func NewAks(ctx context.Context, subscriptionId, resourceGroup, name, location s
 		return nil, fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	factory, err := armcontainerservice.NewClientFactory(subscriptionId, cred, nil)
+	factory, err := armcontainerservice.NewClientFactory(subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("creating aks client factory: %w", err)
 	}
@@ -203,7 +260,7 @@ func (a *aks) Deploy(ctx context.Context, objs []client.Object) error {
 	}
 	fi.Write(zip)
 
-	if err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+	if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
 		Command: to.Ptr("kubectl apply -f manifests/"),
 		Context: &encoded,
 	}, runCommandOpts{}); err != nil {
@@ -265,14 +322,14 @@ func (a *aks) waitStable(ctx context.Context, objs []client.Object) error {
 				switch {
 				case slices.Contains(workloadKinds, kind):
 					lgr.Info("checking rollout status")
-					if err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+					if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
 						Command: to.Ptr(fmt.Sprintf("kubectl rollout status %s/%s -n %s", kind, obj.GetName(), ns)),
 					}, runCommandOpts{}); err != nil {
 						return fmt.Errorf("waiting for %s/%s to be stable: %w", kind, obj.GetName(), err)
 					}
 				case kind == "Pod":
 					lgr.Info("waiting for pod to be ready")
-					if err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+					if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
 						Command: to.Ptr(fmt.Sprintf("kubectl wait --for=condition=Ready pod/%s -n %s", obj.GetName(), ns)),
 					}, runCommandOpts{}); err != nil {
 						return fmt.Errorf("waiting for pod/%s to be stable: %w", obj.GetName(), err)
@@ -287,7 +344,7 @@ func (a *aks) waitStable(ctx context.Context, objs []client.Object) error {
 
 					getLogsFn := func() error { // right now this just dumps all logs on the pod, if we eventually have more logs
 						// than can be stored we will need to "stream" this by using the --since-time flag
-						if err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+						if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
 							Command: to.Ptr(fmt.Sprintf("kubectl logs job/%s -n %s", obj.GetName(), ns)),
 						}, runCommandOpts{
 							outputFile: outputFile,
@@ -301,7 +358,7 @@ func (a *aks) waitStable(ctx context.Context, objs []client.Object) error {
 					// invoke command jobs are supposed to be short-lived, so we have to constantly poll for completion
 					for {
 						// check if job is complete
-						if err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+						if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
 							Command: to.Ptr(fmt.Sprintf("kubectl wait --for=condition=complete --timeout=5s job/%s -n %s", obj.GetName(), ns)),
 						}, runCommandOpts{}); err == nil {
 
@@ -317,7 +374,7 @@ func (a *aks) waitStable(ctx context.Context, objs []client.Object) error {
 						}
 
 						// check if job is failed
-						if err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+						if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
 							Command: to.Ptr(fmt.Sprintf("kubectl wait --for=condition=failed --timeout=5s job/%s -n %s", obj.GetName(), ns)),
 						}, runCommandOpts{}); err == nil {
 
@@ -351,8 +408,8 @@ type runCommandOpts struct {
 	outputFile string
 }
 
-// Runs given request on the cluster, writes logs to file if outputFile is specified via runCommandOpts
-func (a *aks) runCommand(ctx context.Context, request armcontainerservice.RunCommandRequest, opt runCommandOpts) error {
+// Runs given request on the cluster and returns the command output, writes logs to file if outputFile is specified via runCommandOpts
+func (a *aks) runCommand(ctx context.Context, request armcontainerservice.RunCommandRequest, opt runCommandOpts) (string, error) {
 	lgr := logger.FromContext(ctx).With("name", a.name, "resourceGroup", a.resourceGroup, "command", *request.Command)
 	ctx = logger.WithContext(ctx, lgr)
 	lgr.Info("starting to run command")
@@ -360,22 +417,22 @@ func (a *aks) runCommand(ctx context.Context, request armcontainerservice.RunCom
 
 	cred, err := GetAzCred()
 	if err != nil {
-		return fmt.Errorf("getting az credentials: %w", err)
+		return "", fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	client, err := armcontainerservice.NewManagedClustersClient(a.subscriptionId, cred, nil)
+	client, err := armcontainerservice.NewManagedClustersClient(a.subscriptionId, cred, GetClientOptions())
 	if err != nil {
-		return fmt.Errorf("creating aks client: %w", err)
+		return "", fmt.Errorf("creating aks client: %w", err)
 	}
 
 	poller, err := client.BeginRunCommand(ctx, a.resourceGroup, a.name, request, nil)
 	if err != nil {
-		return fmt.Errorf("starting run command: %w", err)
+		return "", fmt.Errorf("starting run command: %w", err)
 	}
 
 	result, err := poller.PollUntilDone(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("running command: %w", err)
+		return "", fmt.Errorf("running command: %w", err)
 	}
 
 	logs := ""
@@ -385,13 +442,13 @@ func (a *aks) runCommand(ctx context.Context, request armcontainerservice.RunCom
 	if opt.outputFile != "" {
 		outputFile, err := os.OpenFile(opt.outputFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 		if err != nil {
-			return fmt.Errorf("creating output file %s: %w", opt.outputFile, err)
+			return "", fmt.Errorf("creating output file %s: %w", opt.outputFile, err)
 		}
 		defer outputFile.Close()
 
 		_, err = outputFile.WriteString(logs)
 		if err != nil {
-			return fmt.Errorf("writing output file %s: %w", opt.outputFile, err)
+			return "", fmt.Errorf("writing output file %s: %w", opt.outputFile, err)
 		}
 	} else {
 		lgr.Info("command output: " + logs)
@@ -399,10 +456,10 @@ func (a *aks) runCommand(ctx context.Context, request armcontainerservice.RunCom
 
 	if *result.Properties.ExitCode != 0 {
 		lgr.Info(fmt.Sprintf("command failed with exit code %d", *result.Properties.ExitCode))
-		return nonZeroExitCode
+		return logs, nonZeroExitCode
 	}
 
-	return nil
+	return logs, nil
 }
 
 // Returns the provisioned aks cluster
@@ -417,7 +474,7 @@ func (a *aks) GetCluster(ctx context.Context) (*armcontainerservice.ManagedClust
 		return nil, fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	client, err := armcontainerservice.NewManagedClustersClient(a.subscriptionId, cred, nil)
+	client, err := armcontainerservice.NewManagedClustersClient(a.subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("creating aks client: %w", err)
 	}
@@ -430,6 +487,20 @@ func (a *aks) GetCluster(ctx context.Context) (*armcontainerservice.ManagedClust
 	return &result.ManagedCluster, nil
 }
 
+// Returns the url of the cluster's oidc issuer, which WorkloadIdentityOpt enables
+func (a *aks) GetOidcIssuerUrl(ctx context.Context) (string, error) {
+	mc, err := a.GetCluster(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if mc.Properties == nil || mc.Properties.OidcIssuerProfile == nil || mc.Properties.OidcIssuerProfile.IssuerURL == nil {
+		return "", fmt.Errorf("oidc issuer url is nil, is the oidc issuer enabled")
+	}
+
+	return *mc.Properties.OidcIssuerProfile.IssuerURL, nil
+}
+
 func (a *aks) GetVnetId(ctx context.Context) (string, error) {
 	lgr := logger.FromContext(ctx).With("name", a.name, "resourceGroup", a.resourceGroup)
 	ctx = logger.WithContext(ctx, lgr)
@@ -441,7 +512,7 @@ func (a *aks) GetVnetId(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	client, err := armnetwork.NewVirtualNetworksClient(a.subscriptionId, cred, nil)
+	client, err := armnetwork.NewVirtualNetworksClient(a.subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return "", fmt.Errorf("creating network client: %w", err)
 	}
@@ -465,6 +536,277 @@ func (a *aks) GetVnetId(ctx context.Context) (string, error) {
 	return *vnets[0].ID, nil
 }
 
+// Assigns the user-assigned identity to every node pool scale set alongside the kubelet identity, so pods on the nodes
+// can get its tokens from imds by its client id. AKS doesn't manage identities added to its scale sets directly, so
+// node pool upgrades, reimages and scale sets created afterwards don't have it, see ScaleSetsWithoutIdentity
+func (a *aks) AssignNodeIdentity(ctx context.Context, identityId string) error {
+	lgr := logger.FromContext(ctx).With("name", a.name, "resourceGroup", a.resourceGroup, "identity", identityId)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to assign node identity")
+	defer lgr.Info("finished assigning node identity")
+
+	client, nodeResourceGroup, scaleSets, err := a.listScaleSets(ctx)
+	if err != nil {
+		return err
+	}
+	if len(scaleSets) == 0 {
+		return fmt.Errorf("no scale sets found in %s", nodeResourceGroup)
+	}
+
+	for _, scaleSet := range scaleSets {
+		// the kubelet identity is already assigned, the update replaces the identities so the existing ones are kept
+		identity := &armcompute.VirtualMachineScaleSetIdentity{
+			Type:                   to.Ptr(armcompute.ResourceIdentityTypeUserAssigned),
+			UserAssignedIdentities: map[string]*armcompute.UserAssignedIdentitiesValue{identityId: {}},
+		}
+		if existing := scaleSet.Identity; existing != nil {
+			for id := range existing.UserAssignedIdentities {
+				identity.UserAssignedIdentities[id] = &armcompute.UserAssignedIdentitiesValue{}
+			}
+			if existing.Type != nil && strings.Contains(string(*existing.Type), string(armcompute.ResourceIdentityTypeSystemAssigned)) {
+				identity.Type = to.Ptr(armcompute.ResourceIdentityTypeSystemAssignedUserAssigned)
+			}
+		}
+
+		poll, err := client.BeginUpdate(ctx, nodeResourceGroup, *scaleSet.Name, armcompute.VirtualMachineScaleSetUpdate{Identity: identity}, nil)
+		if err != nil {
+			return fmt.Errorf("starting to update scale set %s: %w", *scaleSet.Name, err)
+		}
+		if _, err := pollWithLog(ctx, poll, "still assigning identity to scale set "+*scaleSet.Name); err != nil {
+			return fmt.Errorf("updating scale set %s: %w", *scaleSet.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Returns the names of the node pool scale sets the user-assigned identity isn't assigned to, like ones AKS recreated
+// or added since AssignNodeIdentity
+func (a *aks) ScaleSetsWithoutIdentity(ctx context.Context, identityId string) ([]string, error) {
+	_, _, scaleSets, err := a.listScaleSets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, scaleSet := range scaleSets {
+		assigned := false
+		if scaleSet.Identity != nil {
+			for id := range scaleSet.Identity.UserAssignedIdentities {
+				if strings.EqualFold(id, identityId) {
+					assigned = true
+				}
+			}
+		}
+		if !assigned {
+			missing = append(missing, *scaleSet.Name)
+		}
+	}
+
+	return missing, nil
+}
+
+// listScaleSets returns a scale set client and every scale set in the node resource group of the cluster
+func (a *aks) listScaleSets(ctx context.Context) (*armcompute.VirtualMachineScaleSetsClient, string, []*armcompute.VirtualMachineScaleSet, error) {
+	cred, err := GetAzCred()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	client, err := armcompute.NewVirtualMachineScaleSetsClient(a.subscriptionId, cred, GetClientOptions())
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("creating scale set client: %w", err)
+	}
+
+	cluster, err := a.GetCluster(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("getting cluster: %w", err)
+	}
+	nodeResourceGroup := *cluster.Properties.NodeResourceGroup
+
+	var scaleSets []*armcompute.VirtualMachineScaleSet
+	pager := client.NewListPager(nodeResourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("listing scale sets: %w", err)
+		}
+		scaleSets = append(scaleSets, page.Value...)
+	}
+
+	return client, nodeResourceGroup, scaleSets, nil
+}
+
+// Returns the service with the given name and namespace
+func (a *aks) GetService(ctx context.Context, namespace, name string) (*corev1.Service, error) {
+	svc := &corev1.Service{}
+	if err := a.getObject(ctx, "service", namespace, name, svc); err != nil {
+		return nil, err
+	}
+
+	return svc, nil
+}
+
+// Returns the deployment with the given name and namespace
+func (a *aks) GetDeployment(ctx context.Context, namespace, name string) (*appsv1.Deployment, error) {
+	deploy := &appsv1.Deployment{}
+	if err := a.getObject(ctx, "deployment", namespace, name, deploy); err != nil {
+		return nil, err
+	}
+
+	return deploy, nil
+}
+
+// Adds or overwrites annotations on a service in a single kubectl call
+func (a *aks) AnnotateService(ctx context.Context, namespace, name string, annotations map[string]string) error {
+	lgr := logger.FromContext(ctx).With("name", a.name, "resourceGroup", a.resourceGroup, "service", name)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to annotate service")
+	defer lgr.Info("finished annotating service")
+
+	pairs := make([]string, 0, len(annotations))
+	for key, value := range annotations {
+		pairs = append(pairs, shellQuote(key+"="+value))
+	}
+	sort.Strings(pairs)
+
+	cmd := fmt.Sprintf("kubectl annotate service --overwrite %s %s%s", name, strings.Join(pairs, " "), namespaceFlag(namespace))
+	if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+		Command: to.Ptr(cmd),
+	}, runCommandOpts{}); err != nil {
+		return fmt.Errorf("annotating service %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Removes the given annotation keys from a service in a single kubectl call
+func (a *aks) RemoveServiceAnnotations(ctx context.Context, namespace, name string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	lgr := logger.FromContext(ctx).With("name", a.name, "resourceGroup", a.resourceGroup, "service", name)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to remove service annotations")
+	defer lgr.Info("finished removing service annotations")
+
+	removals := make([]string, len(keys))
+	for i, key := range keys {
+		removals[i] = shellQuote(key + "-")
+	}
+
+	cmd := fmt.Sprintf("kubectl annotate service %s %s%s", name, strings.Join(removals, " "), namespaceFlag(namespace))
+	if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+		Command: to.Ptr(cmd),
+	}, runCommandOpts{}); err != nil {
+		return fmt.Errorf("removing annotations from service %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Deletes an object, a missing object isn't an error
+func (a *aks) Delete(ctx context.Context, obj client.Object) error {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	lgr := logger.FromContext(ctx).With("name", a.name, "resourceGroup", a.resourceGroup, "kind", kind, "object", obj.GetName())
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to delete object")
+	defer lgr.Info("finished deleting object")
+
+	cmd := fmt.Sprintf("kubectl delete %s %s%s --ignore-not-found", strings.ToLower(kind), obj.GetName(), namespaceFlag(obj.GetNamespace()))
+	if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+		Command: to.Ptr(cmd),
+	}, runCommandOpts{}); err != nil {
+		return fmt.Errorf("deleting %s/%s: %w", kind, obj.GetName(), err)
+	}
+
+	return nil
+}
+
+// Deletes every object of the kind of obj in its namespace with all of the given labels in a single command
+func (a *aks) DeleteAllOf(ctx context.Context, obj client.Object, matchLabels map[string]string) error {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	selector := labels.SelectorFromSet(matchLabels).String()
+	lgr := logger.FromContext(ctx).With("name", a.name, "resourceGroup", a.resourceGroup, "kind", kind, "selector", selector)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to delete objects")
+	defer lgr.Info("finished deleting objects")
+
+	cmd := fmt.Sprintf("kubectl delete %s%s -l %s --ignore-not-found", strings.ToLower(kind), namespaceFlag(obj.GetNamespace()), shellQuote(selector))
+	if _, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+		Command: to.Ptr(cmd),
+	}, runCommandOpts{}); err != nil {
+		return fmt.Errorf("deleting %s with labels %s: %w", kind, selector, err)
+	}
+
+	return nil
+}
+
+// Returns whether an object exists
+func (a *aks) Exists(ctx context.Context, obj client.Object) (bool, error) {
+	kind := strings.ToLower(obj.GetObjectKind().GroupVersionKind().Kind)
+	cmd := fmt.Sprintf("kubectl get %s %s --ignore-not-found -o name%s", kind, obj.GetName(), namespaceFlag(obj.GetNamespace()))
+
+	out, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+		Command: to.Ptr(cmd),
+	}, runCommandOpts{})
+	if err != nil {
+		return false, fmt.Errorf("getting %s/%s: %w", kind, obj.GetName(), err)
+	}
+
+	return strings.TrimSpace(out) != "", nil
+}
+
+// getObject reads an object with kubectl get and unmarshals the json output into obj
+func (a *aks) getObject(ctx context.Context, kind, namespace, name string, obj any) error {
+	out, err := a.runCommand(ctx, armcontainerservice.RunCommandRequest{
+		Command: to.Ptr(fmt.Sprintf("kubectl get %s %s%s -o json", kind, name, namespaceFlag(namespace))),
+	}, runCommandOpts{})
+	if err != nil {
+		return fmt.Errorf("getting %s/%s: %w", kind, name, err)
+	}
+
+	if err := unmarshalOutput(out, obj); err != nil {
+		return fmt.Errorf("unmarshalling %s/%s: %w", kind, name, err)
+	}
+
+	return nil
+}
+
+// unmarshalOutput unmarshals the json object printed by a command into obj. Run command logs hold stderr alongside
+// stdout, so warnings kubectl prints around the object are skipped: decoding starts at the first line opening an object
+// and stops at its end
+func unmarshalOutput(out string, obj any) error {
+	start := 0
+	if !strings.HasPrefix(out, "{") {
+		i := strings.Index(out, "\n{")
+		if i < 0 {
+			return errors.New("output has no json object")
+		}
+		start = i + 1
+	}
+
+	return json.NewDecoder(strings.NewReader(out[start:])).Decode(obj)
+}
+
+// shellQuote quotes s as a single argument of the shell run commands are run in
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// namespaceFlag returns the kubectl flag selecting namespace, nothing for cluster scoped objects without one
+func namespaceFlag(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	return " -n " + namespace
+}
+
+func (a *aks) GetName() string {
+	return a.name
+}
+
 func (a *aks) GetId() string {
 	return a.id
 }
@@ -0,0 +1,69 @@
+package clients
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	values := []string{
+		"external-dns.alpha.kubernetes.io/hostname=www.example.com",
+		"external-dns.alpha.kubernetes.io/target=it's here",
+		"'quoted' $HOME `pwd` \"double\"; rm -rf /",
+		"",
+	}
+
+	for _, value := range values {
+		// the shell has to hand the quoted value back unchanged
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(value)).Output()
+		if err != nil {
+			t.Fatalf("running shell with %q: %s", value, err)
+		}
+		if string(out) != value {
+			t.Errorf("shell read %q, expected %q", out, value)
+		}
+	}
+}
+
+func TestNamespaceFlag(t *testing.T) {
+	if got := namespaceFlag("kube-system"); got != " -n kube-system" {
+		t.Errorf("namespace flag is %q", got)
+	}
+	if got := namespaceFlag(""); got != "" {
+		t.Errorf("namespace flag without a namespace is %q, expected none", got)
+	}
+}
+
+func TestUnmarshalOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		wantErr bool
+	}{
+		{name: "object", out: "{\"kind\": \"Service\"}\n"},
+		{name: "warning before", out: "Warning: v1 ComponentStatus is deprecated\n{\n  \"kind\": \"Service\"\n}\n"},
+		{name: "stderr after", out: "{\"kind\": \"Service\"}\nE0101 00:00:00.000000 memcache.go:287] couldn't get resource list\n"},
+		{name: "no object", out: "Error from server (NotFound): services \"nginx\" not found\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obj struct {
+				Kind string `json:"kind"`
+			}
+			err := unmarshalOutput(tt.out, &obj)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("unmarshalled %+v, expected an error", obj)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unmarshalling: %s", err)
+			}
+			if obj.Kind != "Service" {
+				t.Errorf("kind is %q, expected Service", obj.Kind)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package clients
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
@@ -50,7 +52,7 @@ func NewRoleAssignment(ctx context.Context, subscriptionId, scope, principalId s
 		return nil, fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	client, err := armauthorization.NewRoleAssignmentsClient(subscriptionId, cred, nil)
+	client, err := armauthorization.NewRoleAssignmentsClient(subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("creating client: %w", err)
 	}
@@ -67,3 +69,44 @@ func NewRoleAssignment(ctx context.Context, subscriptionId, scope, principalId s
 
 	return &roleAssignment{}, nil
 }
+
+// Returns whether principalId has role on scope, either assigned on scope itself or inherited from a parent scope
+func HasRoleAssignment(ctx context.Context, subscriptionId, scope, principalId string, role Role) (bool, error) {
+	lgr := logger.FromContext(ctx).With("role", role.Name, "subscriptionId", subscriptionId, "scope", scope, "principalId", principalId)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to check role assignment")
+	defer lgr.Info("finished checking role assignment")
+
+	cred, err := GetAzCred()
+	if err != nil {
+		return false, fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	client, err := armauthorization.NewRoleAssignmentsClient(subscriptionId, cred, GetClientOptions())
+	if err != nil {
+		return false, fmt.Errorf("creating client: %w", err)
+	}
+
+	// role definition ids are compared by their guid since assignments may reference the definition through another subscription
+	roleGuid := strings.ToLower(path.Base(role.Id))
+	pager := client.NewListForScopePager(scope, &armauthorization.RoleAssignmentsClientListForScopeOptions{
+		Filter: to.Ptr(fmt.Sprintf("atScope() and assignedTo('%s')", principalId)),
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return false, fmt.Errorf("listing role assignments: %w", err)
+		}
+
+		for _, ra := range page.Value {
+			if ra.Properties == nil || ra.Properties.RoleDefinitionID == nil {
+				continue
+			}
+			if strings.ToLower(path.Base(*ra.Properties.RoleDefinitionID)) == roleGuid {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
@@ -1,13 +1,20 @@
 package clients
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 )
 
-var cred azcore.TokenCredential
+var (
+	cred       azcore.TokenCredential
+	clientOpts *arm.ClientOptions
+)
 
 // Uses NewAzureCLICredential, returns creds used to provision all infrastructure and create clients used in tests
 func GetAzCred() (azcore.TokenCredential, error) {
@@ -25,3 +32,36 @@ func GetAzCred() (azcore.TokenCredential, error) {
 	cred = c
 	return cred, nil
 }
+
+// GetClientOptions returns the options every arm client should be created with. This is nil, the sdk defaults, unless UseFakeDns was called
+func GetClientOptions() *arm.ClientOptions {
+	return clientOpts
+}
+
+// UseFakeDns points every arm client created by the clients and tests packages at the given in-process fake
+// instead of Azure, and replaces the az cli credential with a static one. Must be called before any client is created
+func UseFakeDns(f *FakeDns) {
+	cred = fakeCred{}
+	clientOpts = &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Transport: f,
+		},
+		DisableRPRegistration: true,
+	}
+}
+
+// UseAzureDns undoes UseFakeDns, arm clients created afterwards talk to Azure with the az cli credential again
+func UseAzureDns() {
+	cred = nil
+	clientOpts = nil
+}
+
+// fakeCred is a credential that never talks to AAD, used with the fake dns backend
+type fakeCred struct{}
+
+func (fakeCred) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{
+		Token:     "fake-token",
+		ExpiresOn: time.Now().Add(time.Hour),
+	}, nil
+}
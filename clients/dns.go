@@ -60,7 +60,7 @@ func NewZone(ctx context.Context, subscriptionId, resourceGroup, name string, zo
 		return nil, fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	factory, err := armdns.NewClientFactory(subscriptionId, cred, nil)
+	factory, err := armdns.NewClientFactory(subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("creating client factory: %w", err)
 	}
@@ -124,7 +124,7 @@ func (z *zone) GetDnsZone(ctx context.Context) (*armdns.Zone, error) {
 		return nil, fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	client, err := armdns.NewZonesClient(z.subscriptionId, cred, nil)
+	client, err := armdns.NewZonesClient(z.subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("creating client: %w", err)
 	}
@@ -174,7 +174,7 @@ func NewPrivateZone(ctx context.Context, subscriptionId, resourceGroup, name str
 		return nil, fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	client, err := armprivatedns.NewPrivateZonesClient(subscriptionId, cred, nil)
+	client, err := armprivatedns.NewPrivateZonesClient(subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("creating client: %w", err)
 	}
@@ -230,7 +230,7 @@ func (p *privateZone) GetDnsZone(ctx context.Context) (*armprivatedns.PrivateZon
 		return nil, fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	client, err := armprivatedns.NewPrivateZonesClient(p.subscriptionId, cred, nil)
+	client, err := armprivatedns.NewPrivateZonesClient(p.subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("creating client: %w", err)
 	}
@@ -258,7 +258,7 @@ func (p *privateZone) LinkVnet(ctx context.Context, linkName, vnetId string) err
 		return fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	factory, err := armprivatedns.NewClientFactory(p.subscriptionId, cred, nil)
+	factory, err := armprivatedns.NewClientFactory(p.subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return fmt.Errorf("creating client factory: %w", err)
 	}
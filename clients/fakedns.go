@@ -0,0 +1,589 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+	"github.com/google/uuid"
+)
+
+const (
+	fakeDnsDefaultPageSize = 100
+	publicZoneSegment      = "dnszones"
+	privateZoneSegment     = "privatednszones"
+)
+
+// FakeDns is an in-process stand-in for the public and private Azure DNS zone and record set APIs.
+// It implements the azcore Transporter interface so arm clients can be pointed at it with UseFakeDns,
+// and http.Handler so it can also be served over the network
+type FakeDns struct {
+	mu       sync.Mutex
+	pageSize int
+	zones    map[string]*fakeZone // keyed by lower cased zone resource id
+}
+
+type fakeZone struct {
+	id, name    string
+	private     bool
+	nameservers []string
+	recordSets  map[string]*fakeRecordSet // keyed by upper cased record type and lower cased relative name
+}
+
+// fakeRecordSet holds a record set independently of the public or private wire format
+type fakeRecordSet struct {
+	name, recordType, etag string
+	ttl                    int64
+	metadata               map[string]*string
+	a, aaaa                []string
+	cname                  string
+	txt                    [][]string
+	mx                     []fakeMx
+}
+
+type fakeMx struct {
+	exchange   string
+	preference int32
+}
+
+// NewFakeDns returns an empty fake DNS backend
+func NewFakeDns() *FakeDns {
+	return &FakeDns{
+		pageSize: fakeDnsDefaultPageSize,
+		zones:    map[string]*fakeZone{},
+	}
+}
+
+// SetPageSize changes the maximum number of record sets returned in a single list page, used to exercise pagination
+func (f *FakeDns) SetPageSize(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if n < 1 {
+		n = fakeDnsDefaultPageSize
+	}
+	f.pageSize = n
+}
+
+// Do serves a request made through an arm client pipeline
+func (f *FakeDns) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	f.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// ServeHTTP routes zone, record set, and virtual network link requests for public and private zones, and external-dns
+// webhook requests under FakeDnsWebhookPath
+func (f *FakeDns) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, FakeDnsWebhookPath) {
+		f.serveWebhook(w, r)
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) < 8 ||
+		!strings.EqualFold(segments[0], "subscriptions") ||
+		!strings.EqualFold(segments[2], "resourceGroups") ||
+		!strings.EqualFold(segments[4], "providers") ||
+		!strings.EqualFold(segments[5], "Microsoft.Network") {
+		writeFakeError(w, http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("unsupported path %s", r.URL.Path))
+		return
+	}
+
+	zoneType := strings.ToLower(segments[6])
+	if zoneType != publicZoneSegment && zoneType != privateZoneSegment {
+		writeFakeError(w, http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("unsupported resource type %s", segments[6]))
+		return
+	}
+	private := zoneType == privateZoneSegment
+	zoneId := "/" + strings.Join(segments[:8], "/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch len(segments) {
+	case 8:
+		f.serveZone(w, r, zoneId, segments[7], private)
+	case 9:
+		z, ok := f.zones[strings.ToLower(zoneId)]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "ResourceNotFound", fmt.Sprintf("zone %s not found", segments[7]))
+			return
+		}
+
+		recordType := strings.ToUpper(segments[8])
+		if recordType == "RECORDSETS" || recordType == "ALL" {
+			recordType = ""
+		}
+		if r.Method != http.MethodGet {
+			writeFakeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" not supported")
+			return
+		}
+		f.listRecordSets(w, r, z, recordType)
+	case 10:
+		z, ok := f.zones[strings.ToLower(zoneId)]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "ResourceNotFound", fmt.Sprintf("zone %s not found", segments[7]))
+			return
+		}
+
+		if strings.EqualFold(segments[8], "virtualNetworkLinks") {
+			serveVnetLink(w, r)
+			return
+		}
+		f.serveRecordSet(w, r, z, strings.ToUpper(segments[8]), segments[9])
+	default:
+		writeFakeError(w, http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("unsupported path %s", r.URL.Path))
+	}
+}
+
+// AddZone creates a public zone directly in the fake, returning the nameservers assigned to it
+func (f *FakeDns) AddZone(subscriptionId, resourceGroup, name string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	z := f.ensureZone(zoneResourceId(subscriptionId, resourceGroup, publicZoneSegment, name), name, false)
+	return z.nameservers
+}
+
+// AddPrivateZone creates a private zone directly in the fake
+func (f *FakeDns) AddPrivateZone(subscriptionId, resourceGroup, name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.ensureZone(zoneResourceId(subscriptionId, resourceGroup, privateZoneSegment, name), name, true)
+}
+
+func zoneResourceId(subscriptionId, resourceGroup, zoneType, name string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/%s/%s", subscriptionId, resourceGroup, zoneType, name)
+}
+
+// ensureZone must be called with the lock held
+func (f *FakeDns) ensureZone(id, name string, private bool) *fakeZone {
+	if z, ok := f.zones[strings.ToLower(id)]; ok {
+		return z
+	}
+
+	z := &fakeZone{
+		id:         id,
+		name:       name,
+		private:    private,
+		recordSets: map[string]*fakeRecordSet{},
+	}
+	if !private {
+		z.nameservers = []string{"ns1-01.fake-dns.local.", "ns2-01.fake-dns.local."}
+	}
+
+	f.zones[strings.ToLower(id)] = z
+	return z
+}
+
+func (f *FakeDns) serveZone(w http.ResponseWriter, r *http.Request, id, name string, private bool) {
+	switch r.Method {
+	case http.MethodPut, http.MethodPatch:
+		z := f.ensureZone(id, name, private)
+		writeFakeJson(w, http.StatusOK, z.toWire())
+	case http.MethodGet:
+		z, ok := f.zones[strings.ToLower(id)]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "ResourceNotFound", fmt.Sprintf("zone %s not found", name))
+			return
+		}
+		writeFakeJson(w, http.StatusOK, z.toWire())
+	case http.MethodDelete:
+		delete(f.zones, strings.ToLower(id))
+		w.WriteHeader(http.StatusOK)
+	default:
+		writeFakeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" not supported")
+	}
+}
+
+// serveVnetLink accepts virtual network links without tracking them, links have no effect on record sets
+func serveVnetLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	link := armprivatedns.VirtualNetworkLink{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&link)
+	}
+	link.ID = to.Ptr(r.URL.Path)
+	if link.Properties == nil {
+		link.Properties = &armprivatedns.VirtualNetworkLinkProperties{}
+	}
+	link.Properties.ProvisioningState = to.Ptr(armprivatedns.ProvisioningStateSucceeded)
+	writeFakeJson(w, http.StatusOK, link)
+}
+
+func (f *FakeDns) serveRecordSet(w http.ResponseWriter, r *http.Request, z *fakeZone, recordType, name string) {
+	key := recordSetKey(recordType, name)
+	existing, exists := z.recordSets[key]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			writeFakeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("record set %s/%s not found", recordType, name))
+			return
+		}
+		writeFakeJson(w, http.StatusOK, z.recordSetToWire(existing))
+	case http.MethodPut, http.MethodPatch:
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && (!exists || (ifMatch != "*" && ifMatch != existing.etag)) {
+			writeFakeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "etag does not match")
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			writeFakeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "record set already exists")
+			return
+		}
+		if r.Method == http.MethodPatch && !exists {
+			writeFakeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("record set %s/%s not found", recordType, name))
+			return
+		}
+
+		rs, err := z.recordSetFromWire(r, recordType, name)
+		if err != nil {
+			writeFakeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		if r.Method == http.MethodPatch {
+			rs = existing.merge(rs)
+		}
+		rs.etag = uuid.NewString()
+		z.recordSets[key] = rs
+
+		status := http.StatusOK
+		if !exists {
+			status = http.StatusCreated
+		}
+		writeFakeJson(w, status, z.recordSetToWire(rs))
+	case http.MethodDelete:
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && exists && ifMatch != "*" && ifMatch != existing.etag {
+			writeFakeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "etag does not match")
+			return
+		}
+		if !exists {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		delete(z.recordSets, key)
+		w.WriteHeader(http.StatusOK)
+	default:
+		writeFakeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" not supported")
+	}
+}
+
+// listRecordSets writes a single page of record sets, an empty recordType lists every type in the zone
+func (f *FakeDns) listRecordSets(w http.ResponseWriter, r *http.Request, z *fakeZone, recordType string) {
+	query := r.URL.Query()
+	suffix := strings.ToLower(query.Get("$recordsetnamesuffix"))
+
+	var matched []*fakeRecordSet
+	for _, rs := range z.recordSets {
+		if recordType != "" && rs.recordType != recordType {
+			continue
+		}
+		if suffix != "" && !strings.HasSuffix(strings.ToLower(rs.name), suffix) {
+			continue
+		}
+		matched = append(matched, rs)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].name != matched[j].name {
+			return matched[i].name < matched[j].name
+		}
+		return matched[i].recordType < matched[j].recordType
+	})
+
+	pageSize := f.pageSize
+	if top, err := strconv.Atoi(query.Get("$top")); err == nil && top > 0 && top < pageSize {
+		pageSize = top
+	}
+	skip, _ := strconv.Atoi(query.Get("$skiptoken"))
+	if skip > len(matched) {
+		skip = len(matched)
+	}
+	end := skip + pageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	value := make([]any, 0, end-skip)
+	for _, rs := range matched[skip:end] {
+		value = append(value, z.recordSetToWire(rs))
+	}
+
+	page := map[string]any{"value": value}
+	if end < len(matched) {
+		next := *r.URL
+		if next.Host == "" { // served over the network rather than through Do
+			next.Scheme, next.Host = "http", r.Host
+			if r.TLS != nil {
+				next.Scheme = "https"
+			}
+		}
+		q := next.Query()
+		q.Set("$skiptoken", strconv.Itoa(end))
+		next.RawQuery = q.Encode()
+		page["nextLink"] = next.String()
+	}
+
+	writeFakeJson(w, http.StatusOK, page)
+}
+
+func recordSetKey(recordType, name string) string {
+	return strings.ToUpper(recordType) + "/" + strings.ToLower(name)
+}
+
+func (z *fakeZone) fqdn(name string) string {
+	if name == "@" {
+		return z.name + "."
+	}
+	return name + "." + z.name + "."
+}
+
+func (z *fakeZone) toWire() any {
+	if z.private {
+		return armprivatedns.PrivateZone{
+			ID:       to.Ptr(z.id),
+			Name:     to.Ptr(z.name),
+			Location: to.Ptr("global"),
+			Type:     to.Ptr("Microsoft.Network/privateDnsZones"),
+			Properties: &armprivatedns.PrivateZoneProperties{
+				ProvisioningState:  to.Ptr(armprivatedns.ProvisioningStateSucceeded),
+				NumberOfRecordSets: to.Ptr(int64(len(z.recordSets))),
+			},
+		}
+	}
+
+	nameservers := make([]*string, len(z.nameservers))
+	for i, ns := range z.nameservers {
+		nameservers[i] = to.Ptr(ns)
+	}
+	return armdns.Zone{
+		ID:       to.Ptr(z.id),
+		Name:     to.Ptr(z.name),
+		Location: to.Ptr("global"),
+		Type:     to.Ptr("Microsoft.Network/dnszones"),
+		Properties: &armdns.ZoneProperties{
+			NameServers:        nameservers,
+			NumberOfRecordSets: to.Ptr(int64(len(z.recordSets))),
+			ZoneType:           to.Ptr(armdns.ZoneTypePublic),
+		},
+	}
+}
+
+func (z *fakeZone) recordSetFromWire(r *http.Request, recordType, name string) (*fakeRecordSet, error) {
+	rs := &fakeRecordSet{name: name, recordType: recordType}
+
+	var p *armdns.RecordSetProperties
+	if z.private {
+		wire := armprivatedns.RecordSet{}
+		if err := json.NewDecoder(r.Body).Decode(&wire); err != nil {
+			return nil, fmt.Errorf("decoding private record set: %w", err)
+		}
+		p = publicProperties(wire.Properties)
+	} else {
+		wire := armdns.RecordSet{}
+		if err := json.NewDecoder(r.Body).Decode(&wire); err != nil {
+			return nil, fmt.Errorf("decoding record set: %w", err)
+		}
+		p = wire.Properties
+	}
+	if p == nil {
+		return rs, nil
+	}
+
+	rs.ttl = deref(p.TTL)
+	rs.metadata = p.Metadata
+	for _, a := range p.ARecords {
+		rs.a = append(rs.a, deref(a.IPv4Address))
+	}
+	for _, aaaa := range p.AaaaRecords {
+		rs.aaaa = append(rs.aaaa, deref(aaaa.IPv6Address))
+	}
+	if p.CnameRecord != nil {
+		rs.cname = deref(p.CnameRecord.Cname)
+	}
+	for _, txt := range p.TxtRecords {
+		rs.txt = append(rs.txt, derefStrings(txt.Value))
+	}
+	for _, mx := range p.MxRecords {
+		rs.mx = append(rs.mx, fakeMx{exchange: deref(mx.Exchange), preference: deref(mx.Preference)})
+	}
+	return rs, nil
+}
+
+// publicProperties maps private record set properties onto the public ones they share their fields with, so both kinds
+// of record set are read the same way. Properties only private record sets have are dropped
+func publicProperties(p *armprivatedns.RecordSetProperties) *armdns.RecordSetProperties {
+	if p == nil {
+		return nil
+	}
+
+	ret := &armdns.RecordSetProperties{Fqdn: p.Fqdn, TTL: p.TTL, Metadata: p.Metadata}
+	for _, a := range p.ARecords {
+		ret.ARecords = append(ret.ARecords, &armdns.ARecord{IPv4Address: a.IPv4Address})
+	}
+	for _, aaaa := range p.AaaaRecords {
+		ret.AaaaRecords = append(ret.AaaaRecords, &armdns.AaaaRecord{IPv6Address: aaaa.IPv6Address})
+	}
+	if p.CnameRecord != nil {
+		ret.CnameRecord = &armdns.CnameRecord{Cname: p.CnameRecord.Cname}
+	}
+	for _, txt := range p.TxtRecords {
+		ret.TxtRecords = append(ret.TxtRecords, &armdns.TxtRecord{Value: txt.Value})
+	}
+	for _, mx := range p.MxRecords {
+		ret.MxRecords = append(ret.MxRecords, &armdns.MxRecord{Preference: mx.Preference, Exchange: mx.Exchange})
+	}
+	return ret
+}
+
+func (z *fakeZone) recordSetToWire(rs *fakeRecordSet) any {
+	id := z.id + "/" + rs.recordType + "/" + rs.name
+
+	if z.private {
+		p := &armprivatedns.RecordSetProperties{
+			TTL:              to.Ptr(rs.ttl),
+			Fqdn:             to.Ptr(z.fqdn(rs.name)),
+			Metadata:         rs.metadata,
+			IsAutoRegistered: to.Ptr(false),
+		}
+		for _, a := range rs.a {
+			p.ARecords = append(p.ARecords, &armprivatedns.ARecord{IPv4Address: to.Ptr(a)})
+		}
+		for _, aaaa := range rs.aaaa {
+			p.AaaaRecords = append(p.AaaaRecords, &armprivatedns.AaaaRecord{IPv6Address: to.Ptr(aaaa)})
+		}
+		if rs.cname != "" {
+			p.CnameRecord = &armprivatedns.CnameRecord{Cname: to.Ptr(rs.cname)}
+		}
+		for _, txt := range rs.txt {
+			p.TxtRecords = append(p.TxtRecords, &armprivatedns.TxtRecord{Value: to.SliceOfPtrs(txt...)})
+		}
+		for _, mx := range rs.mx {
+			p.MxRecords = append(p.MxRecords, &armprivatedns.MxRecord{Exchange: to.Ptr(mx.exchange), Preference: to.Ptr(mx.preference)})
+		}
+
+		return armprivatedns.RecordSet{
+			ID:         to.Ptr(id),
+			Name:       to.Ptr(rs.name),
+			Type:       to.Ptr("Microsoft.Network/privateDnsZones/" + rs.recordType),
+			Etag:       to.Ptr(rs.etag),
+			Properties: p,
+		}
+	}
+
+	p := &armdns.RecordSetProperties{
+		TTL:               to.Ptr(rs.ttl),
+		Fqdn:              to.Ptr(z.fqdn(rs.name)),
+		Metadata:          rs.metadata,
+		ProvisioningState: to.Ptr("Succeeded"),
+	}
+	for _, a := range rs.a {
+		p.ARecords = append(p.ARecords, &armdns.ARecord{IPv4Address: to.Ptr(a)})
+	}
+	for _, aaaa := range rs.aaaa {
+		p.AaaaRecords = append(p.AaaaRecords, &armdns.AaaaRecord{IPv6Address: to.Ptr(aaaa)})
+	}
+	if rs.cname != "" {
+		p.CnameRecord = &armdns.CnameRecord{Cname: to.Ptr(rs.cname)}
+	}
+	for _, txt := range rs.txt {
+		p.TxtRecords = append(p.TxtRecords, &armdns.TxtRecord{Value: to.SliceOfPtrs(txt...)})
+	}
+	for _, mx := range rs.mx {
+		p.MxRecords = append(p.MxRecords, &armdns.MxRecord{Exchange: to.Ptr(mx.exchange), Preference: to.Ptr(mx.preference)})
+	}
+
+	return armdns.RecordSet{
+		ID:         to.Ptr(id),
+		Name:       to.Ptr(rs.name),
+		Type:       to.Ptr("Microsoft.Network/dnszones/" + rs.recordType),
+		Etag:       to.Ptr(rs.etag),
+		Properties: p,
+	}
+}
+
+// merge applies the populated fields of a PATCH body on top of an existing record set
+func (rs *fakeRecordSet) merge(patch *fakeRecordSet) *fakeRecordSet {
+	merged := *rs
+	if patch.ttl != 0 {
+		merged.ttl = patch.ttl
+	}
+	if patch.metadata != nil {
+		merged.metadata = patch.metadata
+	}
+	if patch.a != nil {
+		merged.a = patch.a
+	}
+	if patch.aaaa != nil {
+		merged.aaaa = patch.aaaa
+	}
+	if patch.cname != "" {
+		merged.cname = patch.cname
+	}
+	if patch.txt != nil {
+		merged.txt = patch.txt
+	}
+	if patch.mx != nil {
+		merged.mx = patch.mx
+	}
+	return &merged
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func derefStrings(ptrs []*string) []string {
+	ret := make([]string, 0, len(ptrs))
+	for _, p := range ptrs {
+		ret = append(ret, deref(p))
+	}
+	return ret
+}
+
+func writeFakeJson(w http.ResponseWriter, status int, body any) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		writeFakeError(w, http.StatusInternalServerError, "InternalServerError", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// writeFakeError writes an error in the ARM error format so the sdk can surface it as an azcore.ResponseError
+func writeFakeError(w http.ResponseWriter, status int, code, message string) {
+	b, _ := json.Marshal(map[string]any{
+		"error": map[string]string{
+			"code":    code,
+			"message": message,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,251 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+)
+
+const (
+	testSubscriptionId = "00000000-0000-0000-0000-000000000000"
+	testResourceGroup  = "rg"
+	testZone           = "example.com"
+)
+
+// useTestFakeDns points the arm clients of the package at a new fake with a public and private testZone until the test
+// ends
+func useTestFakeDns(t *testing.T) *FakeDns {
+	t.Helper()
+
+	fake := NewFakeDns()
+	fake.AddZone(testSubscriptionId, testResourceGroup, testZone)
+	fake.AddPrivateZone(testSubscriptionId, testResourceGroup, testZone)
+	UseFakeDns(fake)
+	t.Cleanup(UseAzureDns)
+
+	return fake
+}
+
+func newTestRecordSetsClient(t *testing.T) *armdns.RecordSetsClient {
+	t.Helper()
+
+	c, err := armdns.NewRecordSetsClient(testSubscriptionId, fakeCred{}, GetClientOptions())
+	if err != nil {
+		t.Fatalf("creating record sets client: %s", err)
+	}
+	return c
+}
+
+func newTestPrivateRecordSetsClient(t *testing.T) *armprivatedns.RecordSetsClient {
+	t.Helper()
+
+	c, err := armprivatedns.NewRecordSetsClient(testSubscriptionId, fakeCred{}, GetClientOptions())
+	if err != nil {
+		t.Fatalf("creating private record sets client: %s", err)
+	}
+	return c
+}
+
+func aRecordSet(ttl int64, ips ...string) armdns.RecordSet {
+	rs := armdns.RecordSet{Properties: &armdns.RecordSetProperties{TTL: to.Ptr(ttl)}}
+	for _, ip := range ips {
+		rs.Properties.ARecords = append(rs.Properties.ARecords, &armdns.ARecord{IPv4Address: to.Ptr(ip)})
+	}
+	return rs
+}
+
+// statusCode returns the http status of an arm error, or 0 for other errors
+func statusCode(err error) int {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode
+	}
+	return 0
+}
+
+func TestFakeDnsRecordSet(t *testing.T) {
+	useTestFakeDns(t)
+	ctx := context.Background()
+	c := newTestRecordSetsClient(t)
+
+	created, err := c.CreateOrUpdate(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, aRecordSet(300, "192.0.2.1", "192.0.2.2"), nil)
+	if err != nil {
+		t.Fatalf("creating record set: %s", err)
+	}
+	if created.Etag == nil || *created.Etag == "" {
+		t.Fatal("created record set has no etag")
+	}
+
+	got, err := c.Get(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, nil)
+	if err != nil {
+		t.Fatalf("getting record set: %s", err)
+	}
+	if fqdn := *got.Properties.Fqdn; fqdn != "www.example.com." {
+		t.Errorf("fqdn is %s, expected www.example.com.", fqdn)
+	}
+	if ttl := *got.Properties.TTL; ttl != 300 {
+		t.Errorf("ttl is %d, expected 300", ttl)
+	}
+	if n := len(got.Properties.ARecords); n != 2 {
+		t.Errorf("record set has %d A records, expected 2", n)
+	}
+	if *got.Etag != *created.Etag {
+		t.Errorf("etag changed from %s to %s without an update", *created.Etag, *got.Etag)
+	}
+
+	if _, err := c.Delete(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, nil); err != nil {
+		t.Fatalf("deleting record set: %s", err)
+	}
+	if _, err := c.Get(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, nil); statusCode(err) != http.StatusNotFound {
+		t.Errorf("getting deleted record set returned %v, expected not found", err)
+	}
+}
+
+func TestFakeDnsPrivateRecordSet(t *testing.T) {
+	useTestFakeDns(t)
+	ctx := context.Background()
+	c := newTestPrivateRecordSetsClient(t)
+
+	rs := armprivatedns.RecordSet{Properties: &armprivatedns.RecordSetProperties{
+		TTL:         to.Ptr[int64](60),
+		AaaaRecords: []*armprivatedns.AaaaRecord{{IPv6Address: to.Ptr("2001:db8::1")}},
+	}}
+	if _, err := c.CreateOrUpdate(ctx, testResourceGroup, testZone, armprivatedns.RecordTypeAAAA, "www", rs, nil); err != nil {
+		t.Fatalf("creating private record set: %s", err)
+	}
+
+	got, err := c.Get(ctx, testResourceGroup, testZone, armprivatedns.RecordTypeAAAA, "www", nil)
+	if err != nil {
+		t.Fatalf("getting private record set: %s", err)
+	}
+	if ip := *got.Properties.AaaaRecords[0].IPv6Address; ip != "2001:db8::1" {
+		t.Errorf("AAAA record is %s, expected 2001:db8::1", ip)
+	}
+
+	// the public zone of the same name is separate
+	if _, err := newTestRecordSetsClient(t).Get(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeAAAA, nil); statusCode(err) != http.StatusNotFound {
+		t.Errorf("getting private record set from the public zone returned %v, expected not found", err)
+	}
+
+	if _, err := c.Delete(ctx, testResourceGroup, testZone, armprivatedns.RecordTypeAAAA, "www", nil); err != nil {
+		t.Fatalf("deleting private record set: %s", err)
+	}
+	if _, err := c.Get(ctx, testResourceGroup, testZone, armprivatedns.RecordTypeAAAA, "www", nil); statusCode(err) != http.StatusNotFound {
+		t.Errorf("getting deleted private record set returned %v, expected not found", err)
+	}
+}
+
+func TestFakeDnsPaging(t *testing.T) {
+	fake := useTestFakeDns(t)
+	ctx := context.Background()
+	c := newTestRecordSetsClient(t)
+
+	const records = 7
+	for i := 0; i < records; i++ {
+		if _, err := c.CreateOrUpdate(ctx, testResourceGroup, testZone, fmt.Sprintf("host-%d", i), armdns.RecordTypeA, aRecordSet(300, "192.0.2.1"), nil); err != nil {
+			t.Fatalf("creating record set %d: %s", i, err)
+		}
+	}
+	// a record of another type isn't listed by type
+	if _, err := c.CreateOrUpdate(ctx, testResourceGroup, testZone, "host-0", armdns.RecordTypeTXT, armdns.RecordSet{Properties: &armdns.RecordSetProperties{
+		TxtRecords: []*armdns.TxtRecord{{Value: []*string{to.Ptr("owner")}}},
+	}}, nil); err != nil {
+		t.Fatalf("creating TXT record set: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		pageSize  int
+		top       *int32
+		wantPages int
+	}{
+		{name: "single page", pageSize: 100, wantPages: 1},
+		{name: "page size", pageSize: 3, wantPages: 3},
+		{name: "top", pageSize: 100, top: to.Ptr[int32](2), wantPages: 4},
+		{name: "top over page size", pageSize: 4, top: to.Ptr[int32](5), wantPages: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.SetPageSize(tt.pageSize)
+
+			pager := c.NewListByTypePager(testResourceGroup, testZone, armdns.RecordTypeA, &armdns.RecordSetsClientListByTypeOptions{Top: tt.top})
+			seen := map[string]bool{}
+			pages := 0
+			for pager.More() {
+				page, err := pager.NextPage(ctx)
+				if err != nil {
+					t.Fatalf("listing page %d: %s", pages, err)
+				}
+				pages++
+				for _, rs := range page.Value {
+					if seen[*rs.Name] {
+						t.Errorf("record set %s listed twice", *rs.Name)
+					}
+					seen[*rs.Name] = true
+				}
+			}
+
+			if pages != tt.wantPages {
+				t.Errorf("listed %d pages, expected %d", pages, tt.wantPages)
+			}
+			if len(seen) != records {
+				t.Errorf("listed %d record sets, expected %d", len(seen), records)
+			}
+		})
+	}
+}
+
+func TestFakeDnsEtags(t *testing.T) {
+	useTestFakeDns(t)
+	ctx := context.Background()
+	c := newTestRecordSetsClient(t)
+
+	created, err := c.CreateOrUpdate(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, aRecordSet(300, "192.0.2.1"), nil)
+	if err != nil {
+		t.Fatalf("creating record set: %s", err)
+	}
+	etag := *created.Etag
+
+	_, err = c.CreateOrUpdate(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, aRecordSet(300, "192.0.2.2"), &armdns.RecordSetsClientCreateOrUpdateOptions{IfMatch: to.Ptr("stale")})
+	if statusCode(err) != http.StatusPreconditionFailed {
+		t.Errorf("updating with a stale etag returned %v, expected a precondition failure", err)
+	}
+
+	_, err = c.CreateOrUpdate(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, aRecordSet(300, "192.0.2.2"), &armdns.RecordSetsClientCreateOrUpdateOptions{IfNoneMatch: to.Ptr("*")})
+	if statusCode(err) != http.StatusPreconditionFailed {
+		t.Errorf("creating an existing record set with If-None-Match returned %v, expected a precondition failure", err)
+	}
+
+	_, err = c.Delete(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, &armdns.RecordSetsClientDeleteOptions{IfMatch: to.Ptr("stale")})
+	if statusCode(err) != http.StatusPreconditionFailed {
+		t.Errorf("deleting with a stale etag returned %v, expected a precondition failure", err)
+	}
+
+	updated, err := c.CreateOrUpdate(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, aRecordSet(300, "192.0.2.2"), &armdns.RecordSetsClientCreateOrUpdateOptions{IfMatch: to.Ptr(etag)})
+	if err != nil {
+		t.Fatalf("updating with the current etag: %s", err)
+	}
+	if *updated.Etag == etag {
+		t.Error("etag didn't change on update")
+	}
+
+	got, err := c.Get(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, nil)
+	if err != nil {
+		t.Fatalf("getting record set: %s", err)
+	}
+	if ip := *got.Properties.ARecords[0].IPv4Address; ip != "192.0.2.2" {
+		t.Errorf("A record is %s after the update, expected 192.0.2.2", ip)
+	}
+
+	if _, err := c.Delete(ctx, testResourceGroup, testZone, "www", armdns.RecordTypeA, &armdns.RecordSetsClientDeleteOptions{IfMatch: updated.Etag}); err != nil {
+		t.Fatalf("deleting with the current etag: %s", err)
+	}
+}
@@ -0,0 +1,230 @@
+package clients
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+)
+
+// fakeNameserverPort is the port the fake answers dns queries on, beside the http api on the port of the fake dns url
+const fakeNameserverPort = "8053"
+
+// FakeDnsNameserver returns the address of the nameserver of a fake served at baseUrl, which public zones of local
+// infras list as their nameserver
+func FakeDnsNameserver(baseUrl string) (string, error) {
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return "", fmt.Errorf("parsing fake dns url: %w", err)
+	}
+	return net.JoinHostPort(u.Hostname(), fakeNameserverPort), nil
+}
+
+// ServeDns answers dns queries for the public zones held by the fake over udp and tcp on addr in the background until
+// ctx is done, acting as the authoritative nameserver of every zone
+func (f *FakeDns) ServeDns(ctx context.Context, addr string) error {
+	lgr := logger.FromContext(ctx).With("addr", addr)
+	lgr.Info("starting to serve fake dns nameserver")
+
+	packetConn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on udp %s: %w", addr, err)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		packetConn.Close()
+		return fmt.Errorf("listening on tcp %s: %w", addr, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		packetConn.Close()
+		listener.Close()
+	}()
+
+	go func() {
+		buf := make([]byte, maxUdpMessageSize)
+		for {
+			n, from, err := packetConn.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					lgr.Error("reading udp query: " + err.Error())
+				}
+				return
+			}
+
+			resp, err := f.answerDns(buf[:n], maxUdpMessageSize)
+			if err != nil {
+				lgr.Error("answering udp query: " + err.Error())
+				continue
+			}
+			packetConn.WriteTo(resp, from)
+		}
+	}()
+
+	go func() {
+		defer lgr.Info("finished serving fake dns nameserver")
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					lgr.Error("accepting tcp connection: " + err.Error())
+				}
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(resolveTimeout))
+
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+
+				resp, err := f.answerDns(query, 0)
+				if err != nil {
+					lgr.Error("answering tcp query: " + err.Error())
+					return
+				}
+				conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+			}()
+		}
+	}()
+
+	return nil
+}
+
+// answerDns builds the response to query from the record sets of the public zones. Responses longer than maxSize are
+// truncated, a maxSize of 0 doesn't limit them
+func (f *FakeDns) answerDns(query []byte, maxSize int) ([]byte, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		return nil, fmt.Errorf("unpacking query: %w", err)
+	}
+
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: msg.Header.ID, Response: true, Authoritative: true},
+		Questions: msg.Questions,
+	}
+	if len(msg.Questions) != 1 {
+		resp.Header.RCode = dnsmessage.RCodeFormatError
+		return resp.Pack()
+	}
+	question := msg.Questions[0]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	z, name, ok := f.webhookZone(false, question.Name.String())
+	if !ok {
+		resp.Header.Authoritative = false
+		resp.Header.RCode = dnsmessage.RCodeRefused
+		return resp.Pack()
+	}
+
+	exists := false
+	for _, rs := range z.recordSets {
+		if strings.EqualFold(rs.name, name) {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		resp.Header.RCode = dnsmessage.RCodeNameError
+		return resp.Pack()
+	}
+
+	// like azure dns a CNAME is returned for any type asked for, without following it
+	rs, ok := z.recordSets[recordSetKey("CNAME", name)]
+	if !ok {
+		for recordType, t := range dnsRecordTypes {
+			if t == question.Type {
+				rs, ok = z.recordSets[recordSetKey(recordType, name)]
+			}
+		}
+	}
+	if ok {
+		answers, err := fakeDnsAnswers(question.Name, rs)
+		if err != nil {
+			return nil, err
+		}
+		resp.Answers = answers
+	}
+
+	packed, err := resp.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("packing response: %w", err)
+	}
+	if maxSize > 0 && len(packed) > maxSize {
+		resp.Header.Truncated = true
+		resp.Answers = nil
+		return resp.Pack()
+	}
+	return packed, nil
+}
+
+// fakeDnsAnswers returns the records of rs as answers to a question for name
+func fakeDnsAnswers(name dnsmessage.Name, rs *fakeRecordSet) ([]dnsmessage.Resource, error) {
+	header := func(t dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: t, Class: dnsmessage.ClassINET, TTL: uint32(rs.ttl)}
+	}
+
+	var answers []dnsmessage.Resource
+	switch rs.recordType {
+	case "A":
+		for _, a := range rs.a {
+			ip := net.ParseIP(a).To4()
+			if ip == nil {
+				return nil, fmt.Errorf("invalid A record %s", a)
+			}
+			body := &dnsmessage.AResource{}
+			copy(body.A[:], ip)
+			answers = append(answers, dnsmessage.Resource{Header: header(dnsmessage.TypeA), Body: body})
+		}
+	case "AAAA":
+		for _, aaaa := range rs.aaaa {
+			ip := net.ParseIP(aaaa).To16()
+			if ip == nil {
+				return nil, fmt.Errorf("invalid AAAA record %s", aaaa)
+			}
+			body := &dnsmessage.AAAAResource{}
+			copy(body.AAAA[:], ip)
+			answers = append(answers, dnsmessage.Resource{Header: header(dnsmessage.TypeAAAA), Body: body})
+		}
+	case "CNAME":
+		target, err := dnsmessage.NewName(strings.TrimSuffix(rs.cname, ".") + ".")
+		if err != nil {
+			return nil, fmt.Errorf("parsing CNAME target %s: %w", rs.cname, err)
+		}
+		answers = append(answers, dnsmessage.Resource{Header: header(dnsmessage.TypeCNAME), Body: &dnsmessage.CNAMEResource{CNAME: target}})
+	case "TXT":
+		for _, txt := range rs.txt {
+			// strings are limited to 255 bytes on the wire, longer values are split like azure dns does
+			var chunks []string
+			for _, value := range txt {
+				for len(value) > 255 {
+					chunks = append(chunks, value[:255])
+					value = value[255:]
+				}
+				chunks = append(chunks, value)
+			}
+			answers = append(answers, dnsmessage.Resource{Header: header(dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: chunks}})
+		}
+	}
+
+	return answers, nil
+}
@@ -0,0 +1,278 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+)
+
+const (
+	// FakeDnsWebhookPath is the path prefix external-dns webhook providers are served under, followed by "public" or "private"
+	FakeDnsWebhookPath = "/external-dns/"
+	webhookMediaType   = "application/external.dns.webhook+json;version=1"
+	webhookDefaultTtl  = 300 // same default the azure providers use when no ttl is set
+)
+
+// webhookEndpoint is the wire format of an external-dns endpoint, kept local so the repo doesn't depend on external-dns
+type webhookEndpoint struct {
+	DNSName          string            `json:"dnsName,omitempty"`
+	Targets          []string          `json:"targets,omitempty"`
+	RecordType       string            `json:"recordType,omitempty"`
+	SetIdentifier    string            `json:"setIdentifier,omitempty"`
+	RecordTTL        int64             `json:"recordTTL,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	ProviderSpecific []any             `json:"providerSpecific,omitempty"`
+}
+
+type webhookChanges struct {
+	Create    []*webhookEndpoint
+	UpdateOld []*webhookEndpoint
+	UpdateNew []*webhookEndpoint
+	Delete    []*webhookEndpoint
+}
+
+// FakeDnsWebhookUrl returns the url external-dns should use as --webhook-provider-url for the public or private zones
+// of a fake served at baseUrl
+func FakeDnsWebhookUrl(baseUrl string, private bool) string {
+	kind := "public"
+	if private {
+		kind = "private"
+	}
+	return strings.TrimSuffix(baseUrl, "/") + FakeDnsWebhookPath + kind
+}
+
+// Start serves the fake over http on addr in the background until ctx is done. Besides the ARM api this also exposes
+// an external-dns webhook provider for the public and private zones so an external-dns deployment can write to the fake
+func (f *FakeDns) Start(ctx context.Context, addr string) error {
+	lgr := logger.FromContext(ctx).With("addr", addr)
+	lgr.Info("starting to serve fake dns")
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+
+	srv := &http.Server{Handler: f, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	go func() {
+		defer lgr.Info("finished serving fake dns")
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			lgr.Error("serving fake dns: " + err.Error())
+		}
+	}()
+
+	return nil
+}
+
+// serveWebhook implements the external-dns webhook provider protocol on top of the zones held by the fake
+func (f *FakeDns) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, FakeDnsWebhookPath), "/"), "/")
+	if segments[0] != "public" && segments[0] != "private" {
+		http.Error(w, fmt.Sprintf("unsupported webhook provider %s", segments[0]), http.StatusNotFound)
+		return
+	}
+	private := segments[0] == "private"
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		f.webhookNegotiate(w, private)
+	case len(segments) == 2 && segments[1] == "records" && r.Method == http.MethodGet:
+		writeWebhookJson(w, f.webhookRecords(private))
+	case len(segments) == 2 && segments[1] == "records" && r.Method == http.MethodPost:
+		changes := webhookChanges{}
+		if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+			http.Error(w, fmt.Sprintf("decoding changes: %s", err), http.StatusBadRequest)
+			return
+		}
+		if err := f.webhookApply(private, changes); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(segments) == 2 && segments[1] == "adjustendpoints" && r.Method == http.MethodPost:
+		endpoints := []*webhookEndpoint{}
+		if err := json.NewDecoder(r.Body).Decode(&endpoints); err != nil {
+			http.Error(w, fmt.Sprintf("decoding endpoints: %s", err), http.StatusBadRequest)
+			return
+		}
+		writeWebhookJson(w, endpoints)
+	default:
+		http.Error(w, fmt.Sprintf("unsupported webhook request %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+	}
+}
+
+// webhookNegotiate returns the domain filter, every zone of the requested visibility
+func (f *FakeDns) webhookNegotiate(w http.ResponseWriter, private bool) {
+	include := []string{}
+	for _, z := range f.zones {
+		if z.private == private {
+			include = append(include, z.name)
+		}
+	}
+	sort.Strings(include)
+
+	writeWebhookJson(w, map[string][]string{"include": include})
+}
+
+func (f *FakeDns) webhookRecords(private bool) []*webhookEndpoint {
+	endpoints := []*webhookEndpoint{}
+	for _, z := range f.zones {
+		if z.private != private {
+			continue
+		}
+
+		for _, rs := range z.recordSets {
+			ep := &webhookEndpoint{
+				DNSName:    strings.TrimSuffix(z.fqdn(rs.name), "."),
+				RecordType: rs.recordType,
+				RecordTTL:  rs.ttl,
+			}
+			switch rs.recordType {
+			case "A":
+				ep.Targets = rs.a
+			case "AAAA":
+				ep.Targets = rs.aaaa
+			case "CNAME":
+				ep.Targets = []string{rs.cname}
+			case "TXT":
+				for _, txt := range rs.txt {
+					ep.Targets = append(ep.Targets, strings.Join(txt, ""))
+				}
+			case "MX":
+				for _, mx := range rs.mx {
+					ep.Targets = append(ep.Targets, fmt.Sprintf("%d %s", mx.preference, mx.exchange))
+				}
+			default:
+				continue
+			}
+			endpoints = append(endpoints, ep)
+		}
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].DNSName != endpoints[j].DNSName {
+			return endpoints[i].DNSName < endpoints[j].DNSName
+		}
+		return endpoints[i].RecordType < endpoints[j].RecordType
+	})
+	return endpoints
+}
+
+// webhookApply must be called with the lock held. UpdateOld needs no handling because UpdateNew replaces the record set.
+// Like the azure providers, endpoints outside of every zone are skipped
+func (f *FakeDns) webhookApply(private bool, changes webhookChanges) error {
+	for _, ep := range changes.Delete {
+		z, name, ok := f.webhookZone(private, ep.DNSName)
+		if !ok {
+			continue
+		}
+		delete(z.recordSets, recordSetKey(ep.RecordType, name))
+	}
+
+	for _, ep := range append(changes.Create, changes.UpdateNew...) {
+		z, name, ok := f.webhookZone(private, ep.DNSName)
+		if !ok {
+			continue
+		}
+
+		rs := &fakeRecordSet{
+			name:       name,
+			recordType: strings.ToUpper(ep.RecordType),
+			etag:       uuid.NewString(),
+			ttl:        ep.RecordTTL,
+		}
+		if rs.ttl == 0 {
+			rs.ttl = webhookDefaultTtl
+		}
+
+		switch rs.recordType {
+		case "A":
+			rs.a = ep.Targets
+		case "AAAA":
+			rs.aaaa = ep.Targets
+		case "CNAME":
+			if len(ep.Targets) > 0 {
+				rs.cname = ep.Targets[0]
+			}
+		case "TXT":
+			for _, target := range ep.Targets {
+				rs.txt = append(rs.txt, []string{target})
+			}
+		case "MX":
+			for _, target := range ep.Targets {
+				preference, exchange, _ := strings.Cut(target, " ")
+				p, err := strconv.ParseInt(preference, 10, 32)
+				if err != nil {
+					return fmt.Errorf("parsing mx target %s: %w", target, err)
+				}
+				rs.mx = append(rs.mx, fakeMx{exchange: exchange, preference: int32(p)})
+			}
+		default:
+			return fmt.Errorf("unsupported record type %s", ep.RecordType)
+		}
+
+		z.recordSets[recordSetKey(rs.recordType, name)] = rs
+	}
+
+	return nil
+}
+
+// webhookZone finds the most specific zone holding dnsName and returns the record set name relative to it
+func (f *FakeDns) webhookZone(private bool, dnsName string) (*fakeZone, string, bool) {
+	dnsName = strings.ToLower(strings.TrimSuffix(dnsName, "."))
+
+	var match *fakeZone
+	for _, z := range f.zones {
+		if z.private != private {
+			continue
+		}
+
+		zoneName := strings.ToLower(z.name)
+		if dnsName != zoneName && !strings.HasSuffix(dnsName, "."+zoneName) {
+			continue
+		}
+		if match == nil || len(zoneName) > len(match.name) {
+			match = z
+		}
+	}
+
+	if match == nil {
+		return nil, "", false
+	}
+
+	name := strings.TrimSuffix(strings.TrimSuffix(dnsName, strings.ToLower(match.name)), ".")
+	if name == "" {
+		name = "@"
+	}
+	return match, name, true
+}
+
+func writeWebhookJson(w http.ResponseWriter, body any) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", webhookMediaType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -0,0 +1,120 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/msi/armmsi"
+	"github.com/Azure/go-autorest/autorest/azure"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+)
+
+// managedIdentity is a user-assigned managed identity, loaded from the infrastructure file
+type managedIdentity struct {
+	name, subscriptionId, resourceGroup string
+	id                                  string
+	clientId, principalId               string
+}
+
+// Called to create Provisioned object from .json file
+func LoadManagedIdentity(id azure.Resource, clientId, principalId string) *managedIdentity {
+	return &managedIdentity{
+		name:           id.ResourceName,
+		subscriptionId: id.SubscriptionID,
+		resourceGroup:  id.ResourceGroup,
+		id:             id.String(),
+		clientId:       clientId,
+		principalId:    principalId,
+	}
+}
+
+// Creates a user-assigned managed identity with the given name
+func NewManagedIdentity(ctx context.Context, subscriptionId, resourceGroup, name, location string) (*managedIdentity, error) {
+	lgr := logger.FromContext(ctx).With("name", name, "resourceGroup", resourceGroup, "location", location)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to create managed identity")
+	defer lgr.Info("finished creating managed identity")
+
+	cred, err := GetAzCred()
+	if err != nil {
+		return nil, fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	client, err := armmsi.NewUserAssignedIdentitiesClient(subscriptionId, cred, GetClientOptions())
+	if err != nil {
+		return nil, fmt.Errorf("creating client: %w", err)
+	}
+
+	resp, err := client.CreateOrUpdate(ctx, resourceGroup, name, armmsi.Identity{
+		Location: to.Ptr(location),
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating managed identity: %w", err)
+	}
+
+	// guard against things that should be impossible
+	if resp.ID == nil {
+		return nil, fmt.Errorf("managed identity id is nil")
+	}
+	if resp.Properties == nil || resp.Properties.ClientID == nil || resp.Properties.PrincipalID == nil {
+		return nil, fmt.Errorf("managed identity client or principal id is nil")
+	}
+
+	return &managedIdentity{
+		name:           name,
+		subscriptionId: subscriptionId,
+		resourceGroup:  resourceGroup,
+		id:             *resp.ID,
+		clientId:       *resp.Properties.ClientID,
+		principalId:    *resp.Properties.PrincipalID,
+	}, nil
+}
+
+// Trusts tokens the issuer signs for subject, like a service account of a cluster with an oidc issuer. Credentials of
+// the same identity can't be written concurrently
+func (m *managedIdentity) NewFederatedCredential(ctx context.Context, name, issuer, subject string) error {
+	lgr := logger.FromContext(ctx).With("identity", m.name, "name", name, "issuer", issuer, "subject", subject)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to create federated credential")
+	defer lgr.Info("finished creating federated credential")
+
+	cred, err := GetAzCred()
+	if err != nil {
+		return fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	client, err := armmsi.NewFederatedIdentityCredentialsClient(m.subscriptionId, cred, GetClientOptions())
+	if err != nil {
+		return fmt.Errorf("creating client: %w", err)
+	}
+
+	if _, err := client.CreateOrUpdate(ctx, m.resourceGroup, m.name, name, armmsi.FederatedIdentityCredential{
+		Properties: &armmsi.FederatedIdentityCredentialProperties{
+			Issuer:    to.Ptr(issuer),
+			Subject:   to.Ptr(subject),
+			Audiences: []*string{to.Ptr("api://AzureADTokenExchange")},
+		},
+	}, nil); err != nil {
+		return fmt.Errorf("creating federated credential: %w", err)
+	}
+
+	return nil
+}
+
+func (m *managedIdentity) GetName() string {
+	return m.name
+}
+
+func (m *managedIdentity) GetId() string {
+	return m.id
+}
+
+func (m *managedIdentity) GetClientId() string {
+	return m.clientId
+}
+
+func (m *managedIdentity) GetPrincipalId() string {
+	return m.principalId
+}
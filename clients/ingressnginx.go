@@ -0,0 +1,204 @@
+package clients
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// IngressNginxClassName is the ingress class served by the controller from NewIngressNginxController
+	IngressNginxClassName = "nginx-e2e"
+	// IngressNginxServiceName is the load balancer service the controller publishes as the address of its ingresses
+	IngressNginxServiceName = "ingress-nginx-controller"
+
+	ingressNginxName       = "ingress-nginx"
+	ingressNginxController = "k8s.io/ingress-nginx-e2e"
+	ingressNginxImage      = "registry.k8s.io/ingress-nginx/controller:v1.9.4"
+)
+
+// Returns the manifests for an ingress-nginx controller in kube-system serving IngressNginxClassName. The controller
+// writes the ip of IngressNginxServiceName into the status of its ingresses, which is what external-dns publishes.
+// The admission webhook is left out since tests only create valid ingresses
+func NewIngressNginxController() []client.Object {
+	labels := map[string]string{"app": ingressNginxName}
+
+	return []client.Object{
+		&corev1.ServiceAccount{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "ServiceAccount",
+				APIVersion: "v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ingressNginxName,
+				Namespace: "kube-system",
+			},
+		},
+		&rbacv1.ClusterRole{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "ClusterRole",
+				APIVersion: "rbac.authorization.k8s.io/v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: ingressNginxName,
+			},
+			Rules: []rbacv1.PolicyRule{
+				{
+					APIGroups: []string{""},
+					Resources: []string{"configmaps", "endpoints", "nodes", "pods", "secrets", "namespaces", "services"},
+					Verbs:     []string{"get", "list", "watch"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"events"},
+					Verbs:     []string{"create", "patch"},
+				},
+				{
+					APIGroups: []string{"coordination.k8s.io"},
+					Resources: []string{"leases"},
+					Verbs:     []string{"get", "list", "watch", "create", "update"},
+				},
+				{
+					APIGroups: []string{"networking.k8s.io"},
+					Resources: []string{"ingresses", "ingressclasses"},
+					Verbs:     []string{"get", "list", "watch"},
+				},
+				{
+					APIGroups: []string{"networking.k8s.io"},
+					Resources: []string{"ingresses/status"},
+					Verbs:     []string{"update"},
+				},
+				{
+					APIGroups: []string{"discovery.k8s.io"},
+					Resources: []string{"endpointslices"},
+					Verbs:     []string{"get", "list", "watch"},
+				},
+			},
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "ClusterRoleBinding",
+				APIVersion: "rbac.authorization.k8s.io/v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: ingressNginxName,
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     ingressNginxName,
+			},
+			Subjects: []rbacv1.Subject{{
+				Kind:      "ServiceAccount",
+				Name:      ingressNginxName,
+				Namespace: "kube-system",
+			}},
+		},
+		&networkingv1.IngressClass{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "IngressClass",
+				APIVersion: "networking.k8s.io/v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: IngressNginxClassName,
+			},
+			Spec: networkingv1.IngressClassSpec{
+				Controller: ingressNginxController,
+			},
+		},
+		&corev1.Service{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Service",
+				APIVersion: "v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      IngressNginxServiceName,
+				Namespace: "kube-system",
+			},
+			Spec: corev1.ServiceSpec{
+				ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyCluster,
+				Type:                  corev1.ServiceTypeLoadBalancer,
+				Selector:              labels,
+				Ports: []corev1.ServicePort{
+					{
+						Name:       "http",
+						Protocol:   "TCP",
+						Port:       80,
+						TargetPort: intstr.FromString("http"),
+					},
+				},
+			},
+		},
+		&appsv1.Deployment{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Deployment",
+				APIVersion: "apps/v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      IngressNginxServiceName,
+				Namespace: "kube-system",
+			},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: labels,
+					},
+					Spec: *WithPreferSystemNodes(&corev1.PodSpec{
+						ServiceAccountName: ingressNginxName,
+						Containers: []corev1.Container{
+							{
+								Name:  "controller",
+								Image: ingressNginxImage,
+								Args: []string{
+									"/nginx-ingress-controller",
+									"--publish-service=kube-system/" + IngressNginxServiceName,
+									"--election-id=" + ingressNginxName + "-leader",
+									"--controller-class=" + ingressNginxController,
+									"--ingress-class=" + IngressNginxClassName,
+								},
+								Env: []corev1.EnvVar{
+									{
+										Name:      "POD_NAME",
+										ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}},
+									},
+									{
+										Name:      "POD_NAMESPACE",
+										ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}},
+									},
+								},
+								Ports: []corev1.ContainerPort{
+									{
+										Name:          "http",
+										ContainerPort: 80,
+									},
+								},
+								ReadinessProbe: &corev1.Probe{
+									ProbeHandler: corev1.ProbeHandler{
+										HTTPGet: &corev1.HTTPGetAction{
+											Path: "/healthz",
+											Port: intstr.FromInt(10254),
+										},
+									},
+								},
+								SecurityContext: &corev1.SecurityContext{
+									RunAsUser:                to.Ptr[int64](101),
+									AllowPrivilegeEscalation: to.Ptr(true),
+									Capabilities: &corev1.Capabilities{
+										Drop: []corev1.Capability{"ALL"},
+										Add:  []corev1.Capability{"NET_BIND_SERVICE"},
+									},
+								},
+							},
+						},
+					}),
+				},
+			},
+		},
+	}
+}
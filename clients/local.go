@@ -0,0 +1,528 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+	"golang.org/x/exp/slices"
+	"golang.org/x/sync/errgroup"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+)
+
+const (
+	fieldOwner         = "external-dns-e2e"
+	localStableTimeout = 10 * time.Minute
+	localPollInterval  = 2 * time.Second
+	aksClusterLabelKey = "kubernetes.azure.com/cluster"
+)
+
+// local is a cluster reached directly through a kubeconfig, like a kind cluster or an existing dev cluster.
+// It implements the same operations as aks without going through ARM RunCommand
+type local struct {
+	name       string
+	kubeconfig string
+	options    map[string]struct{}
+}
+
+// Retrieves objects from infrastructure file to create local instance
+func LoadLocal(name, kubeconfig string, options map[string]struct{}) *local {
+	return &local{
+		name:       name,
+		kubeconfig: kubeconfig,
+		options:    options,
+	}
+}
+
+// Creates a local cluster from a kubeconfig and checks that the api server is reachable
+func NewLocal(ctx context.Context, name, kubeconfig string) (*local, error) {
+	lgr := logger.FromContext(ctx).With("name", name, "kubeconfig", kubeconfig)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to connect to local cluster")
+	defer lgr.Info("finished connecting to local cluster")
+
+	l := &local{
+		name:       name,
+		kubeconfig: kubeconfig,
+		options:    map[string]struct{}{},
+	}
+
+	c, err := l.client()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.List(ctx, &corev1.NamespaceList{}, client.Limit(1)); err != nil {
+		return nil, fmt.Errorf("reaching api server: %w", err)
+	}
+
+	return l, nil
+}
+
+func (l *local) restConfig() (*rest.Config, error) {
+	cfg, err := clientcmd.BuildConfigFromFlags("", l.kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("loading kubeconfig %s: %w", l.kubeconfig, err)
+	}
+
+	return cfg, nil
+}
+
+func (l *local) client() (client.Client, error) {
+	cfg, err := l.restConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme.Scheme})
+	if err != nil {
+		return nil, fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	return c, nil
+}
+
+// Deploys given client.Objects to cluster with server side apply
+func (l *local) Deploy(ctx context.Context, objs []client.Object) error {
+	lgr := logger.FromContext(ctx).With("name", l.name)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to deploy resources")
+	defer lgr.Info("finished deploying resources")
+
+	c, err := l.client()
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		copy := withoutAksNodeAffinity(obj.DeepCopyObject().(client.Object))
+		copy.SetResourceVersion("")
+		copy.SetManagedFields(nil)
+
+		if err := c.Patch(ctx, copy, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership); err != nil {
+			return fmt.Errorf("applying %s/%s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+	}
+
+	if err := l.waitStable(ctx, objs); err != nil {
+		return fmt.Errorf("waiting for resources to be stable: %w", err)
+	}
+
+	return nil
+}
+
+// withoutAksNodeAffinity drops the required node affinity on nodes labelled by AKS so workloads built for
+// AKS system nodes can schedule onto any cluster
+func withoutAksNodeAffinity(obj client.Object) client.Object {
+	var spec *corev1.PodSpec
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		spec = &o.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		spec = &o.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		spec = &o.Spec.Template.Spec
+	case *batchv1.Job:
+		spec = &o.Spec.Template.Spec
+	case *corev1.Pod:
+		spec = &o.Spec
+	default:
+		return obj
+	}
+
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil || spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return obj
+	}
+
+	required := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	terms := []corev1.NodeSelectorTerm{}
+	for _, term := range required.NodeSelectorTerms {
+		aksOnly := slices.ContainsFunc(term.MatchExpressions, func(req corev1.NodeSelectorRequirement) bool {
+			return req.Key == aksClusterLabelKey
+		})
+		if !aksOnly {
+			terms = append(terms, term)
+		}
+	}
+
+	if len(terms) == 0 {
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = nil
+	} else {
+		required.NodeSelectorTerms = terms
+	}
+
+	return obj
+}
+
+// Waits for given pods, workloads, and jobs to complete
+func (l *local) waitStable(ctx context.Context, objs []client.Object) error {
+	lgr := logger.FromContext(ctx).With("name", l.name)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to wait for resources to be stable")
+	defer lgr.Info("finished waiting for resources to be stable")
+
+	c, err := l.client()
+	if err != nil {
+		return err
+	}
+
+	var eg errgroup.Group
+	for _, obj := range objs {
+		func(obj client.Object) {
+			eg.Go(func() error {
+				kind := obj.GetObjectKind().GroupVersionKind().GroupKind().Kind
+				ns := obj.GetNamespace()
+				if ns == "" {
+					ns = "default"
+				}
+				key := types.NamespacedName{Namespace: ns, Name: obj.GetName()}
+
+				lgr := lgr.With("kind", kind, "name", obj.GetName(), "namespace", ns)
+				lgr.Info("checking stability of " + kind + "/" + obj.GetName())
+
+				var done wait.ConditionWithContextFunc
+				switch {
+				case slices.Contains(workloadKinds, kind):
+					done = func(ctx context.Context) (bool, error) {
+						return workloadReady(ctx, c, kind, key)
+					}
+				case kind == "Pod":
+					done = func(ctx context.Context) (bool, error) {
+						pod := &corev1.Pod{}
+						if err := c.Get(ctx, key, pod); err != nil {
+							return false, client.IgnoreNotFound(err)
+						}
+						for _, cond := range pod.Status.Conditions {
+							if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+								return true, nil
+							}
+						}
+						return false, nil
+					}
+				case kind == "Job":
+					outputFile := fmt.Sprintf("job-%s.log", obj.GetName()) // output to a file for jobs, same as aks
+					if err := os.RemoveAll(outputFile); err != nil {
+						return fmt.Errorf("removing previous job log file: %w", err)
+					}
+
+					done = func(ctx context.Context) (bool, error) {
+						job := &batchv1.Job{}
+						if err := c.Get(ctx, key, job); err != nil {
+							return false, client.IgnoreNotFound(err)
+						}
+						for _, cond := range job.Status.Conditions {
+							if cond.Status != corev1.ConditionTrue {
+								continue
+							}
+
+							switch cond.Type {
+							case batchv1.JobComplete:
+								return true, l.writeJobLogs(ctx, key, outputFile)
+							case batchv1.JobFailed:
+								l.writeJobLogs(ctx, key, outputFile)
+								return false, fmt.Errorf("job/%s failed", obj.GetName())
+							}
+						}
+						return false, nil
+					}
+				default:
+					return nil
+				}
+
+				if err := wait.PollUntilContextTimeout(ctx, localPollInterval, localStableTimeout, true, done); err != nil {
+					return fmt.Errorf("waiting for %s/%s to be stable: %w", kind, obj.GetName(), err)
+				}
+
+				return nil
+			})
+		}(obj)
+	}
+
+	if err := eg.Wait(); err != nil {
+		return fmt.Errorf("waiting for resources to be stable: %w", err)
+	}
+
+	return nil
+}
+
+// workloadReady reports whether every replica of a workload has been rolled out, like kubectl rollout status
+func workloadReady(ctx context.Context, c client.Client, kind string, key types.NamespacedName) (bool, error) {
+	switch kind {
+	case "Deployment":
+		deploy := &appsv1.Deployment{}
+		if err := c.Get(ctx, key, deploy); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		replicas := int32(1)
+		if deploy.Spec.Replicas != nil {
+			replicas = *deploy.Spec.Replicas
+		}
+		return deploy.Status.ObservedGeneration >= deploy.Generation &&
+			deploy.Status.UpdatedReplicas == replicas &&
+			deploy.Status.AvailableReplicas == replicas, nil
+	case "StatefulSet":
+		sts := &appsv1.StatefulSet{}
+		if err := c.Get(ctx, key, sts); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		replicas := int32(1)
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+		return sts.Status.ObservedGeneration >= sts.Generation &&
+			sts.Status.UpdatedReplicas == replicas &&
+			sts.Status.ReadyReplicas == replicas, nil
+	case "DaemonSet":
+		ds := &appsv1.DaemonSet{}
+		if err := c.Get(ctx, key, ds); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return ds.Status.ObservedGeneration >= ds.Generation &&
+			ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled &&
+			ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled, nil
+	}
+
+	return true, nil
+}
+
+// writeJobLogs appends the logs of every pod belonging to a job to outputFile
+func (l *local) writeJobLogs(ctx context.Context, job types.NamespacedName, outputFile string) error {
+	cfg, err := l.restConfig()
+	if err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("creating kubernetes clientset: %w", err)
+	}
+
+	pods, err := clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + job.Name})
+	if err != nil {
+		return fmt.Errorf("listing pods for job/%s: %w", job.Name, err)
+	}
+
+	f, err := os.OpenFile(outputFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("creating output file %s: %w", outputFile, err)
+	}
+	defer f.Close()
+
+	for _, pod := range pods.Items {
+		logs, err := clientset.CoreV1().Pods(job.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+		if err != nil {
+			return fmt.Errorf("getting logs for pod/%s: %w", pod.Name, err)
+		}
+
+		if _, err := f.Write(logs); err != nil {
+			return fmt.Errorf("writing output file %s: %w", outputFile, err)
+		}
+	}
+
+	return nil
+}
+
+// Returns the service with the given name and namespace
+func (l *local) GetService(ctx context.Context, namespace, name string) (*corev1.Service, error) {
+	c, err := l.client()
+	if err != nil {
+		return nil, err
+	}
+
+	svc := &corev1.Service{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, svc); err != nil {
+		return nil, fmt.Errorf("getting service/%s: %w", name, err)
+	}
+
+	return svc, nil
+}
+
+// Returns the deployment with the given name and namespace
+func (l *local) GetDeployment(ctx context.Context, namespace, name string) (*appsv1.Deployment, error) {
+	c, err := l.client()
+	if err != nil {
+		return nil, err
+	}
+
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, deploy); err != nil {
+		return nil, fmt.Errorf("getting deployment/%s: %w", name, err)
+	}
+
+	return deploy, nil
+}
+
+// Returns whether an object exists
+func (l *local) Exists(ctx context.Context, obj client.Object) (bool, error) {
+	c, err := l.client()
+	if err != nil {
+		return false, err
+	}
+
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	got := obj.DeepCopyObject().(client.Object)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), got); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("getting %s/%s: %w", kind, obj.GetName(), err)
+	}
+
+	return true, nil
+}
+
+// Adds or overwrites annotations on a service
+func (l *local) AnnotateService(ctx context.Context, namespace, name string, annotations map[string]string) error {
+	patch := map[string]*string{}
+	for key, value := range annotations {
+		patch[key] = to.Ptr(value)
+	}
+
+	return l.patchServiceAnnotations(ctx, namespace, name, patch)
+}
+
+// Removes the given annotation keys from a service
+func (l *local) RemoveServiceAnnotations(ctx context.Context, namespace, name string, keys []string) error {
+	patch := map[string]*string{}
+	for _, key := range keys {
+		patch[key] = nil // null removes the key in a merge patch
+	}
+
+	return l.patchServiceAnnotations(ctx, namespace, name, patch)
+}
+
+// Deletes an object, a missing object isn't an error
+func (l *local) Delete(ctx context.Context, obj client.Object) error {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	lgr := logger.FromContext(ctx).With("name", l.name, "kind", kind, "object", obj.GetName())
+	lgr.Info("starting to delete object")
+	defer lgr.Info("finished deleting object")
+
+	c, err := l.client()
+	if err != nil {
+		return err
+	}
+
+	if err := c.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("deleting %s/%s: %w", kind, obj.GetName(), err)
+	}
+
+	return nil
+}
+
+// Deletes every object of the kind of obj in its namespace with all of the given labels
+func (l *local) DeleteAllOf(ctx context.Context, obj client.Object, matchLabels map[string]string) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	lgr := logger.FromContext(ctx).With("name", l.name, "kind", gvk.Kind, "labels", matchLabels)
+	lgr.Info("starting to delete objects")
+	defer lgr.Info("finished deleting objects")
+
+	c, err := l.client()
+	if err != nil {
+		return err
+	}
+
+	// listing and deleting one by one like kubectl, services don't support deleting a collection
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := c.List(ctx, list, client.InNamespace(obj.GetNamespace()), client.MatchingLabels(matchLabels)); err != nil {
+		return fmt.Errorf("listing %s: %w", gvk.Kind, err)
+	}
+
+	for i := range list.Items {
+		if err := c.Delete(ctx, &list.Items[i]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting %s/%s: %w", gvk.Kind, list.Items[i].GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+func (l *local) patchServiceAnnotations(ctx context.Context, namespace, name string, annotations map[string]*string) error {
+	lgr := logger.FromContext(ctx).With("name", l.name, "service", name)
+	lgr.Info("starting to patch service annotations")
+	defer lgr.Info("finished patching service annotations")
+
+	c, err := l.client()
+	if err != nil {
+		return err
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": annotations}})
+	if err != nil {
+		return fmt.Errorf("marshalling annotation patch: %w", err)
+	}
+
+	if err := c.Patch(ctx, svc, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("service/%s not found: %w", name, err)
+		}
+		return fmt.Errorf("patching service/%s annotations: %w", name, err)
+	}
+
+	return nil
+}
+
+// Returns a managed cluster shaped description of the local cluster, only the name and id are populated
+func (l *local) GetCluster(ctx context.Context) (*armcontainerservice.ManagedCluster, error) {
+	return &armcontainerservice.ManagedCluster{
+		Name: to.Ptr(l.name),
+		ID:   to.Ptr(l.GetId()),
+	}, nil
+}
+
+func (l *local) GetVnetId(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("local cluster %s is not attached to an azure vnet", l.name)
+}
+
+func (l *local) GetName() string {
+	return l.name
+}
+
+// GetId returns a stable identifier for the cluster, used as the external-dns txt owner id
+func (l *local) GetId() string {
+	return "local-" + l.name
+}
+
+func (l *local) GetKubeconfig() string {
+	return l.kubeconfig
+}
+
+func (l *local) GetPrincipalId() string {
+	return ""
+}
+
+func (l *local) GetLocation() string {
+	return "local"
+}
+
+func (l *local) GetDnsServiceIp() string {
+	return ""
+}
+
+func (l *local) GetClientId() string {
+	return ""
+}
+
+func (l *local) GetOptions() map[string]struct{} {
+	return l.options
+}
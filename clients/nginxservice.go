@@ -3,6 +3,7 @@ package clients
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -49,14 +50,20 @@ func NewNginxDeployment() *appsv1.Deployment {
 
 // Returns nginx services with necessary config to create ipv4 and ipv6 records
 func NewNginxServices(zoneName string) (*corev1.Service, *corev1.Service) {
-	ipv4Service := &corev1.Service{
+	return NewNginxService("nginx-svc-ipv4", "", nil), NewNginxService("nginx-svc-ipv6", corev1.IPv6Protocol, nil)
+}
+
+// Returns a load balancer service in front of the nginx deployment. An empty ipFamily leaves the choice to the cluster
+func NewNginxService(name string, ipFamily corev1.IPFamily, annotations map[string]string) *corev1.Service {
+	svc := &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Service",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "nginx-svc-ipv4",
-			Namespace: "kube-system",
+			Name:        name,
+			Namespace:   "kube-system",
+			Annotations: annotations,
 		},
 		Spec: corev1.ServiceSpec{
 			ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyCluster,
@@ -72,31 +79,54 @@ func NewNginxServices(zoneName string) (*corev1.Service, *corev1.Service) {
 		},
 	}
 
-	ipv6Service := &corev1.Service{
+	if ipFamily != "" {
+		svc.Spec.IPFamilies = []corev1.IPFamily{ipFamily}
+	}
+
+	return svc
+}
+
+// Returns an ingress routing every host to the nginx service serviceName
+func NewNginxIngress(name, serviceName string, hosts []string, annotations map[string]string) *networkingv1.Ingress {
+	pathType := networkingv1.PathTypePrefix
+
+	rules := make([]networkingv1.IngressRule, len(hosts))
+	for i, host := range hosts {
+		rules[i] = networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{
+						{
+							Path:     "/",
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: serviceName,
+									Port: networkingv1.ServiceBackendPort{Number: 80},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return &networkingv1.Ingress{
 		TypeMeta: metav1.TypeMeta{
-			Kind:       "Service",
-			APIVersion: "v1",
+			Kind:       "Ingress",
+			APIVersion: "networking.k8s.io/v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "nginx-svc-ipv6",
-			Namespace: "kube-system",
+			Name:        name,
+			Namespace:   "kube-system",
+			Annotations: annotations,
 		},
-		Spec: corev1.ServiceSpec{
-			ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyCluster,
-			Type:                  corev1.ServiceTypeLoadBalancer,
-			Selector:              map[string]string{"app": "nginx"},
-			Ports: []corev1.ServicePort{
-				{
-					Protocol:   "TCP",
-					Port:       80,
-					TargetPort: intstr.FromInt(80),
-				},
-			},
-			IPFamilies: []corev1.IPFamily{corev1.IPv6Protocol},
+		Spec: networkingv1.IngressSpec{
+			Rules: rules,
 		},
 	}
-
-	return ipv4Service, ipv6Service
 }
 
 func WithPreferSystemNodes(spec *corev1.PodSpec) *corev1.PodSpec {
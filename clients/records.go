@@ -0,0 +1,287 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+)
+
+// RecordSet is a record set of a public or private zone independently of the arm type it was read from. Values are
+// sorted and formatted like DnsAnswer values: ips for A and AAAA records, the target without a trailing dot for CNAME
+// records, the joined strings of TXT records and the preference and exchange of MX records
+type RecordSet struct {
+	// Name is relative to the zone, @ for the apex
+	Name string
+	// Fqdn has no trailing dot
+	Fqdn   string
+	Type   string
+	Ttl    int64
+	Etag   string
+	Values []string
+}
+
+// RecordQuery selects the record sets of one type in a public or private zone
+type RecordQuery struct {
+	SubscriptionId string
+	ResourceGroup  string
+	Zone           string
+	Private        bool
+	Type           string
+	// Name is a name relative to Zone to return only the record set with, every record set of Type is returned when empty
+	Name string
+}
+
+// RecordSets are the record sets a query matched and the number of pages listed to find them
+type RecordSets struct {
+	RecordSets []RecordSet
+	Pages      int
+}
+
+// RelativeName returns the name of fqdn relative to zone, @ for the apex
+func RelativeName(fqdn, zone string) string {
+	fqdn = strings.TrimSuffix(fqdn, ".")
+	zone = strings.TrimSuffix(zone, ".")
+	if strings.EqualFold(fqdn, zone) {
+		return "@"
+	}
+
+	suffix := "." + zone
+	if len(fqdn) > len(suffix) && strings.EqualFold(fqdn[len(fqdn)-len(suffix):], suffix) {
+		return fqdn[:len(fqdn)-len(suffix)]
+	}
+	return fqdn
+}
+
+// QueryRecordSets lists every page of the record sets of q.Type in the zone and returns the ones matching q
+func QueryRecordSets(ctx context.Context, q RecordQuery) (RecordSets, error) {
+	cred, err := GetAzCred()
+	if err != nil {
+		return RecordSets{}, fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	var ret RecordSets
+	add := func(rs RecordSet) {
+		if q.Name == "" || strings.EqualFold(rs.Name, q.Name) {
+			ret.RecordSets = append(ret.RecordSets, rs)
+		}
+	}
+
+	if q.Private {
+		factory, err := armprivatedns.NewClientFactory(q.SubscriptionId, cred, GetClientOptions())
+		if err != nil {
+			return RecordSets{}, fmt.Errorf("creating private dns client factory: %w", err)
+		}
+
+		pager := factory.NewRecordSetsClient().NewListByTypePager(q.ResourceGroup, q.Zone, armprivatedns.RecordType(q.Type), nil)
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return RecordSets{}, fmt.Errorf("listing %s record sets in private zone %s: %w", q.Type, q.Zone, err)
+			}
+
+			ret.Pages++
+			for _, rs := range page.Value {
+				add(fromPrivateRecordSet(q.Zone, q.Type, rs))
+			}
+		}
+
+		return ret, nil
+	}
+
+	factory, err := armdns.NewClientFactory(q.SubscriptionId, cred, GetClientOptions())
+	if err != nil {
+		return RecordSets{}, fmt.Errorf("creating dns client factory: %w", err)
+	}
+
+	pager := factory.NewRecordSetsClient().NewListByTypePager(q.ResourceGroup, q.Zone, armdns.RecordType(q.Type), nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return RecordSets{}, fmt.Errorf("listing %s record sets in zone %s: %w", q.Type, q.Zone, err)
+		}
+
+		ret.Pages++
+		for _, rs := range page.Value {
+			add(fromRecordSet(q.Zone, q.Type, rs))
+		}
+	}
+
+	return ret, nil
+}
+
+// GetRecordSet gets the record set named q.Name of q.Type directly, without listing the zone, and returns nil if
+// there's none. q.Name is required
+func GetRecordSet(ctx context.Context, q RecordQuery) (*RecordSet, error) {
+	if q.Name == "" {
+		return nil, fmt.Errorf("getting %s record set in zone %s: name is required", q.Type, q.Zone)
+	}
+
+	cred, err := GetAzCred()
+	if err != nil {
+		return nil, fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	if q.Private {
+		client, err := armprivatedns.NewRecordSetsClient(q.SubscriptionId, cred, GetClientOptions())
+		if err != nil {
+			return nil, fmt.Errorf("creating private record sets client: %w", err)
+		}
+
+		resp, err := client.Get(ctx, q.ResourceGroup, q.Zone, armprivatedns.RecordType(q.Type), q.Name, nil)
+		if isNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting %s record set %s in private zone %s: %w", q.Type, q.Name, q.Zone, err)
+		}
+
+		rs := fromPrivateRecordSet(q.Zone, q.Type, &resp.RecordSet)
+		return &rs, nil
+	}
+
+	client, err := armdns.NewRecordSetsClient(q.SubscriptionId, cred, GetClientOptions())
+	if err != nil {
+		return nil, fmt.Errorf("creating record sets client: %w", err)
+	}
+
+	resp, err := client.Get(ctx, q.ResourceGroup, q.Zone, q.Name, armdns.RecordType(q.Type), nil)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting %s record set %s in zone %s: %w", q.Type, q.Name, q.Zone, err)
+	}
+
+	rs := fromRecordSet(q.Zone, q.Type, &resp.RecordSet)
+	return &rs, nil
+}
+
+// Diff compares the values of rs with want as sets and returns the values missing from rs and the values rs holds that
+// aren't wanted. Ips are compared in their canonical form, a nil rs is missing every value
+func (rs *RecordSet) Diff(want []string) (missing, extra []string) {
+	got := map[string]bool{}
+	if rs != nil {
+		for _, value := range rs.Values {
+			got[canonicalValue(value)] = true
+		}
+	}
+
+	wanted := map[string]bool{}
+	for _, value := range want {
+		value = canonicalValue(value)
+		wanted[value] = true
+		if !got[value] {
+			missing = append(missing, value)
+		}
+	}
+	if rs != nil {
+		for _, value := range rs.Values {
+			if !wanted[canonicalValue(value)] {
+				extra = append(extra, value)
+			}
+		}
+	}
+
+	return missing, extra
+}
+
+// canonicalValue formats ips the same way however they were written, other values are compared case insensitively
+// like dns names
+func canonicalValue(value string) string {
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.String()
+	}
+	return strings.ToLower(strings.TrimSuffix(value, "."))
+}
+
+func fromRecordSet(zone, recordType string, rs *armdns.RecordSet) RecordSet {
+	ret := RecordSet{Type: recordType}
+	if rs.Name != nil {
+		ret.Name = *rs.Name
+	}
+	if rs.Etag != nil {
+		ret.Etag = *rs.Etag
+	}
+
+	props := rs.Properties
+	if props == nil {
+		return ret
+	}
+	if props.Fqdn != nil {
+		ret.Fqdn = strings.TrimSuffix(*props.Fqdn, ".")
+	}
+	if props.TTL != nil {
+		ret.Ttl = *props.TTL
+	}
+
+	for _, a := range props.ARecords {
+		ret.Values = appendValue(ret.Values, a.IPv4Address)
+	}
+	for _, aaaa := range props.AaaaRecords {
+		ret.Values = appendValue(ret.Values, aaaa.IPv6Address)
+	}
+	if props.CnameRecord != nil {
+		ret.Values = appendValue(ret.Values, props.CnameRecord.Cname)
+	}
+	for _, txt := range props.TxtRecords {
+		ret.Values = append(ret.Values, joinTxt(txt.Value))
+	}
+	for _, mx := range props.MxRecords {
+		ret.Values = append(ret.Values, mxValue(mx.Preference, mx.Exchange))
+	}
+
+	return finishRecordSet(ret, zone)
+}
+
+// fromPrivateRecordSet reads rs like the public record set it has the same fields as
+func fromPrivateRecordSet(zone, recordType string, rs *armprivatedns.RecordSet) RecordSet {
+	return fromRecordSet(zone, recordType, &armdns.RecordSet{Name: rs.Name, Etag: rs.Etag, Properties: publicProperties(rs.Properties)})
+}
+
+// finishRecordSet fills in the fqdn when arm didn't return one and sorts the values
+func finishRecordSet(rs RecordSet, zone string) RecordSet {
+	if rs.Fqdn == "" {
+		rs.Fqdn = strings.TrimSuffix(zone, ".")
+		if rs.Name != "@" {
+			rs.Fqdn = rs.Name + "." + rs.Fqdn
+		}
+	}
+
+	sort.Strings(rs.Values)
+	return rs
+}
+
+func appendValue(values []string, value *string) []string {
+	if value == nil {
+		return values
+	}
+	return append(values, strings.TrimSuffix(*value, "."))
+}
+
+// TXT values longer than 255 characters are split into several strings, owner ids of aks clusters can be long enough
+func joinTxt(value []*string) string {
+	var b strings.Builder
+	for _, v := range value {
+		if v != nil {
+			b.WriteString(*v)
+		}
+	}
+	return b.String()
+}
+
+func mxValue(preference *int32, exchange *string) string {
+	var p int32
+	if preference != nil {
+		p = *preference
+	}
+	var e string
+	if exchange != nil {
+		e = strings.TrimSuffix(*exchange, ".")
+	}
+	return fmt.Sprintf("%d %s", p, e)
+}
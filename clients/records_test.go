@@ -0,0 +1,48 @@
+package clients
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+)
+
+func TestQueryRecordSets(t *testing.T) {
+	fake := useTestFakeDns(t)
+	fake.SetPageSize(2)
+	ctx := context.Background()
+	c := newTestRecordSetsClient(t)
+
+	for _, name := range []string{"@", "a", "b", "c", "d"} {
+		if _, err := c.CreateOrUpdate(ctx, testResourceGroup, testZone, name, armdns.RecordTypeA, aRecordSet(300, "192.0.2.1"), nil); err != nil {
+			t.Fatalf("creating record set %s: %s", name, err)
+		}
+	}
+
+	q := RecordQuery{SubscriptionId: testSubscriptionId, ResourceGroup: testResourceGroup, Zone: testZone, Type: "A"}
+	all, err := QueryRecordSets(ctx, q)
+	if err != nil {
+		t.Fatalf("querying record sets: %s", err)
+	}
+	if len(all.RecordSets) != 5 || all.Pages != 3 {
+		t.Errorf("queried %d record sets over %d pages, expected 5 over 3", len(all.RecordSets), all.Pages)
+	}
+
+	q.Name = RelativeName(testZone, testZone)
+	apex, err := GetRecordSet(ctx, q)
+	if err != nil {
+		t.Fatalf("getting apex record set: %s", err)
+	}
+	if apex == nil || apex.Fqdn != testZone {
+		t.Errorf("apex record set is %+v, expected fqdn %s", apex, testZone)
+	}
+
+	q.Name, q.Private = "d", true
+	missing, err := GetRecordSet(ctx, q)
+	if err != nil {
+		t.Fatalf("getting private record set: %s", err)
+	}
+	if missing != nil {
+		t.Errorf("got private record set %+v, expected none", missing)
+	}
+}
@@ -0,0 +1,167 @@
+package clients
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+)
+
+const (
+	dnsPort        = "53"
+	resolveTimeout = 5 * time.Second
+	// udp answers are limited to 512 bytes without edns, larger ones come back truncated and need tcp
+	maxUdpMessageSize = 512
+)
+
+var dnsRecordTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+// DnsAnswer is a record a nameserver answered with, Value is formatted like the records in arm: ips for A and AAAA
+// records, the target without a trailing dot for CNAME records and the joined strings of TXT records
+type DnsAnswer struct {
+	Value string
+	Ttl   uint32
+}
+
+// Resolve queries nameserver directly for the recordType records of fqdn over network, udp or tcp, without recursion or
+// caching so the answer is what the zone currently holds. Nameservers without a port are queried on 53. A name without
+// records of recordType returns no answers and no error
+func Resolve(ctx context.Context, nameserver, network, fqdn, recordType string) ([]DnsAnswer, error) {
+	lgr := logger.FromContext(ctx).With("nameserver", nameserver, "network", network, "fqdn", fqdn, "recordType", recordType)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to resolve")
+	defer lgr.Info("finished resolving")
+
+	qtype, ok := dnsRecordTypes[strings.ToUpper(recordType)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported record type %s", recordType)
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("unsupported network %s", network)
+	}
+
+	name, err := dnsmessage.NewName(strings.TrimSuffix(fqdn, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("parsing name %s: %w", fqdn, err)
+	}
+
+	id := uint16(rand.Intn(1 << 16))
+	query, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	if err != nil {
+		return nil, fmt.Errorf("packing query: %w", err)
+	}
+
+	if _, _, err := net.SplitHostPort(nameserver); err != nil {
+		nameserver = net.JoinHostPort(strings.TrimSuffix(nameserver, "."), dnsPort)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("dialing %s: %w", nameserver, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	resp, err := exchangeDns(conn, network, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s: %w", nameserver, err)
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		return nil, fmt.Errorf("unpacking response: %w", err)
+	}
+	if msg.Header.ID != id {
+		return nil, fmt.Errorf("response id %d doesn't match query id %d", msg.Header.ID, id)
+	}
+	if msg.Header.Truncated {
+		return nil, errors.New("response truncated, query over tcp instead")
+	}
+	switch msg.Header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("nameserver responded %s", msg.Header.RCode)
+	}
+
+	var answers []DnsAnswer
+	for _, rr := range msg.Answers {
+		if rr.Header.Type != qtype || !strings.EqualFold(rr.Header.Name.String(), name.String()) {
+			continue
+		}
+
+		answer := DnsAnswer{Ttl: rr.Header.TTL}
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			answer.Value = net.IP(body.A[:]).String()
+		case *dnsmessage.AAAAResource:
+			answer.Value = net.IP(body.AAAA[:]).String()
+		case *dnsmessage.CNAMEResource:
+			answer.Value = strings.TrimSuffix(body.CNAME.String(), ".")
+		case *dnsmessage.TXTResource:
+			answer.Value = strings.Join(body.TXT, "")
+		default:
+			continue
+		}
+		answers = append(answers, answer)
+	}
+
+	return answers, nil
+}
+
+// exchangeDns writes query to conn and reads the response, tcp messages are prefixed with their length
+func exchangeDns(conn net.Conn, network string, query []byte) ([]byte, error) {
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, fmt.Errorf("writing query: %w", err)
+		}
+
+		resp := make([]byte, maxUdpMessageSize)
+		n, err := conn.Read(resp)
+		if err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
+		return resp[:n], nil
+	}
+
+	if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(query)))); err != nil {
+		return nil, fmt.Errorf("writing query length: %w", err)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, fmt.Errorf("writing query: %w", err)
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, fmt.Errorf("reading response length: %w", err)
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,126 @@
+package clients
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+)
+
+// serveTestNameserver serves the fake as a nameserver on a free local port until the test ends, returning its address
+func serveTestNameserver(t *testing.T, fake *FakeDns) string {
+	t.Helper()
+
+	// ServeDns listens on the same port for udp and tcp, so a port free for tcp is picked and reused for both
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("finding a free port: %s", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := fake.ServeDns(ctx, addr); err != nil {
+		t.Fatalf("serving nameserver: %s", err)
+	}
+	return addr
+}
+
+func TestResolve(t *testing.T) {
+	fake := useTestFakeDns(t)
+	nameserver := serveTestNameserver(t, fake)
+	ctx := context.Background()
+	c := newTestRecordSetsClient(t)
+
+	recordSets := map[armdns.RecordType]map[string]*armdns.RecordSetProperties{
+		armdns.RecordTypeA: {
+			"www": {TTL: to.Ptr[int64](300), ARecords: []*armdns.ARecord{{IPv4Address: to.Ptr("192.0.2.1")}, {IPv4Address: to.Ptr("192.0.2.2")}}},
+		},
+		armdns.RecordTypeAAAA: {
+			"www": {TTL: to.Ptr[int64](120), AaaaRecords: []*armdns.AaaaRecord{{IPv6Address: to.Ptr("2001:db8::1")}}},
+		},
+		armdns.RecordTypeCNAME: {
+			"alias": {TTL: to.Ptr[int64](60), CnameRecord: &armdns.CnameRecord{Cname: to.Ptr("www.example.com.")}},
+		},
+		armdns.RecordTypeTXT: {
+			// longer than a single 255 byte string, so it's split on the wire and joined again
+			"www": {TTL: to.Ptr[int64](30), TxtRecords: []*armdns.TxtRecord{{Value: []*string{to.Ptr(strings.Repeat("o", 300))}}}},
+			// more than a udp response holds
+			"big": {TTL: to.Ptr[int64](30), TxtRecords: []*armdns.TxtRecord{
+				{Value: []*string{to.Ptr(strings.Repeat("a", 200))}},
+				{Value: []*string{to.Ptr(strings.Repeat("b", 200))}},
+				{Value: []*string{to.Ptr(strings.Repeat("c", 200))}},
+			}},
+		},
+	}
+	for recordType, byName := range recordSets {
+		for name, props := range byName {
+			if _, err := c.CreateOrUpdate(ctx, testResourceGroup, testZone, name, recordType, armdns.RecordSet{Properties: props}, nil); err != nil {
+				t.Fatalf("creating %s record set %s: %s", recordType, name, err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		fqdn       string
+		recordType string
+		want       []DnsAnswer
+		wantErr    string
+		udpOnly    bool
+	}{
+		{name: "A", fqdn: "www.example.com", recordType: "A", want: []DnsAnswer{{"192.0.2.1", 300}, {"192.0.2.2", 300}}},
+		{name: "AAAA", fqdn: "www.example.com.", recordType: "AAAA", want: []DnsAnswer{{"2001:db8::1", 120}}},
+		{name: "CNAME", fqdn: "alias.example.com", recordType: "CNAME", want: []DnsAnswer{{"www.example.com", 60}}},
+		{name: "TXT", fqdn: "www.example.com", recordType: "TXT", want: []DnsAnswer{{strings.Repeat("o", 300), 30}}},
+		{name: "no records of type", fqdn: "alias.example.com", recordType: "TXT"},
+		{name: "NXDOMAIN", fqdn: "missing.example.com", recordType: "A"},
+		{name: "outside every zone", fqdn: "www.example.org", recordType: "A", wantErr: "Refused"},
+		{name: "truncated", fqdn: "big.example.com", recordType: "TXT", wantErr: "truncated", udpOnly: true},
+	}
+
+	for _, network := range []string{"udp", "tcp"} {
+		for _, tt := range tests {
+			if tt.udpOnly && network != "udp" {
+				continue
+			}
+
+			t.Run(network+" "+tt.name, func(t *testing.T) {
+				got, err := Resolve(ctx, nameserver, network, tt.fqdn, tt.recordType)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("resolving returned %v, expected an error containing %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("resolving: %s", err)
+				}
+
+				if len(got) != len(tt.want) {
+					t.Fatalf("got answers %v, expected %v", got, tt.want)
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Errorf("answer %d is %v, expected %v", i, got[i], tt.want[i])
+					}
+				}
+			})
+		}
+	}
+
+	// the answers cut off over udp come back whole over tcp
+	t.Run("tcp after truncation", func(t *testing.T) {
+		got, err := Resolve(ctx, nameserver, "tcp", "big.example.com", "TXT")
+		if err != nil {
+			t.Fatalf("resolving: %s", err)
+		}
+		if len(got) != 3 {
+			t.Errorf("got %d answers, expected 3", len(got))
+		}
+	})
+}
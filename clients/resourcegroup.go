@@ -2,9 +2,14 @@ package clients
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
@@ -19,6 +24,8 @@ type rg struct {
 
 type RgOpt func(rg *armresources.ResourceGroup) error
 
+const deletionDueTimeTag = "deletion_due_time"
+
 // Used for testing purposes: deletes resource group after specified duration
 func DeleteAfterOpt(d time.Duration) RgOpt {
 	return func(rg *armresources.ResourceGroup) error {
@@ -27,7 +34,7 @@ func DeleteAfterOpt(d time.Duration) RgOpt {
 		}
 
 		rg.Tags["deletion_marked_by"] = to.Ptr("gc")
-		rg.Tags["deletion_due_time"] = to.Ptr(fmt.Sprint(time.Now().Add(d).Unix()))
+		rg.Tags[deletionDueTimeTag] = to.Ptr(fmt.Sprint(time.Now().Add(d).Unix()))
 
 		return nil
 	}
@@ -53,7 +60,7 @@ func NewResourceGroup(ctx context.Context, subscriptionId, name, location string
 		return nil, fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	client, err := armresources.NewResourceGroupsClient(subscriptionId, cred, nil)
+	client, err := armresources.NewResourceGroupsClient(subscriptionId, cred, GetClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("creating resource group client: %w", err)
 	}
@@ -93,3 +100,121 @@ func (r *rg) GetName() string {
 func (r *rg) GetId() string {
 	return r.id
 }
+
+// ExpiredResourceGroup is a resource group whose deletion_due_time tag has passed
+type ExpiredResourceGroup struct {
+	Name    string
+	DueTime time.Time
+}
+
+// Lists resource groups starting with prefix whose deletion_due_time set by DeleteAfterOpt is before now. Groups without
+// the tag or with an unparsable one are never returned
+func ListExpiredResourceGroups(ctx context.Context, subscriptionId, prefix string, now time.Time) ([]ExpiredResourceGroup, error) {
+	lgr := logger.FromContext(ctx).With("prefix", prefix, "subscriptionId", subscriptionId)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to list expired resource groups")
+	defer lgr.Info("finished listing expired resource groups")
+
+	cred, err := GetAzCred()
+	if err != nil {
+		return nil, fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	client, err := armresources.NewResourceGroupsClient(subscriptionId, cred, GetClientOptions())
+	if err != nil {
+		return nil, fmt.Errorf("creating resource group client: %w", err)
+	}
+
+	var expired []ExpiredResourceGroup
+	pager := client.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing resource groups: %w", err)
+		}
+
+		for _, group := range page.Value {
+			if group.Name == nil || !strings.HasPrefix(strings.ToLower(*group.Name), strings.ToLower(prefix)) {
+				continue
+			}
+
+			due, ok := group.Tags[deletionDueTimeTag]
+			if !ok || due == nil {
+				continue
+			}
+
+			unix, err := strconv.ParseInt(*due, 10, 64)
+			if err != nil {
+				lgr.Info("skipping resource group with unparsable "+deletionDueTimeTag, "resourceGroup", *group.Name, "value", *due)
+				continue
+			}
+
+			dueTime := time.Unix(unix, 0)
+			if dueTime.Before(now) {
+				expired = append(expired, ExpiredResourceGroup{Name: *group.Name, DueTime: dueTime})
+			}
+		}
+	}
+
+	return expired, nil
+}
+
+// Returns whether a resource group exists
+func ResourceGroupExists(ctx context.Context, subscriptionId, name string) (bool, error) {
+	cred, err := GetAzCred()
+	if err != nil {
+		return false, fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	client, err := armresources.NewResourceGroupsClient(subscriptionId, cred, GetClientOptions())
+	if err != nil {
+		return false, fmt.Errorf("creating resource group client: %w", err)
+	}
+
+	resp, err := client.CheckExistence(ctx, name, nil)
+	if err != nil {
+		return false, fmt.Errorf("checking resource group existence: %w", err)
+	}
+
+	return resp.Success, nil
+}
+
+// Deletes a resource group and everything in it, waiting for the deletion to finish. Returns false without an error
+// when the resource group doesn't exist
+func DeleteResourceGroup(ctx context.Context, subscriptionId, name string) (bool, error) {
+	lgr := logger.FromContext(ctx).With("name", name, "subscriptionId", subscriptionId)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to delete resource group")
+	defer lgr.Info("finished deleting resource group")
+
+	cred, err := GetAzCred()
+	if err != nil {
+		return false, fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	client, err := armresources.NewResourceGroupsClient(subscriptionId, cred, GetClientOptions())
+	if err != nil {
+		return false, fmt.Errorf("creating resource group client: %w", err)
+	}
+
+	poller, err := client.BeginDelete(ctx, name, nil)
+	if err != nil {
+		if isNotFound(err) {
+			lgr.Info("resource group already deleted")
+			return false, nil
+		}
+		return false, fmt.Errorf("starting to delete resource group: %w", err)
+	}
+
+	if _, err := pollWithLog(ctx, poller, "still deleting resource group "+name); err != nil {
+		return false, fmt.Errorf("deleting resource group: %w", err)
+	}
+
+	return true, nil
+}
+
+// isNotFound reports whether err is an arm response for a resource that doesn't exist
+func isNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,254 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+)
+
+const (
+	graphEndpoint = "https://graph.microsoft.com/v1.0"
+	graphScope    = "https://graph.microsoft.com/.default"
+	// secrets only need to outlive a test run, teardown deletes the app registration anyway
+	secretLifetime = 24 * time.Hour
+	// tag the app registration with the resource group of its infrastructure so gc can find it once the group expires.
+	// App registrations belong to the tenant, so the subscription is needed to tell which group it is
+	subscriptionTagPrefix  = "subscription:"
+	resourceGroupTagPrefix = "resource_group:"
+)
+
+// servicePrincipal is an app registration and its service principal, the stand-in for the apps customers authenticate
+// external-dns with. Secrets are never saved to the infrastructure file, new ones are added when they're needed
+type servicePrincipal struct {
+	name                  string
+	appObjectId           string
+	clientId, principalId string
+}
+
+// Called to create Provisioned object from .json file
+func LoadServicePrincipal(name, appObjectId, clientId, principalId string) *servicePrincipal {
+	return &servicePrincipal{
+		name:        name,
+		appObjectId: appObjectId,
+		clientId:    clientId,
+		principalId: principalId,
+	}
+}
+
+// Creates an app registration with the given display name and a service principal for it through Microsoft Graph. The
+// app registration is tagged with the resource group the rest of its infrastructure lives in
+func NewServicePrincipal(ctx context.Context, name, subscriptionId, resourceGroup string) (*servicePrincipal, error) {
+	lgr := logger.FromContext(ctx).With("name", name, "subscriptionId", subscriptionId, "resourceGroup", resourceGroup)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to create service principal")
+	defer lgr.Info("finished creating service principal")
+
+	var app struct {
+		Id    string `json:"id"`
+		AppId string `json:"appId"`
+	}
+	if err := graphDo(ctx, http.MethodPost, "/applications", map[string]any{"displayName": name, "tags": []string{subscriptionTagPrefix + subscriptionId, resourceGroupTagPrefix + resourceGroup}}, &app); err != nil {
+		return nil, fmt.Errorf("creating app registration: %w", err)
+	}
+
+	var sp struct {
+		Id string `json:"id"`
+	}
+	if err := graphDo(ctx, http.MethodPost, "/servicePrincipals", map[string]any{"appId": app.AppId}, &sp); err != nil {
+		return nil, fmt.Errorf("creating service principal for app %s: %w", app.AppId, err)
+	}
+
+	return &servicePrincipal{
+		name:        name,
+		appObjectId: app.Id,
+		clientId:    app.AppId,
+		principalId: sp.Id,
+	}, nil
+}
+
+// Adds a client secret to the app registration, returning the secret and the key id it's referred to by
+func (s *servicePrincipal) AddSecret(ctx context.Context) (string, string, error) {
+	lgr := logger.FromContext(ctx).With("name", s.name)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to add service principal secret")
+	defer lgr.Info("finished adding service principal secret")
+
+	var password struct {
+		KeyId      string `json:"keyId"`
+		SecretText string `json:"secretText"`
+	}
+	body := map[string]any{
+		"passwordCredential": map[string]any{
+			"displayName": "external-dns e2e",
+			"endDateTime": time.Now().Add(secretLifetime).UTC().Format(time.RFC3339),
+		},
+	}
+	if err := graphDo(ctx, http.MethodPost, "/applications/"+s.appObjectId+"/addPassword", body, &password); err != nil {
+		return "", "", fmt.Errorf("adding password: %w", err)
+	}
+
+	return password.SecretText, password.KeyId, nil
+}
+
+// Removes every client secret of the app registration except the one with keyId, so only that one authenticates
+func (s *servicePrincipal) RemoveSecretsExcept(ctx context.Context, keyId string) error {
+	lgr := logger.FromContext(ctx).With("name", s.name, "keep", keyId)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to remove service principal secrets")
+	defer lgr.Info("finished removing service principal secrets")
+
+	var app struct {
+		PasswordCredentials []struct {
+			KeyId string `json:"keyId"`
+		} `json:"passwordCredentials"`
+	}
+	if err := graphDo(ctx, http.MethodGet, "/applications/"+s.appObjectId+"?$select=passwordCredentials", nil, &app); err != nil {
+		return fmt.Errorf("getting passwords: %w", err)
+	}
+
+	for _, password := range app.PasswordCredentials {
+		if password.KeyId == keyId {
+			continue
+		}
+
+		if err := graphDo(ctx, http.MethodPost, "/applications/"+s.appObjectId+"/removePassword", map[string]any{"keyId": password.KeyId}, nil); err != nil {
+			return fmt.Errorf("removing password %s: %w", password.KeyId, err)
+		}
+	}
+
+	return nil
+}
+
+// Deletes the app registration, which deletes its service principal too. Returns false without an error when the app
+// registration doesn't exist
+func (s *servicePrincipal) Delete(ctx context.Context) (bool, error) {
+	lgr := logger.FromContext(ctx).With("name", s.name)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to delete service principal")
+	defer lgr.Info("finished deleting service principal")
+
+	if err := graphDo(ctx, http.MethodDelete, "/applications/"+s.appObjectId, nil, nil); err != nil {
+		if isNotFound(err) {
+			lgr.Info("service principal already deleted")
+			return false, nil
+		}
+		return false, fmt.Errorf("deleting app registration: %w", err)
+	}
+
+	return true, nil
+}
+
+// ServicePrincipalApp is an app registration created by NewServicePrincipal. SubscriptionId and ResourceGroup are empty
+// when the app registration wasn't tagged with its resource group
+type ServicePrincipalApp struct {
+	Name                          string
+	AppObjectId                   string
+	SubscriptionId, ResourceGroup string
+}
+
+// Lists the app registrations whose display name starts with prefix
+func ListServicePrincipalApps(ctx context.Context, prefix string) ([]ServicePrincipalApp, error) {
+	lgr := logger.FromContext(ctx).With("prefix", prefix)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to list service principal apps")
+	defer lgr.Info("finished listing service principal apps")
+
+	query := url.Values{}
+	query.Set("$filter", fmt.Sprintf("startswith(displayName,'%s')", strings.ReplaceAll(prefix, "'", "''")))
+	query.Set("$select", "id,displayName,tags")
+
+	var apps []ServicePrincipalApp
+	for path := "/applications?" + query.Encode(); path != ""; {
+		var page struct {
+			Value []struct {
+				Id          string   `json:"id"`
+				DisplayName string   `json:"displayName"`
+				Tags        []string `json:"tags"`
+			} `json:"value"`
+			NextLink string `json:"@odata.nextLink"`
+		}
+		if err := graphDo(ctx, http.MethodGet, path, nil, &page); err != nil {
+			return nil, fmt.Errorf("listing app registrations: %w", err)
+		}
+
+		for _, app := range page.Value {
+			listed := ServicePrincipalApp{Name: app.DisplayName, AppObjectId: app.Id}
+			for _, tag := range app.Tags {
+				switch {
+				case strings.HasPrefix(tag, subscriptionTagPrefix):
+					listed.SubscriptionId = strings.TrimPrefix(tag, subscriptionTagPrefix)
+				case strings.HasPrefix(tag, resourceGroupTagPrefix):
+					listed.ResourceGroup = strings.TrimPrefix(tag, resourceGroupTagPrefix)
+				}
+			}
+			apps = append(apps, listed)
+		}
+
+		path = strings.TrimPrefix(page.NextLink, graphEndpoint)
+	}
+
+	return apps, nil
+}
+
+func (s *servicePrincipal) GetName() string {
+	return s.name
+}
+
+// GetId returns the object id of the app registration
+func (s *servicePrincipal) GetId() string {
+	return s.appObjectId
+}
+
+func (s *servicePrincipal) GetClientId() string {
+	return s.clientId
+}
+
+func (s *servicePrincipal) GetPrincipalId() string {
+	return s.principalId
+}
+
+// graphDo sends a request to Microsoft Graph, marshalling body and unmarshalling the response into out when they aren't nil
+func graphDo(ctx context.Context, method, path string, body, out any) error {
+	cred, err := GetAzCred()
+	if err != nil {
+		return fmt.Errorf("getting az credentials: %w", err)
+	}
+
+	pl := runtime.NewPipeline("clients", "v0.0.0", runtime.PipelineOptions{}, &policy.ClientOptions{
+		PerRetryPolicies: []policy.Policy{runtime.NewBearerTokenPolicy(cred, []string{graphScope}, nil)},
+	})
+
+	req, err := runtime.NewRequest(ctx, method, graphEndpoint+path)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		if err := runtime.MarshalAsJSON(req, body); err != nil {
+			return fmt.Errorf("marshalling request: %w", err)
+		}
+	}
+
+	resp, err := pl.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK, http.StatusCreated, http.StatusNoContent) {
+		return runtime.NewResponseError(resp)
+	}
+
+	if out != nil {
+		if err := runtime.UnmarshalAsJSON(resp, out); err != nil {
+			return fmt.Errorf("unmarshalling response: %w", err)
+		}
+	}
+
+	return nil
+}
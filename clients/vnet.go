@@ -23,7 +23,14 @@ var (
 	networkClientFactory  *armnetwork.ClientFactory
 )
 
-func NewVnet(ctx context.Context, subId, rg, region, privateZoneName string) (string, string, error) {
+// Default address prefixes of the dual-stack vnet and subnet clusters are created in
+var (
+	DefaultVnetAddressPrefixes   = []string{"fd00:db8:deca::/48", "10.1.0.0/16"}
+	DefaultSubnetAddressPrefixes = []string{"fd00:db8:deca:deed::/64", "10.1.0.0/24"}
+)
+
+// Creates a vnet with addressPrefixes holding a subnet with subnetAddressPrefixes, returns the vnet and subnet ids
+func NewVnet(ctx context.Context, subId, rg, region string, addressPrefixes, subnetAddressPrefixes []string) (string, string, error) {
 	subscriptionID = subId
 	resourceGroupName = rg
 	location = region
@@ -33,7 +40,7 @@ func NewVnet(ctx context.Context, subId, rg, region, privateZoneName string) (st
 		return "", "", fmt.Errorf("getting az credentials: %w", err)
 	}
 
-	networkClientFactory, err = armnetwork.NewClientFactory(subscriptionID, cred, nil)
+	networkClientFactory, err = armnetwork.NewClientFactory(subscriptionID, cred, GetClientOptions())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -41,12 +48,12 @@ func NewVnet(ctx context.Context, subId, rg, region, privateZoneName string) (st
 	virtualNetworksClient = networkClientFactory.NewVirtualNetworksClient()
 	subnetsClient = networkClientFactory.NewSubnetsClient()
 
-	virtualNetwork, err := createVirtualNetwork(ctx)
+	virtualNetwork, err := createVirtualNetwork(ctx, addressPrefixes)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	subnet, err := createSubnet(ctx)
+	subnet, err := createSubnet(ctx, subnetAddressPrefixes)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -54,7 +61,7 @@ func NewVnet(ctx context.Context, subId, rg, region, privateZoneName string) (st
 	return *virtualNetwork.ID, *subnet.ID, nil
 }
 
-func createVirtualNetwork(ctx context.Context) (*armnetwork.VirtualNetwork, error) {
+func createVirtualNetwork(ctx context.Context, addressPrefixes []string) (*armnetwork.VirtualNetwork, error) {
 	pollerResp, err := virtualNetworksClient.BeginCreateOrUpdate(
 		ctx,
 		resourceGroupName,
@@ -63,10 +70,7 @@ func createVirtualNetwork(ctx context.Context) (*armnetwork.VirtualNetwork, erro
 			Location: to.Ptr(location),
 			Properties: &armnetwork.VirtualNetworkPropertiesFormat{
 				AddressSpace: &armnetwork.AddressSpace{
-					AddressPrefixes: []*string{
-						to.Ptr("fd00:db8:deca::/48"),
-						to.Ptr("10.1.0.0/16"),
-					},
+					AddressPrefixes: to.SliceOfPtrs(addressPrefixes...),
 				},
 			},
 		},
@@ -83,7 +87,7 @@ func createVirtualNetwork(ctx context.Context) (*armnetwork.VirtualNetwork, erro
 	return &resp.VirtualNetwork, nil
 }
 
-func createSubnet(ctx context.Context) (*armnetwork.Subnet, error) {
+func createSubnet(ctx context.Context, addressPrefixes []string) (*armnetwork.Subnet, error) {
 	pollerResp, err := subnetsClient.BeginCreateOrUpdate(
 		ctx,
 		resourceGroupName,
@@ -91,10 +95,7 @@ func createSubnet(ctx context.Context) (*armnetwork.Subnet, error) {
 		subnetName,
 		armnetwork.Subnet{
 			Properties: &armnetwork.SubnetPropertiesFormat{
-				AddressPrefixes: []*string{
-					to.Ptr("fd00:db8:deca:deed::/64"),
-					to.Ptr("10.1.0.0/24"),
-				},
+				AddressPrefixes: to.SliceOfPtrs(addressPrefixes...),
 			},
 		},
 		nil)
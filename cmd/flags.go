@@ -11,7 +11,20 @@ const (
 	tenantIdFlag       = "tenant"
 	infraNamesFlag     = "names"
 	infraFileFlag      = "infra-file"
+	infraSpecFlag      = "infra-spec"
 	infraNameFlag      = "infra-name"
+	kubeconfigFlag     = "kubeconfig"
+	fakeDnsUrlFlag     = "fake-dns-url"
+	junitFileFlag      = "junit-file"
+	jsonFileFlag       = "json-file"
+	latencyFileFlag    = "latency-file"
+	latencySloFlag     = "latency-slo"
+	workersFlag        = "workers"
+	scaleServicesFlag  = "scale-services"
+	dryRunFlag         = "dry-run"
+
+	externalDnsVersionFlag  = "external-dns-version"
+	externalDnsRegistryFlag = "external-dns-registry"
 )
 
 var (
@@ -19,14 +32,17 @@ var (
 	tenantId       string
 )
 
-// Saves tenantId and subscriptionId, used in provisioning infrastructure in infra command
+// Saves tenantId and subscriptionId, used in provisioning infrastructure in infra command.
+// Both are required unless running against a local cluster with --kubeconfig
 func setupSubTenantFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&subscriptionId, subscriptionIdFlag, "", "subscription")
-	cmd.MarkFlagRequired(subscriptionIdFlag)
 	cmd.Flags().StringVar(&tenantId, tenantIdFlag, "", "tenant")
-	cmd.MarkFlagRequired(tenantIdFlag)
 
 	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if kubeconfig != "" {
+			return nil
+		}
+
 		if subscriptionId == "" {
 			return errors.New("subscription is required")
 		}
@@ -56,6 +72,96 @@ func setupInfraFileFlag(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&infraFile, infraFileFlag, "./infra-config.json", "file to load infrastructure from")
 }
 
+var (
+	infraSpec string
+)
+
+// Saves the yaml or json file describing the infrastructure to provision
+func setupInfraSpecFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&infraSpec, infraSpecFlag, "", "yaml or json file describing the infrastructure to provision, if empty the built-in infrastructure is used")
+}
+
 var (
 	infraName string
 )
+
+// Saves the name of the infrastructure in the infra file to test
+func setupInfraNameFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&infraName, infraNameFlag, "", "name of the infrastructure in the infra file to test, if empty tests all")
+}
+
+var (
+	kubeconfig string
+	fakeDnsUrl string
+)
+
+// Saves the kubeconfig of an existing cluster and the url the fake dns backend is served on, used instead of provisioning AKS
+func setupLocalClusterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&kubeconfig, kubeconfigFlag, "", "kubeconfig of an existing cluster, like kind, to run against instead of provisioning AKS")
+	cmd.Flags().StringVar(&fakeDnsUrl, fakeDnsUrlFlag, "http://172.18.0.1:8080", "url pods in the local cluster use to reach the fake dns backend served by the test command")
+}
+
+var (
+	junitResultsFile   string
+	jsonResultsFile    string
+	latencyResultsFile string
+)
+
+// Saves the files test results are written to
+func setupResultsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&junitResultsFile, junitFileFlag, "./e2e-results.xml", "file to write JUnit XML test results to")
+	cmd.Flags().StringVar(&jsonResultsFile, jsonFileFlag, "./e2e-results.json", "file to write JSON test results to")
+	cmd.Flags().StringVar(&latencyResultsFile, latencyFileFlag, "./e2e-latency.json", "file to write JSON record latency histograms to")
+}
+
+var (
+	latencySlos []string
+)
+
+// Saves the latency slos that fail a test run when missed
+func setupLatencySloFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&latencySlos, latencySloFlag, []string{}, "highest p95 latency allowed for records to reach a stage after their service is annotated, as stage=duration like arm=4m. Stages are arm, resolvable and converged")
+}
+
+var (
+	workers int
+)
+
+// Saves the maximum number of tests run at the same time
+func setupWorkersFlag(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&workers, workersFlag, 4, "maximum number of tests to run concurrently against each infrastructure")
+}
+
+var (
+	scaleServices int
+)
+
+// Saves the number of services the scale suite deploys at once
+func setupScaleServicesFlag(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&scaleServices, scaleServicesFlag, 100, "number of services, each with its own hostname, the scale suite deploys at once, 0 skips the suite")
+}
+
+var (
+	dryRun bool
+)
+
+// Saves whether a command should only report what it would change
+func setupDryRunFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&dryRun, dryRunFlag, false, "only report what would be deleted")
+}
+
+var (
+	externalDnsVersions []string
+	externalDnsRegistry string
+)
+
+// Saves the external-dns image tags to run every infrastructure against
+func setupExternalDnsVersionsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&externalDnsVersions, externalDnsVersionFlag, []string{}, "external-dns versions to run every infrastructure against, if empty the configured version is used")
+}
+
+// Saves the external-dns versions and the registry their images are pulled from
+func setupExternalDnsFlags(cmd *cobra.Command) {
+	setupExternalDnsVersionsFlag(cmd)
+	cmd.Flags().StringVar(&externalDnsRegistry, externalDnsRegistryFlag, "", "registry to pull the external-dns image from, if empty the configured registry is used")
+}
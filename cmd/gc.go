@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+)
+
+// maximum number of resource groups deleted at the same time
+const gcConcurrency = 10
+
+func init() {
+	gcCmd.Flags().StringVar(&subscriptionId, subscriptionIdFlag, "", "subscription")
+	gcCmd.MarkFlagRequired(subscriptionIdFlag)
+	setupDryRunFlag(gcCmd)
+	rootCmd.AddCommand(gcCmd)
+}
+
+// GC Command deletes e2e resource groups whose deletion_due_time tag has passed, along with the app registrations
+// created for them
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Deletes expired e2e resource groups and their app registrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		lgr := logger.FromContext(ctx).With("subscriptionId", subscriptionId, "dryRun", dryRun)
+		ctx = logger.WithContext(ctx, lgr)
+
+		expired, err := clients.ListExpiredResourceGroups(ctx, subscriptionId, infra.ResourceGroupPrefix, time.Now())
+		if err != nil {
+			return logger.Error(lgr, fmt.Errorf("listing expired resource groups: %w", err))
+		}
+
+		for _, group := range expired {
+			lgr.Info("found expired resource group", "resourceGroup", group.Name, "dueTime", group.DueTime.UTC().Format(time.RFC3339))
+		}
+
+		apps, err := expiredApps(ctx, expired)
+		if err != nil {
+			return logger.Error(lgr, fmt.Errorf("listing expired app registrations: %w", err))
+		}
+
+		if dryRun {
+			lgr.Info(fmt.Sprintf("gc summary: %d expired resource groups and %d expired app registrations found, none deleted because of dry run", len(expired), len(apps)))
+			return nil
+		}
+
+		deleted := make([]bool, len(expired)+len(apps))
+		failed := make([]error, len(expired)+len(apps))
+
+		var eg errgroup.Group
+		eg.SetLimit(gcConcurrency)
+		for i, group := range expired {
+			func(i int, group clients.ExpiredResourceGroup) {
+				eg.Go(func() error {
+					lgr := lgr.With("resourceGroup", group.Name)
+					ctx := logger.WithContext(ctx, lgr)
+
+					deleted[i], failed[i] = clients.DeleteResourceGroup(ctx, subscriptionId, group.Name)
+					if failed[i] != nil {
+						logger.Error(lgr, fmt.Errorf("deleting resource group %s: %w", group.Name, failed[i]))
+					}
+					return nil
+				})
+			}(i, group)
+		}
+		for i, app := range apps {
+			func(i int, app clients.ServicePrincipalApp) {
+				eg.Go(func() error {
+					lgr := lgr.With("app", app.Name)
+					ctx := logger.WithContext(ctx, lgr)
+
+					sp := clients.LoadServicePrincipal(app.Name, app.AppObjectId, "", "")
+					deleted[i], failed[i] = sp.Delete(ctx)
+					if failed[i] != nil {
+						logger.Error(lgr, fmt.Errorf("deleting app registration %s: %w", app.Name, failed[i]))
+					}
+					return nil
+				})
+			}(len(expired)+i, app)
+		}
+		eg.Wait()
+
+		groupsDeleted, groupsFailed := countDeleted(deleted[:len(expired)], failed[:len(expired)])
+		appsDeleted, appsFailed := countDeleted(deleted[len(expired):], failed[len(expired):])
+		lgr.Info(fmt.Sprintf("gc summary: %d expired resource groups found, %d deleted, %d already gone, %d failed", len(expired), groupsDeleted, len(expired)-groupsDeleted-groupsFailed, groupsFailed))
+		lgr.Info(fmt.Sprintf("gc summary: %d expired app registrations found, %d deleted, %d already gone, %d failed", len(apps), appsDeleted, len(apps)-appsDeleted-appsFailed, appsFailed))
+
+		if groupsFailed > 0 || appsFailed > 0 {
+			return fmt.Errorf("failed to delete %d resource groups and %d app registrations", groupsFailed, appsFailed)
+		}
+
+		return nil
+	},
+}
+
+// Returns the e2e app registrations whose resource group in the subscription is expired or already deleted. App
+// registrations without a resource group tag are left alone since there's no telling whether their infrastructure is
+// still in use
+func expiredApps(ctx context.Context, expired []clients.ExpiredResourceGroup) ([]clients.ServicePrincipalApp, error) {
+	lgr := logger.FromContext(ctx)
+
+	apps, err := clients.ListServicePrincipalApps(ctx, infra.ServicePrincipalPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing app registrations: %w", err)
+	}
+
+	expiredGroups := make(map[string]bool, len(expired))
+	for _, group := range expired {
+		expiredGroups[strings.ToLower(group.Name)] = true
+	}
+
+	var ret []clients.ServicePrincipalApp
+	for _, app := range apps {
+		if app.SubscriptionId == "" || app.ResourceGroup == "" {
+			lgr.Info("skipping app registration without a resource group tag", "app", app.Name)
+			continue
+		}
+		if !strings.EqualFold(app.SubscriptionId, subscriptionId) {
+			continue
+		}
+
+		if !expiredGroups[strings.ToLower(app.ResourceGroup)] {
+			exists, err := clients.ResourceGroupExists(ctx, subscriptionId, app.ResourceGroup)
+			if err != nil {
+				return nil, fmt.Errorf("checking resource group %s of app registration %s: %w", app.ResourceGroup, app.Name, err)
+			}
+			if exists {
+				continue
+			}
+		}
+
+		lgr.Info("found expired app registration", "app", app.Name, "resourceGroup", app.ResourceGroup)
+		ret = append(ret, app)
+	}
+
+	return ret, nil
+}
+
+// Counts the deletions that succeeded and failed
+func countDeleted(deleted []bool, failed []error) (int, int) {
+	numDeleted, numFailed := 0, 0
+	for i := range deleted {
+		switch {
+		case failed[i] != nil:
+			numFailed++
+		case deleted[i]:
+			numDeleted++
+		}
+	}
+	return numDeleted, numFailed
+}
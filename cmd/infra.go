@@ -3,6 +3,7 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -14,6 +15,9 @@ func init() {
 	setupSubTenantFlags(infraCmd)
 	setupInfraNamesFlag(infraCmd)
 	setupInfraFileFlag(infraCmd)
+	setupInfraSpecFlag(infraCmd)
+	setupLocalClusterFlags(infraCmd)
+	setupExternalDnsFlags(infraCmd)
 	rootCmd.AddCommand(infraCmd)
 }
 
@@ -23,9 +27,27 @@ var infraCmd = &cobra.Command{
 	Short: "Sets up infrastructure for e2e tests",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		infras := infra.Infras
+		if infraSpec != "" {
+			if kubeconfig != "" {
+				return fmt.Errorf("--%s can't be used with --%s", infraSpecFlag, kubeconfigFlag)
+			}
+
+			spec, err := infra.LoadSpec(infraSpec)
+			if err != nil {
+				return fmt.Errorf("loading infrastructure spec: %w", err)
+			}
+			infras = spec.ToInfras()
+		}
+		if kubeconfig != "" {
+			infras = infra.LocalInfras(kubeconfig, fakeDnsUrl)
+		}
 		if len(infraNames) > 0 {
 			infras = infras.FilterNames(infraNames)
 		}
+		infras, err := infras.WithExternalDns(externalDnsVersions, externalDnsRegistry)
+		if err != nil {
+			return fmt.Errorf("configuring external-dns: %w", err)
+		}
 
 		if len(infras) == 0 {
 			return fmt.Errorf("no infrastructure configurations found")
@@ -59,3 +81,29 @@ var infraCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// Reads provisioned infrastructure saved by the infra command
+func loadProvisioned(infraFile string) ([]infra.Provisioned, error) {
+	file, err := os.Open(infraFile)
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	defer file.Close()
+
+	bytes, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	var loaded []infra.LoadableProvisioned
+	if err := json.Unmarshal(bytes, &loaded); err != nil {
+		return nil, fmt.Errorf("unmarshalling saved infrastructure: %w", err)
+	}
+
+	provisioned, err := infra.ToProvisioned(loaded)
+	if err != nil {
+		return nil, fmt.Errorf("generating provisioned infrastructure: %w", err)
+	}
+
+	return provisioned, nil
+}
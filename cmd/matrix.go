@@ -7,10 +7,12 @@ import (
 
 	"github.com/Azure/azure-provider-external-dns-e2e/github"
 	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/pkgResources/config"
 )
 
 func init() {
 	setupInfraNamesFlag(matrixCmd)
+	setupExternalDnsVersionsFlag(matrixCmd)
 	rootCmd.AddCommand(matrixCmd)
 }
 
@@ -28,7 +30,12 @@ var matrixCmd = &cobra.Command{
 			infraNamers = append(infraNamers, namer{infra.Name})
 		}
 
-		matrix, err := github.NameMatrix(infraNamers)
+		versions := externalDnsVersions
+		if len(versions) == 0 {
+			versions = []string{config.DefaultExternalDnsVersion}
+		}
+
+		matrix, err := github.NameMatrix(infraNamers, versions)
 		if err != nil {
 			return fmt.Errorf("creating matrix: %w", err)
 		}
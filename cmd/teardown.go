@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+)
+
+func init() {
+	setupInfraFileFlag(teardownCmd)
+	rootCmd.AddCommand(teardownCmd)
+}
+
+// Teardown Command deletes the infrastructure saved in an infrastructure configuration file
+var teardownCmd = &cobra.Command{
+	Use:   "teardown",
+	Short: "Deletes infrastructure provisioned for e2e tests",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		lgr := logger.FromContext(ctx)
+
+		provisioned, err := loadProvisioned(infraFile)
+		if err != nil {
+			return err
+		}
+
+		results, err := infra.Teardown(ctx, provisioned)
+		for _, result := range results {
+			switch {
+			case result.Err != nil:
+				lgr.Error("failed to tear down resource group", "resourceGroup", result.ResourceGroup, "infras", result.Infras, "deleted", result.Deleted, "error", result.Err.Error())
+			case result.Deleted:
+				lgr.Info("deleted resource group", "resourceGroup", result.ResourceGroup, "infras", result.Infras, "servicePrincipals", result.ServicePrincipals)
+			default:
+				lgr.Info("nothing to delete for resource group", "resourceGroup", result.ResourceGroup, "infras", result.Infras)
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("tearing down infrastructure: %w", err)
+		}
+
+		return nil
+	},
+}
@@ -2,13 +2,16 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"os"
+	"net"
+	"net/url"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
 	"github.com/Azure/azure-provider-external-dns-e2e/infra"
 	"github.com/Azure/azure-provider-external-dns-e2e/logger"
 	"github.com/Azure/azure-provider-external-dns-e2e/suites"
@@ -17,53 +20,193 @@ import (
 
 func init() {
 	setupInfraFileFlag(testCmd)
+	setupInfraNameFlag(testCmd)
+	setupResultsFlags(testCmd)
+	setupLatencySloFlag(testCmd)
+	setupWorkersFlag(testCmd)
+	setupScaleServicesFlag(testCmd)
 	rootCmd.AddCommand(testCmd)
 }
 
-// Reads from saved infrastructure configuration file and runs e2e tests, returns errors propagated from failed tests
+// Reads from saved infrastructure configuration file and runs e2e tests against every infrastructure in it concurrently,
+// returns errors propagated from failed tests
 var testCmd = &cobra.Command{
 	Use:   "test",
 	Short: "Runs e2e tests",
+	// test failures are reported through the results files, usage would only bury them
+	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 		lgr := logger.FromContext(ctx)
 
-		file, err := os.Open(infraFile)
+		slos, err := tests.ParseSlos(latencySlos)
 		if err != nil {
-			return fmt.Errorf("opening file: %w", err)
+			return err
 		}
-		defer file.Close()
 
-		bytes, err := io.ReadAll(file)
+		provisioned, err := loadProvisioned(infraFile)
 		if err != nil {
-			return fmt.Errorf("reading file: %w", err)
+			return err
 		}
 
-		var loaded []infra.LoadableProvisioned
-		if err := json.Unmarshal(bytes, &loaded); err != nil {
-			return fmt.Errorf("unmarshalling saved infrastructure: %w", err)
+		if infraName != "" {
+			provisioned = filterProvisioned(provisioned, infraName)
+			if len(provisioned) == 0 {
+				return fmt.Errorf("no provisioned infrastructure named %s in %s", infraName, infraFile)
+			}
+		}
+		if len(provisioned) == 0 {
+			return fmt.Errorf("no provisioned infrastructure in %s", infraFile)
 		}
 
-		provisioned, err := infra.ToProvisioned(loaded)
-		if err != nil {
-			return fmt.Errorf("generating provisioned infrastructure: %w", err)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		if err := serveFakeDns(ctx, provisioned); err != nil {
+			return logger.Error(lgr, fmt.Errorf("serving fake dns: %w", err))
 		}
 
-		if len(provisioned) != 1 {
-			return fmt.Errorf("expected 1 provisioned infrastructure, got %d", len(provisioned))
+		var eg errgroup.Group
+		infraResults := make([]tests.Results, len(provisioned))
+
+		for idx, p := range provisioned {
+			func(idx int, p infra.Provisioned) {
+				eg.Go(func() error {
+					lgr := lgr.With("infra", p.Name)
+					ctx := logger.WithContext(ctx, lgr)
+
+					// a broken infra is reported as a failed setup test so it shows up in its results like any failure
+					start := time.Now()
+					if err := tests.CheckObjectsForTesting(ctx, p); err != nil {
+						err = logger.Error(lgr, fmt.Errorf("checking objects for testing %s: %w", p.Name, err))
+						infraResults[idx] = tests.Results{{
+							Name:     "check objects for testing",
+							Suite:    "setup",
+							Infra:    p.Name,
+							Start:    start,
+							Duration: time.Since(start),
+							Status:   tests.Failed,
+							Error:    err.Error(),
+						}}
+						return nil
+					}
+
+					infraResults[idx] = tests.RunSuites(ctx, p, suites.All(p, suites.Options{ScaleServices: scaleServices}), workers)
+					return nil
+				})
+			}(idx, p)
 		}
+		eg.Wait()
 
-		//Should run public and private dns suites one at a time.
-		tests.SetObjectsForTesting(ctx, provisioned[0])
-		tests := suites.All(provisioned[0])
+		var results tests.Results
+		for _, r := range infraResults {
+			results = append(results, r...)
+		}
+		results = append(results, slos.Check(results)...)
 
-		for _, suite := range tests {
-			if err := suite.Run(context.Background(), provisioned[0]); err != nil {
-				return logger.Error(lgr, fmt.Errorf("test failed: %w", err))
+		for _, h := range results.Histograms() {
+			lgr.Info("record latency", "infra", h.Infra, "stage", h.Stage, "records", h.Count, "min", h.Min, "p50", h.P50, "p95", h.P95, "max", h.Max)
+		}
+
+		if err := results.WriteJson(jsonResultsFile); err != nil {
+			return logger.Error(lgr, fmt.Errorf("writing json results: %w", err))
+		}
+		if err := results.WriteJunit(junitResultsFile); err != nil {
+			return logger.Error(lgr, fmt.Errorf("writing junit results: %w", err))
+		}
+		if err := results.WriteLatencyJson(latencyResultsFile); err != nil {
+			return logger.Error(lgr, fmt.Errorf("writing latency results: %w", err))
+		}
+
+		var errs []error
+		for _, summary := range results.ByInfra() {
+			lgr.Info("finished testing infrastructure", "infra", summary.Infra, "tests", summary.Tests, "failed", summary.Failed)
+			if summary.Failed > 0 {
+				errs = append(errs, fmt.Errorf("%s: %d of %d tests failed", summary.Infra, summary.Failed, summary.Tests))
 			}
+		}
 
+		if err := errors.Join(errs...); err != nil {
+			return logger.Error(lgr, err)
 		}
 
 		return nil
 	},
 }
+
+// Returns the provisioned infrastructure with the given name
+func filterProvisioned(provisioned []infra.Provisioned, name string) []infra.Provisioned {
+	var ret []infra.Provisioned
+	for _, p := range provisioned {
+		if p.Name == name {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// serveFakeDns starts the fake dns backend holding the zones of local infras and the nameserver of its public zones,
+// points the arm clients used by tests at it, and deploys external-dns now that its webhook provider is reachable. The
+// arm clients are pointed at the fake for the whole process, so local infras can't be tested alongside ones in Azure
+func serveFakeDns(ctx context.Context, provisioned []infra.Provisioned) error {
+	var local []infra.Provisioned
+	for _, p := range provisioned {
+		if p.FakeDnsUrl != "" {
+			local = append(local, p)
+		}
+	}
+	if len(local) == 0 {
+		return nil
+	}
+	if len(local) != len(provisioned) {
+		return errors.New("local infrastructure can't be tested alongside infrastructure in Azure")
+	}
+
+	u, err := url.Parse(local[0].FakeDnsUrl)
+	if err != nil {
+		return fmt.Errorf("parsing fake dns url: %w", err)
+	}
+	// without a port the fake would listen on a random one that external-dns isn't pointed at
+	if u.Port() == "" {
+		return fmt.Errorf("fake dns url %s has no port", local[0].FakeDnsUrl)
+	}
+
+	fake := clients.NewFakeDns()
+	for _, p := range local {
+		if p.FakeDnsUrl != local[0].FakeDnsUrl {
+			return fmt.Errorf("local infrastructure %s uses fake dns url %s, expected %s", p.Name, p.FakeDnsUrl, local[0].FakeDnsUrl)
+		}
+
+		for _, zone := range p.Zones {
+			fake.AddZone(p.SubscriptionId, p.ResourceGroup.GetName(), zone.GetName())
+		}
+		for _, privateZone := range p.PrivateZones {
+			fake.AddPrivateZone(p.SubscriptionId, p.ResourceGroup.GetName(), privateZone.GetName())
+		}
+	}
+
+	if err := fake.Start(ctx, ":"+u.Port()); err != nil {
+		return fmt.Errorf("starting fake dns: %w", err)
+	}
+
+	nameserver, err := clients.FakeDnsNameserver(local[0].FakeDnsUrl)
+	if err != nil {
+		return err
+	}
+	_, nameserverPort, err := net.SplitHostPort(nameserver)
+	if err != nil {
+		return fmt.Errorf("parsing fake dns nameserver %s: %w", nameserver, err)
+	}
+	if err := fake.ServeDns(ctx, ":"+nameserverPort); err != nil {
+		return fmt.Errorf("starting fake dns nameserver: %w", err)
+	}
+	clients.UseFakeDns(fake)
+
+	for _, p := range local {
+		if err := p.DeployExternalDns(ctx); err != nil {
+			return fmt.Errorf("deploying external dns for %s: %w", p.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -12,21 +12,22 @@ type Namer interface {
 }
 
 // NameMatrix returns a GitHub Actions matrix that will be used to dynamically
-// generate a matrix of test jobs. This returns a JSON string that can be
-// unmarshalled into a matrix https://github.blog/changelog/2020-04-15-github-actions-new-workflow-features/#new-fromjson-method-in-expressions.
-func NameMatrix(namers []Namer) (string, error) {
+// generate a matrix of test jobs, one for every name and external-dns version.
+// This returns a JSON string that can be unmarshalled into a matrix https://github.blog/changelog/2020-04-15-github-actions-new-workflow-features/#new-fromjson-method-in-expressions.
+func NameMatrix(namers []Namer, externalDnsVersions []string) (string, error) {
 	names := make([]string, len(namers))
 	for i, n := range namers {
 		names[i] = n.Name()
 	}
 
 	matrix := matrix{
-		"name": names,
+		"name":               names,
+		"externalDnsVersion": externalDnsVersions,
 	}
 
 	b, err := json.Marshal(matrix)
 	if err != nil {
-		return "", fmt.Errorf("")
+		return "", fmt.Errorf("marshalling matrix: %w", err)
 	}
 
 	return string(b), nil
@@ -11,9 +11,18 @@ import (
 
 // Used to save provisioned infrastructure to .json file, used by ToLoadable() and called from the infra command
 func (p Provisioned) Loadable() (LoadableProvisioned, error) {
-	cluster, err := azure.ParseResourceID(p.Cluster.GetId())
-	if err != nil {
-		return LoadableProvisioned{}, fmt.Errorf("parsing cluster resource id: %w", err)
+	var cluster azure.Resource
+	var kubeconfig string
+	if l, ok := p.Cluster.(kubeconfigCluster); ok {
+		// local clusters have no arm resource id, the name and kubeconfig are enough to load them
+		cluster = azure.Resource{ResourceName: p.Cluster.GetName()}
+		kubeconfig = l.GetKubeconfig()
+	} else {
+		var err error
+		cluster, err = azure.ParseResourceID(p.Cluster.GetId())
+		if err != nil {
+			return LoadableProvisioned{}, fmt.Errorf("parsing cluster resource id: %w", err)
+		}
 	}
 
 	resourceGroup, err := arm.ParseResourceID(p.ResourceGroup.GetId())
@@ -42,21 +51,53 @@ func (p Provisioned) Loadable() (LoadableProvisioned, error) {
 		privateZones[i] = z
 	}
 
+	var externalDnsIdentity *LoadableIdentity
+	if p.ExternalDnsIdentity != nil {
+		id, err := azure.ParseResourceID(p.ExternalDnsIdentity.GetId())
+		if err != nil {
+			return LoadableProvisioned{}, fmt.Errorf("parsing external dns identity resource id: %w", err)
+		}
+		externalDnsIdentity = &LoadableIdentity{
+			ResourceId:  id,
+			ClientId:    p.ExternalDnsIdentity.GetClientId(),
+			PrincipalId: p.ExternalDnsIdentity.GetPrincipalId(),
+		}
+	}
+
+	var externalDnsServicePrincipal *LoadableServicePrincipal
+	if p.ExternalDnsServicePrincipal != nil {
+		externalDnsServicePrincipal = &LoadableServicePrincipal{
+			Name:        p.ExternalDnsServicePrincipal.GetName(),
+			AppObjectId: p.ExternalDnsServicePrincipal.GetId(),
+			ClientId:    p.ExternalDnsServicePrincipal.GetClientId(),
+			PrincipalId: p.ExternalDnsServicePrincipal.GetPrincipalId(),
+		}
+	}
+
 	return LoadableProvisioned{
-		Name:                p.Name,
-		Cluster:             cluster,
-		ClusterLocation:     p.Cluster.GetLocation(),
-		ClusterDnsServiceIp: p.Cluster.GetDnsServiceIp(),
-		ClusterPrincipalId:  p.Cluster.GetPrincipalId(),
-		ClusterClientId:     p.Cluster.GetClientId(),
-		ClusterOptions:      p.Cluster.GetOptions(),
-		Zones:               zones,
-		PrivateZones:        privateZones,
-		ResourceGroup:       *resourceGroup,
-		SubscriptionId:      p.SubscriptionId,
-		TenantId:            p.TenantId,
-		Ipv4ServiceName:     p.Ipv4ServiceName,
-		Ipv6ServiceName:     p.Ipv6ServiceName,
+		Name:                        p.Name,
+		Cluster:                     cluster,
+		ClusterLocation:             p.Cluster.GetLocation(),
+		ClusterDnsServiceIp:         p.Cluster.GetDnsServiceIp(),
+		ClusterPrincipalId:          p.Cluster.GetPrincipalId(),
+		ClusterClientId:             p.Cluster.GetClientId(),
+		ClusterOptions:              p.Cluster.GetOptions(),
+		ClusterKubeconfig:           kubeconfig,
+		Zones:                       zones,
+		PrivateZones:                privateZones,
+		ResourceGroup:               *resourceGroup,
+		SubscriptionId:              p.SubscriptionId,
+		TenantId:                    p.TenantId,
+		Ipv4ServiceName:             p.Ipv4ServiceName,
+		Ipv6ServiceName:             p.Ipv6ServiceName,
+		FakeDnsUrl:                  p.FakeDnsUrl,
+		ExternalDnsSyncInterval:     p.ExternalDnsSyncInterval,
+		ExternalDnsRegistry:         p.ExternalDnsRegistry,
+		ExternalDnsVersion:          p.ExternalDnsVersion,
+		ExternalDnsAuth:             p.ExternalDnsAuth,
+		ExternalDnsIdentity:         externalDnsIdentity,
+		ExternalDnsServicePrincipal: externalDnsServicePrincipal,
+		Existing:                    p.Existing,
 	}, nil
 
 }
@@ -99,15 +140,38 @@ func (l LoadableProvisioned) Provisioned() (Provisioned, error) {
 		pzs[i] = clients.LoadPrivateZone(pz)
 	}
 
+	var c cluster = clients.LoadAks(l.Cluster, l.ClusterDnsServiceIp, l.ClusterLocation, l.ClusterPrincipalId, l.ClusterClientId, l.ClusterOptions)
+	if l.ClusterKubeconfig != "" {
+		c = clients.LoadLocal(l.Cluster.ResourceName, l.ClusterKubeconfig, l.ClusterOptions)
+	}
+
+	var externalDnsIdentity identity
+	if l.ExternalDnsIdentity != nil {
+		externalDnsIdentity = clients.LoadManagedIdentity(l.ExternalDnsIdentity.ResourceId, l.ExternalDnsIdentity.ClientId, l.ExternalDnsIdentity.PrincipalId)
+	}
+
+	var externalDnsServicePrincipal servicePrincipal
+	if sp := l.ExternalDnsServicePrincipal; sp != nil {
+		externalDnsServicePrincipal = clients.LoadServicePrincipal(sp.Name, sp.AppObjectId, sp.ClientId, sp.PrincipalId)
+	}
+
 	return Provisioned{
-		Name:            l.Name,
-		Cluster:         clients.LoadAks(l.Cluster, l.ClusterDnsServiceIp, l.ClusterLocation, l.ClusterPrincipalId, l.ClusterClientId, l.ClusterOptions),
-		Zones:           zs,
-		PrivateZones:    pzs,
-		ResourceGroup:   clients.LoadRg(l.ResourceGroup),
-		SubscriptionId:  l.SubscriptionId,
-		TenantId:        l.TenantId,
-		Ipv4ServiceName: l.Ipv4ServiceName,
-		Ipv6ServiceName: l.Ipv6ServiceName,
+		Name:                        l.Name,
+		Cluster:                     c,
+		Zones:                       zs,
+		PrivateZones:                pzs,
+		ResourceGroup:               clients.LoadRg(l.ResourceGroup),
+		SubscriptionId:              l.SubscriptionId,
+		TenantId:                    l.TenantId,
+		Ipv4ServiceName:             l.Ipv4ServiceName,
+		Ipv6ServiceName:             l.Ipv6ServiceName,
+		FakeDnsUrl:                  l.FakeDnsUrl,
+		ExternalDnsSyncInterval:     l.ExternalDnsSyncInterval,
+		ExternalDnsRegistry:         l.ExternalDnsRegistry,
+		ExternalDnsVersion:          l.ExternalDnsVersion,
+		ExternalDnsAuth:             l.ExternalDnsAuth,
+		ExternalDnsIdentity:         externalDnsIdentity,
+		ExternalDnsServicePrincipal: externalDnsServicePrincipal,
+		Existing:                    l.Existing,
 	}, nil
 }
@@ -1,34 +1,47 @@
 package infra
 
 import (
-	"github.com/google/uuid"
+	"fmt"
 
-	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/google/uuid"
+	"golang.org/x/exp/slices"
 )
 
+// ResourceGroupPrefix starts the name of every resource group provisioned by the infra command
+const ResourceGroupPrefix = "externalDns-e2e"
+
+// ServicePrincipalPrefix starts the display name of every app registration provisioned by the infra command
+const ServicePrincipalPrefix = ResourceGroupPrefix + "-external-dns-"
+
 // Default values used for infrastructure, can be modified if needed
 var (
-	rg              = "externalDns-e2e" + uuid.New().String()
+	rg              = ResourceGroupPrefix + uuid.New().String()
 	location        = "westus"
 	publicZoneName  = "public-zone-" + uuid.NewString()
 	privateZoneName = "private-zone-" + uuid.NewString()
 )
 
-// Infras is a list of infrastructure configurations the e2e tests will run against
-var Infras = infras{
-	{
-		Name:          "basic cluster",
-		ResourceGroup: rg,
-		Location:      location,
-		Suffix:        uuid.New().String(),
-	},
-	{
-		Name:          "private cluster",
-		ResourceGroup: rg,
-		Location:      location,
-		Suffix:        uuid.New().String(),
-		McOpts:        []clients.McOpt{clients.PrivateClusterOpt},
-	},
+// localSubscriptionId is used in the fake zone ids of local infras when no subscription is given
+const localSubscriptionId = "00000000-0000-0000-0000-000000000000"
+
+// Infras is a list of infrastructure configurations the e2e tests will run against when no spec file is given
+var Infras = DefaultSpec.ToInfras()
+
+// LocalInfras returns an infrastructure configuration that runs against an existing cluster, like kind, instead of AKS.
+// Zones are served by the fake dns backend on fakeDnsUrl, which must be reachable from pods in the cluster
+func LocalInfras(kubeconfig, fakeDnsUrl string) infras {
+	return infras{
+		{
+			Name:          "local cluster",
+			ResourceGroup: rg,
+			Location:      "local",
+			Suffix:        uuid.New().String(),
+			PublicZones:   []string{publicZoneName},
+			PrivateZones:  []string{privateZoneName},
+			Kubeconfig:    kubeconfig,
+			FakeDnsUrl:    fakeDnsUrl,
+		},
+	}
 }
 
 // Filters out infrastructure not specified in command line args and returns a list of infras to run tests against
@@ -44,3 +57,38 @@ func (i infras) FilterNames(names []string) infras {
 	}
 	return ret
 }
+
+// WithExternalDns returns the infras deploying the given external-dns versions from registry, left as configured when
+// empty. With more than one version every infra is repeated once per version and named after it, so each version runs
+// against its own cluster
+func (i infras) WithExternalDns(versions []string, registry string) (infras, error) {
+	// unset make variables pass empty versions
+	versions = slices.DeleteFunc(slices.Clone(versions), func(version string) bool { return version == "" })
+
+	ret := infras{}
+	for _, inf := range i {
+		if registry != "" {
+			inf.ExternalDnsRegistry = registry
+		}
+
+		if len(versions) == 0 {
+			ret = append(ret, inf)
+			continue
+		}
+		// external-dns is deployed under the same names whatever the version, so a cluster can only run one of them
+		if len(versions) > 1 && (inf.ExistingCluster != "" || inf.Kubeconfig != "") {
+			return nil, fmt.Errorf("infra %s reuses a cluster so it can't run several external-dns versions", inf.Name)
+		}
+
+		for _, version := range versions {
+			versioned := inf
+			versioned.ExternalDnsVersion = version
+			if len(versions) > 1 {
+				versioned.Name = fmt.Sprintf("%s %s", inf.Name, version)
+				versioned.Suffix = uuid.New().String()
+			}
+			ret = append(ret, versioned)
+		}
+	}
+	return ret, nil
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/go-autorest/autorest/azure"
 	"golang.org/x/sync/errgroup"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -25,10 +28,23 @@ func (i *infra) Provision(ctx context.Context, tenantId, subscriptionId string)
 	lgr.Info("provisioning infrastructure")
 	defer lgr.Info("finished provisioning infrastructure")
 
+	if i.Kubeconfig != "" {
+		return i.provisionLocal(ctx, tenantId, subscriptionId)
+	}
+	if i.ExistingCluster != "" {
+		return i.provisionExisting(ctx, tenantId)
+	}
+
 	ret := Provisioned{
-		Name:           i.Name,
-		SubscriptionId: subscriptionId,
-		TenantId:       tenantId,
+		Name:                    i.Name,
+		SubscriptionId:          subscriptionId,
+		TenantId:                tenantId,
+		Zones:                   make([]zone, len(i.PublicZones)),
+		PrivateZones:            make([]privateZone, len(i.PrivateZones)),
+		ExternalDnsSyncInterval: i.ExternalDnsSyncInterval,
+		ExternalDnsRegistry:     i.ExternalDnsRegistry,
+		ExternalDnsVersion:      i.ExternalDnsVersion,
+		ExternalDnsAuth:         i.ExternalDnsAuth,
 	}
 
 	var err error
@@ -44,23 +60,59 @@ func (i *infra) Provision(ctx context.Context, tenantId, subscriptionId string)
 	var subnetId string
 	var vnetId string
 
-	resEg.Go(func() error {
-		zone, err := clients.NewZone(ctx, subscriptionId, i.ResourceGroup, publicZoneName)
-		if err != nil {
-			return logger.Error(lgr, fmt.Errorf("creating zone: %w", err))
-		}
-		ret.Zones = append(ret.Zones, zone)
-		return nil
-	})
+	for idx, name := range i.PublicZones {
+		func(idx int, name string) {
+			resEg.Go(func() error {
+				zone, err := clients.NewZone(ctx, subscriptionId, i.ResourceGroup, name)
+				if err != nil {
+					return logger.Error(lgr, fmt.Errorf("creating zone: %w", err))
+				}
+				ret.Zones[idx] = zone
+				return nil
+			})
+		}(idx, name)
+	}
 
-	resEg.Go(func() error {
-		privateZone, err := clients.NewPrivateZone(ctx, subscriptionId, i.ResourceGroup, privateZoneName)
-		if err != nil {
-			return logger.Error(lgr, fmt.Errorf("creating private zone: %w", err))
-		}
-		ret.PrivateZones = append(ret.PrivateZones, privateZone)
-		return nil
-	})
+	for idx, name := range i.PrivateZones {
+		func(idx int, name string) {
+			resEg.Go(func() error {
+				privateZone, err := clients.NewPrivateZone(ctx, subscriptionId, i.ResourceGroup, name)
+				if err != nil {
+					return logger.Error(lgr, fmt.Errorf("creating private zone: %w", err))
+				}
+				ret.PrivateZones[idx] = privateZone
+				return nil
+			})
+		}(idx, name)
+	}
+
+	// created alongside the zones so it has replicated by the time roles are assigned to it. With managed identity it's
+	// assigned to the nodes so the dns roles aren't granted to the kubelet identity
+	if i.ExternalDnsAuth == manifests.WorkloadIdentityAuth || i.ExternalDnsAuth == manifests.ManagedIdentityAuth {
+		resEg.Go(func() error {
+			identity, err := clients.NewManagedIdentity(ctx, subscriptionId, i.ResourceGroup, "external-dns"+i.Suffix, i.Location)
+			if err != nil {
+				return logger.Error(lgr, fmt.Errorf("creating external dns identity: %w", err))
+			}
+			ret.ExternalDnsIdentity = identity
+			return nil
+		})
+	}
+
+	if i.ExternalDnsAuth == manifests.ServicePrincipalSecretAuth {
+		resEg.Go(func() error {
+			sp, err := clients.NewServicePrincipal(ctx, ServicePrincipalPrefix+i.Suffix, subscriptionId, i.ResourceGroup)
+			if err != nil {
+				return logger.Error(lgr, fmt.Errorf("creating external dns service principal: %w", err))
+			}
+			ret.ExternalDnsServicePrincipal = sp
+
+			if ret.externalDnsSecret, _, err = sp.AddSecret(ctx); err != nil {
+				return logger.Error(lgr, fmt.Errorf("adding external dns service principal secret: %w", err))
+			}
+			return nil
+		})
+	}
 
 	if err := resEg.Wait(); err != nil {
 		return Provisioned{}, logger.Error(lgr, err)
@@ -68,14 +120,15 @@ func (i *infra) Provision(ctx context.Context, tenantId, subscriptionId string)
 
 	//create vnet and link
 	resEg.Go(func() error {
-		vnetId, subnetId, err = clients.NewVnet(ctx, subscriptionId, i.ResourceGroup, i.Location, ret.PrivateZones[0].GetName())
+		vnetId, subnetId, err = clients.NewVnet(ctx, subscriptionId, i.ResourceGroup, i.Location, i.VnetAddressPrefixes, i.SubnetAddressPrefixes)
 		if err != nil {
 			return logger.Error(lgr, fmt.Errorf("creating vnet: %w", err))
 		}
 
-		err = ret.PrivateZones[0].LinkVnet(ctx, linkName, vnetId)
-		if err != nil {
-			return logger.Error(lgr, fmt.Errorf("creating vnet link: %w", err))
+		for _, pz := range ret.PrivateZones {
+			if err := pz.LinkVnet(ctx, linkName, vnetId); err != nil {
+				return logger.Error(lgr, fmt.Errorf("creating vnet link: %w", err))
+			}
 		}
 		return nil
 	})
@@ -98,6 +151,17 @@ func (i *infra) Provision(ctx context.Context, tenantId, subscriptionId string)
 		return Provisioned{}, logger.Error(lgr, err)
 	}
 
+	switch ret.ExternalDnsAuth {
+	case manifests.WorkloadIdentityAuth:
+		if err := federateExternalDns(ctx, ret); err != nil {
+			return Provisioned{}, logger.Error(lgr, fmt.Errorf("federating external dns identity: %w", err))
+		}
+	case manifests.ManagedIdentityAuth:
+		if err := assignNodeIdentity(ctx, ret); err != nil {
+			return Provisioned{}, logger.Error(lgr, fmt.Errorf("assigning external dns identity to nodes: %w", err))
+		}
+	}
+
 	//setting permissions for private zones
 	var permEg errgroup.Group
 	for _, pz := range ret.PrivateZones {
@@ -108,7 +172,7 @@ func (i *infra) Provision(ctx context.Context, tenantId, subscriptionId string)
 					return logger.Error(lgr, fmt.Errorf("getting dns: %w", err))
 				}
 
-				principalId := ret.Cluster.GetPrincipalId()
+				principalId := ret.dnsPrincipalId()
 				role := clients.PrivateDnsContributorRole
 				if _, err := clients.NewRoleAssignment(ctx, subscriptionId, *dns.ID, principalId, role); err != nil {
 					return logger.Error(lgr, fmt.Errorf("creating %s role assignment: %w", role.Name, err))
@@ -128,7 +192,7 @@ func (i *infra) Provision(ctx context.Context, tenantId, subscriptionId string)
 					return logger.Error(lgr, fmt.Errorf("getting dns: %w", err))
 				}
 
-				principalId := ret.Cluster.GetPrincipalId()
+				principalId := ret.dnsPrincipalId()
 				role := clients.DnsContributorRole
 				if _, err := clients.NewRoleAssignment(ctx, subscriptionId, *dns.ID, principalId, role); err != nil {
 					return logger.Error(lgr, fmt.Errorf("creating %s role assignment: %w", role.Name, err))
@@ -180,6 +244,352 @@ func (i *infra) Provision(ctx context.Context, tenantId, subscriptionId string)
 	return ret, nil
 }
 
+// Sets up an existing cluster reached through a kubeconfig with zones that live in the fake dns backend.
+// External dns isn't deployed here since it can't start until the fake is served, the test command deploys it
+func (i *infra) provisionLocal(ctx context.Context, tenantId, subscriptionId string) (Provisioned, *logger.LoggedError) {
+	lgr := logger.FromContext(ctx)
+
+	if subscriptionId == "" {
+		subscriptionId = localSubscriptionId
+	}
+
+	ret := Provisioned{
+		Name:                    i.Name,
+		SubscriptionId:          subscriptionId,
+		TenantId:                tenantId,
+		FakeDnsUrl:              i.FakeDnsUrl,
+		ExternalDnsSyncInterval: i.ExternalDnsSyncInterval,
+		ExternalDnsRegistry:     i.ExternalDnsRegistry,
+		ExternalDnsVersion:      i.ExternalDnsVersion,
+	}
+
+	rgId, err := arm.ParseResourceID(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionId, i.ResourceGroup))
+	if err != nil {
+		return Provisioned{}, logger.Error(lgr, fmt.Errorf("parsing resource group id: %w", err))
+	}
+	ret.ResourceGroup = clients.LoadRg(*rgId)
+
+	// the test command serves the zones on a nameserver beside the fake dns api
+	nameserver, err := clients.FakeDnsNameserver(i.FakeDnsUrl)
+	if err != nil {
+		return Provisioned{}, logger.Error(lgr, err)
+	}
+
+	for _, name := range i.PublicZones {
+		zoneId, err := azure.ParseResourceID(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/dnszones/%s", subscriptionId, i.ResourceGroup, name))
+		if err != nil {
+			return Provisioned{}, logger.Error(lgr, fmt.Errorf("parsing zone id: %w", err))
+		}
+		ret.Zones = append(ret.Zones, clients.LoadZone(zoneId, []string{nameserver}))
+	}
+
+	for _, name := range i.PrivateZones {
+		privateZoneId, err := azure.ParseResourceID(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/privateDnsZones/%s", subscriptionId, i.ResourceGroup, name))
+		if err != nil {
+			return Provisioned{}, logger.Error(lgr, fmt.Errorf("parsing private zone id: %w", err))
+		}
+		ret.PrivateZones = append(ret.PrivateZones, clients.LoadPrivateZone(privateZoneId))
+	}
+
+	ret.Cluster, err = clients.NewLocal(ctx, "cluster"+i.Suffix, i.Kubeconfig)
+	if err != nil {
+		return Provisioned{}, logger.Error(lgr, fmt.Errorf("connecting to local cluster: %w", err))
+	}
+
+	ipv4Service, ipv6Service, err := deployNginx(ctx, ret)
+	if err != nil {
+		return ret, logger.Error(lgr, fmt.Errorf("error deploying nginx onto cluster %w", err))
+	}
+
+	ret.Ipv4ServiceName = ipv4Service.Name
+	ret.Ipv6ServiceName = ipv6Service.Name
+
+	return ret, nil
+}
+
+// Adopts an existing cluster and zones by resource id. The cluster is granted the dns roles it's missing on each zone,
+// and external dns and nginx are only deployed when they aren't already on the cluster, so running against a dev
+// cluster keeps whatever external dns it already runs
+func (i *infra) provisionExisting(ctx context.Context, tenantId string) (Provisioned, *logger.LoggedError) {
+	lgr := logger.FromContext(ctx)
+
+	// tests query records in the resource group and subscription of the zones, which validation ensures are shared
+	firstZoneId, err := azure.ParseResourceID(i.ExistingZones[0])
+	if err != nil {
+		return Provisioned{}, logger.Error(lgr, fmt.Errorf("parsing zone id: %w", err))
+	}
+
+	ret := Provisioned{
+		Name:                    i.Name,
+		SubscriptionId:          firstZoneId.SubscriptionID,
+		TenantId:                tenantId,
+		Existing:                true,
+		Zones:                   make([]zone, len(i.ExistingZones)),
+		PrivateZones:            make([]privateZone, len(i.ExistingPrivateZones)),
+		ExternalDnsSyncInterval: i.ExternalDnsSyncInterval,
+		ExternalDnsRegistry:     i.ExternalDnsRegistry,
+		ExternalDnsVersion:      i.ExternalDnsVersion,
+	}
+
+	rgId, err := arm.ParseResourceID(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", firstZoneId.SubscriptionID, firstZoneId.ResourceGroup))
+	if err != nil {
+		return Provisioned{}, logger.Error(lgr, fmt.Errorf("parsing resource group id: %w", err))
+	}
+	ret.ResourceGroup = clients.LoadRg(*rgId)
+
+	clusterId, err := azure.ParseResourceID(i.ExistingCluster)
+	if err != nil {
+		return Provisioned{}, logger.Error(lgr, fmt.Errorf("parsing cluster id: %w", err))
+	}
+
+	var resEg errgroup.Group
+
+	resEg.Go(func() error {
+		ret.Cluster, err = clients.LoadExistingAks(ctx, clusterId)
+		if err != nil {
+			return logger.Error(lgr, fmt.Errorf("loading existing managed cluster: %w", err))
+		}
+		return nil
+	})
+
+	for idx, id := range i.ExistingZones {
+		func(idx int, id string) {
+			resEg.Go(func() error {
+				zoneId, err := azure.ParseResourceID(id)
+				if err != nil {
+					return logger.Error(lgr, fmt.Errorf("parsing zone id: %w", err))
+				}
+
+				dns, err := clients.LoadZone(zoneId, nil).GetDnsZone(ctx)
+				if err != nil {
+					return logger.Error(lgr, fmt.Errorf("getting existing zone %s: %w", id, err))
+				}
+
+				var nameservers []string
+				if dns.Properties != nil {
+					for _, ns := range dns.Properties.NameServers {
+						nameservers = append(nameservers, *ns)
+					}
+				}
+				ret.Zones[idx] = clients.LoadZone(zoneId, nameservers)
+				return nil
+			})
+		}(idx, id)
+	}
+
+	for idx, id := range i.ExistingPrivateZones {
+		func(idx int, id string) {
+			resEg.Go(func() error {
+				privateZoneId, err := azure.ParseResourceID(id)
+				if err != nil {
+					return logger.Error(lgr, fmt.Errorf("parsing private zone id: %w", err))
+				}
+
+				privateZone := clients.LoadPrivateZone(privateZoneId)
+				if _, err := privateZone.GetDnsZone(ctx); err != nil {
+					return logger.Error(lgr, fmt.Errorf("getting existing private zone %s: %w", id, err))
+				}
+				ret.PrivateZones[idx] = privateZone
+				return nil
+			})
+		}(idx, id)
+	}
+
+	if err := resEg.Wait(); err != nil {
+		return Provisioned{}, logger.Error(lgr, err)
+	}
+
+	var permEg errgroup.Group
+	for _, z := range ret.Zones {
+		func(scope string) {
+			permEg.Go(func() error {
+				return ensureRoleAssignment(ctx, ret, scope, clients.DnsContributorRole)
+			})
+		}(z.GetId())
+	}
+	for _, pz := range ret.PrivateZones {
+		func(scope string) {
+			permEg.Go(func() error {
+				return ensureRoleAssignment(ctx, ret, scope, clients.PrivateDnsContributorRole)
+			})
+		}(pz.GetId())
+	}
+
+	if err := permEg.Wait(); err != nil {
+		return Provisioned{}, logger.Error(lgr, err)
+	}
+
+	currentConfig := externalDnsConfig(ret)
+	missing, err := anyMissing(ctx, ret.Cluster, manifests.ExternalDnsResources(currentConfig.Conf, currentConfig.Deploy, currentConfig.DnsConfigs))
+	if err != nil {
+		return ret, logger.Error(lgr, fmt.Errorf("checking for external dns: %w", err))
+	}
+	if missing {
+		if err := deployExternalDNS(ctx, ret); err != nil {
+			return ret, logger.Error(lgr, fmt.Errorf("error deploying external dns onto cluster %w", err))
+		}
+	} else {
+		lgr.Info("external dns already deployed, leaving it untouched")
+	}
+
+	nginxDeployment := clients.NewNginxDeployment()
+	ipv4Service, ipv6Service := clients.NewNginxServices(ret.Zones[0].GetName())
+	missing, err = anyMissing(ctx, ret.Cluster, []client.Object{nginxDeployment, ipv4Service, ipv6Service})
+	if err != nil {
+		return ret, logger.Error(lgr, fmt.Errorf("checking for nginx: %w", err))
+	}
+	if missing {
+		if ipv4Service, ipv6Service, err = deployNginx(ctx, ret); err != nil {
+			return ret, logger.Error(lgr, fmt.Errorf("error deploying nginx onto cluster %w", err))
+		}
+	} else {
+		lgr.Info("nginx already deployed, leaving it untouched")
+	}
+
+	ret.Ipv4ServiceName = ipv4Service.Name
+	ret.Ipv6ServiceName = ipv6Service.Name
+
+	return ret, nil
+}
+
+// Assigns role on scope to the identity external dns uses unless it already has it
+func ensureRoleAssignment(ctx context.Context, p Provisioned, scope string, role clients.Role) error {
+	lgr := logger.FromContext(ctx)
+	principalId := p.dnsPrincipalId()
+
+	ok, err := clients.HasRoleAssignment(ctx, p.SubscriptionId, scope, principalId, role)
+	if err != nil {
+		return logger.Error(lgr, fmt.Errorf("checking %s role assignment: %w", role.Name, err))
+	}
+	if ok {
+		lgr.Info("role assignment already exists", "role", role.Name, "scope", scope)
+		return nil
+	}
+
+	if _, err := clients.NewRoleAssignment(ctx, p.SubscriptionId, scope, principalId, role); err != nil {
+		return logger.Error(lgr, fmt.Errorf("creating %s role assignment: %w", role.Name, err))
+	}
+
+	return nil
+}
+
+// Returns whether any of the deployments or services in objs is missing from the cluster. Other kinds, like rbac, come
+// alongside them so they aren't checked
+func anyMissing(ctx context.Context, c cluster, objs []client.Object) (bool, error) {
+	for _, obj := range objs {
+		switch obj.(type) {
+		case *appsv1.Deployment, *corev1.Service:
+		default:
+			continue
+		}
+
+		exists, err := c.Exists(ctx, obj)
+		if err != nil {
+			return false, fmt.Errorf("checking for %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+		if !exists {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Returns the principal id of the identity external dns authenticates as, which needs the dns roles on the zones
+func (p Provisioned) dnsPrincipalId() string {
+	if p.ExternalDnsIdentity != nil {
+		return p.ExternalDnsIdentity.GetPrincipalId()
+	}
+	if p.ExternalDnsServicePrincipal != nil {
+		return p.ExternalDnsServicePrincipal.GetPrincipalId()
+	}
+	return p.Cluster.GetPrincipalId()
+}
+
+// RotateExternalDnsSecret adds a client secret to the external dns service principal, redeploys external dns with it,
+// then removes every other secret so external dns can only be syncing with the new one
+func (p Provisioned) RotateExternalDnsSecret(ctx context.Context) error {
+	lgr := logger.FromContext(ctx).With("infra", p.Name)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to rotate external dns secret")
+	defer lgr.Info("finished rotating external dns secret")
+
+	if p.ExternalDnsServicePrincipal == nil {
+		return fmt.Errorf("external dns of %s doesn't use a service principal", p.Name)
+	}
+
+	secret, keyId, err := p.ExternalDnsServicePrincipal.AddSecret(ctx)
+	if err != nil {
+		return fmt.Errorf("adding secret: %w", err)
+	}
+	p.externalDnsSecret = secret
+
+	// deploy waits for the pods, rolled by the changed azure.json, to be ready before the old secret is removed
+	if err := deployExternalDNS(ctx, p); err != nil {
+		return fmt.Errorf("deploying external dns with new secret: %w", err)
+	}
+
+	if err := p.ExternalDnsServicePrincipal.RemoveSecretsExcept(ctx, keyId); err != nil {
+		return fmt.Errorf("removing old secrets: %w", err)
+	}
+
+	return nil
+}
+
+// Assigns the external dns identity to the nodes of the cluster, where external dns gets its tokens from imds
+func assignNodeIdentity(ctx context.Context, p Provisioned) error {
+	c, ok := p.Cluster.(nodeIdentityCluster)
+	if !ok {
+		return fmt.Errorf("cluster %s can't be assigned node identities", p.Cluster.GetName())
+	}
+
+	return c.AssignNodeIdentity(ctx, p.ExternalDnsIdentity.GetId())
+}
+
+// NodeIdentityMissing returns the node pool scale sets of the cluster that no longer have the external dns identity the
+// infra command assigned them, AKS drops it when it recreates a scale set
+func (p Provisioned) NodeIdentityMissing(ctx context.Context) ([]string, error) {
+	c, ok := p.Cluster.(nodeIdentityCluster)
+	if !ok {
+		return nil, fmt.Errorf("cluster %s can't be assigned node identities", p.Cluster.GetName())
+	}
+	if p.ExternalDnsIdentity == nil {
+		return nil, fmt.Errorf("infrastructure %s has no external dns identity", p.Name)
+	}
+
+	return c.ScaleSetsWithoutIdentity(ctx, p.ExternalDnsIdentity.GetId())
+}
+
+// Federates the external dns identity with the service account of every external dns deployment
+func federateExternalDns(ctx context.Context, p Provisioned) error {
+	c, ok := p.Cluster.(oidcCluster)
+	if !ok {
+		return fmt.Errorf("cluster %s has no oidc issuer", p.Cluster.GetName())
+	}
+
+	issuer, err := c.GetOidcIssuerUrl(ctx)
+	if err != nil {
+		return fmt.Errorf("getting oidc issuer url: %w", err)
+	}
+
+	identity, ok := p.ExternalDnsIdentity.(federatedIdentity)
+	if !ok {
+		return fmt.Errorf("identity %s can't be federated", p.ExternalDnsIdentity.GetName())
+	}
+
+	// credentials of one identity can't be written concurrently, so these are created one at a time
+	currentConfig := externalDnsConfig(p)
+	for _, dnsConfig := range currentConfig.DnsConfigs {
+		name := dnsConfig.Provider.ResourceName()
+		subject := fmt.Sprintf("system:serviceaccount:%s:%s", currentConfig.Conf.NS, name)
+		if err := identity.NewFederatedCredential(ctx, name, issuer, subject); err != nil {
+			return fmt.Errorf("creating federated credential for %s: %w", subject, err)
+		}
+	}
+
+	return nil
+}
+
 // Calls Provision function above on every type of infra specified in command line
 func (is infras) Provision(tenantId, subscriptionId string) ([]Provisioned, error) {
 	lgr := logger.FromContext(context.Background())
@@ -238,21 +648,29 @@ func deployNginx(ctx context.Context, p Provisioned) (*corev1.Service, *corev1.S
 
 }
 
+// DeployExternalDns deploys ExternalDNS onto the cluster, used for local clusters once the fake dns backend it writes to
+// is served and to redeploy another version
+func (p Provisioned) DeployExternalDns(ctx context.Context) error {
+	return deployExternalDNS(ctx, p)
+}
+
+// DeployedExternalDnsVersion returns the image tag of the ExternalDNS deployed onto the cluster
+func (p Provisioned) DeployedExternalDnsVersion() string {
+	return externalDnsConfig(p).Conf.ExternalDnsVersion
+}
+
+// DnsSyncInterval returns how often the ExternalDNS deployed onto the cluster syncs records
+func (p Provisioned) DnsSyncInterval() time.Duration {
+	return externalDnsConfig(p).Conf.DnsSyncInterval
+}
+
 // Deploys ExternalDNS onto cluster
 func deployExternalDNS(ctx context.Context, p Provisioned) error {
 	lgr := logger.FromContext(ctx).With("infra", p.Name)
 	lgr.Info("deploying external DNS onto cluster")
 	defer lgr.Info("finished deploying ext DNS")
 
-	publicZoneName := p.Zones[0].GetName()
-	privateZoneName := p.PrivateZones[0].GetName()
-
-	publicDnsConfig := manifests.GetPublicDnsConfig(p.TenantId, p.SubscriptionId, p.ResourceGroup.GetName(), publicZoneName)
-	privateDnsConfig := manifests.GetPrivateDnsConfig(p.TenantId, p.SubscriptionId, p.ResourceGroup.GetName(), privateZoneName)
-
-	exConfig := manifests.SetExampleConfig(p.Cluster.GetClientId(), p.Cluster.GetId(), publicDnsConfig, privateDnsConfig)
-	currentConfig := exConfig[0] //currently only using one config from external_dns_config.go
-
+	currentConfig := externalDnsConfig(p)
 	objs := manifests.ExternalDnsResources(currentConfig.Conf, currentConfig.Deploy, currentConfig.DnsConfigs)
 
 	if err := p.Cluster.Deploy(ctx, objs); err != nil {
@@ -263,3 +681,46 @@ func deployExternalDNS(ctx context.Context, p Provisioned) error {
 	return nil
 
 }
+
+// Returns the ExternalDNS configuration for the provisioned infrastructure
+func externalDnsConfig(p Provisioned) manifests.ConfigStruct {
+	publicZoneNames := make([]string, len(p.Zones))
+	for i, z := range p.Zones {
+		publicZoneNames[i] = z.GetName()
+	}
+	privateZoneNames := make([]string, len(p.PrivateZones))
+	for i, pz := range p.PrivateZones {
+		privateZoneNames[i] = pz.GetName()
+	}
+
+	publicDnsConfig := manifests.GetPublicDnsConfig(p.TenantId, p.SubscriptionId, p.ResourceGroup.GetName(), publicZoneNames...)
+	privateDnsConfig := manifests.GetPrivateDnsConfig(p.TenantId, p.SubscriptionId, p.ResourceGroup.GetName(), privateZoneNames...)
+	if p.FakeDnsUrl != "" {
+		publicDnsConfig.WebhookUrl = clients.FakeDnsWebhookUrl(p.FakeDnsUrl, false)
+		privateDnsConfig.WebhookUrl = clients.FakeDnsWebhookUrl(p.FakeDnsUrl, true)
+	}
+
+	exConfig := manifests.SetExampleConfig(p.Cluster.GetClientId(), p.Cluster.GetId(), publicDnsConfig, privateDnsConfig)
+	currentConfig := exConfig[0] //currently only using one config from external_dns_config.go
+	if p.ExternalDnsSyncInterval != 0 {
+		currentConfig.Conf.DnsSyncInterval = p.ExternalDnsSyncInterval
+	}
+	if p.ExternalDnsRegistry != "" {
+		currentConfig.Conf.Registry = p.ExternalDnsRegistry
+	}
+	if p.ExternalDnsVersion != "" {
+		currentConfig.Conf.ExternalDnsVersion = p.ExternalDnsVersion
+	}
+	if p.ExternalDnsIdentity != nil {
+		currentConfig.Conf.MSIClientID = p.ExternalDnsIdentity.GetClientId()
+	}
+	for _, dnsConfig := range currentConfig.DnsConfigs {
+		dnsConfig.AuthMode = p.ExternalDnsAuth
+		if p.ExternalDnsServicePrincipal != nil {
+			dnsConfig.AadClientId = p.ExternalDnsServicePrincipal.GetClientId()
+			dnsConfig.AadClientSecret = p.externalDnsSecret
+		}
+	}
+
+	return currentConfig
+}
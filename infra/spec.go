@@ -0,0 +1,410 @@
+package infra
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/google/uuid"
+	"golang.org/x/exp/slices"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	manifests "github.com/Azure/azure-provider-external-dns-e2e/pkgResources/pkgManifests"
+)
+
+// Spec describes the infrastructure the e2e tests run against. It's read from a yaml or json file by the infra command,
+// fields left out are filled in with the same defaults the built-in infrastructure uses. There's deliberately no json
+// schema for it: most rules, like subnets fitting in the vnet or zones sharing a resource group, can't be expressed in
+// one, so LoadSpec stays the single place specs are checked instead of a schema that drifts from it
+type Spec struct {
+	Infras []InfraSpec `json:"infras"`
+}
+
+// InfraSpec describes a single infrastructure
+type InfraSpec struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	// ResourceGroup defaults to the resource group shared by every infra of a run
+	ResourceGroup string          `json:"resourceGroup,omitempty"`
+	Cluster       ClusterSpec     `json:"cluster,omitempty"`
+	Zones         ZonesSpec       `json:"zones,omitempty"`
+	Network       NetworkSpec     `json:"network,omitempty"`
+	ExternalDns   ExternalDnsSpec `json:"externalDns,omitempty"`
+	// Existing adopts an existing cluster and zones instead of provisioning them, for rerunning suites against a dev cluster
+	Existing *ExistingSpec `json:"existing,omitempty"`
+}
+
+// ClusterSpec describes the managed cluster of an infrastructure
+type ClusterSpec struct {
+	// Options are names of cluster options, like "private cluster"
+	Options []string `json:"options,omitempty"`
+}
+
+// ZonesSpec lists the zones to create, each defaults to a single zone with a generated name. Tests use the first of each.
+// Like every provisioned zone, non-alphanumeric characters are dropped from the names and ".com" is appended
+type ZonesSpec struct {
+	Public  []string `json:"public,omitempty"`
+	Private []string `json:"private,omitempty"`
+}
+
+// NetworkSpec describes the dual-stack vnet and subnet the cluster is created in
+type NetworkSpec struct {
+	VnetAddressPrefixes   []string `json:"vnetAddressPrefixes,omitempty"`
+	SubnetAddressPrefixes []string `json:"subnetAddressPrefixes,omitempty"`
+}
+
+// ExternalDnsSpec overrides the configuration of the deployed external-dns
+type ExternalDnsSpec struct {
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+	Registry     string           `json:"registry,omitempty"`
+	// Version is the external-dns image tag, like v0.14.0
+	Version string `json:"version,omitempty"`
+	// Auth is how external-dns authenticates to Azure, managedIdentity (the default), workloadIdentity or servicePrincipalSecret
+	Auth string `json:"auth,omitempty"`
+}
+
+// ExistingSpec holds the resource ids of existing resources. The zones must share a resource group, which tests query
+// records in. Nothing in it is deleted by teardown
+type ExistingSpec struct {
+	Cluster      string   `json:"cluster"`
+	PublicZones  []string `json:"publicZones"`
+	PrivateZones []string `json:"privateZones"`
+}
+
+// DefaultSpec describes the infrastructure used when no spec file is given. Other external-dns auth modes each need
+// a cluster of their own, so they're opt-in through a spec like spec.example.yaml
+var DefaultSpec = Spec{
+	Infras: []InfraSpec{
+		{
+			Name:     "basic cluster",
+			Location: location,
+		},
+		{
+			Name:     "private cluster",
+			Location: location,
+			Cluster:  ClusterSpec{Options: []string{clients.PrivateClusterOpt.Name}},
+		},
+	},
+}
+
+// mcOpts are the cluster options a spec can refer to by name
+var mcOpts = map[string]clients.McOpt{
+	clients.PrivateClusterOpt.Name:   clients.PrivateClusterOpt,
+	clients.WorkloadIdentityOpt.Name: clients.WorkloadIdentityOpt,
+}
+
+// authModes are the external-dns auth modes a spec can refer to by name
+var authModes = map[string]manifests.AuthMode{
+	manifests.ManagedIdentityAuth.String():        manifests.ManagedIdentityAuth,
+	manifests.WorkloadIdentityAuth.String():       manifests.WorkloadIdentityAuth,
+	manifests.ServicePrincipalSecretAuth.String(): manifests.ServicePrincipalSecretAuth,
+}
+
+// LoadSpec reads and validates a yaml or json spec file. Unknown fields are rejected so typos don't silently fall back to defaults
+func LoadSpec(file string) (Spec, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return Spec{}, fmt.Errorf("reading spec file %s: %w", file, err)
+	}
+
+	var spec Spec
+	if err := yaml.UnmarshalStrict(bytes, &spec); err != nil {
+		return Spec{}, fmt.Errorf("parsing spec file %s: %w", file, err)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return Spec{}, fmt.Errorf("validating spec file %s: %w", file, err)
+	}
+
+	return spec, nil
+}
+
+// Validate returns every problem with the spec joined into one error
+func (s Spec) Validate() error {
+	if len(s.Infras) == 0 {
+		return errors.New("spec has no infras")
+	}
+
+	var errs []error
+	names := map[string]struct{}{}
+	networks := map[string]NetworkSpec{}
+	for i, is := range s.Infras {
+		if is.Name == "" {
+			errs = append(errs, fmt.Errorf("infras[%d]: name is required", i))
+		} else if _, ok := names[is.Name]; ok {
+			errs = append(errs, fmt.Errorf("infras[%d]: name %q is used by another infra", i, is.Name))
+		}
+		names[is.Name] = struct{}{}
+
+		for _, err := range is.validate() {
+			errs = append(errs, fmt.Errorf("infras[%d] %q: %w", i, is.Name, err))
+		}
+
+		if is.Existing != nil {
+			continue
+		}
+
+		// infras in the same resource group share a vnet, so they can't ask for different ranges
+		group := strings.ToLower(is.ResourceGroup)
+		network := is.Network.withDefaults()
+		if other, ok := networks[group]; ok && !network.equal(other) {
+			errs = append(errs, fmt.Errorf("infras[%d] %q: network differs from another infra in the same resource group", i, is.Name))
+		}
+		networks[group] = network
+	}
+
+	return errors.Join(errs...)
+}
+
+func (is InfraSpec) validate() []error {
+	if is.Existing != nil {
+		return is.validateExisting()
+	}
+
+	var errs []error
+
+	if is.Location == "" {
+		errs = append(errs, errors.New("location is required"))
+	}
+
+	if len(is.ResourceGroup) > 90 {
+		errs = append(errs, fmt.Errorf("resourceGroup %s is longer than 90 characters", is.ResourceGroup))
+	}
+
+	for _, opt := range is.Cluster.Options {
+		if _, ok := mcOpts[opt]; !ok {
+			errs = append(errs, fmt.Errorf("cluster option %q is unknown", opt))
+		}
+	}
+
+	for _, zone := range append(append([]string{}, is.Zones.Public...), is.Zones.Private...) {
+		for _, msg := range validation.IsDNS1123Subdomain(zone) {
+			errs = append(errs, fmt.Errorf("zone %q: %s", zone, msg))
+		}
+	}
+
+	errs = append(errs, is.Network.validate()...)
+	errs = append(errs, is.ExternalDns.validate()...)
+
+	return errs
+}
+
+// existing infras only take resource ids and external-dns config since everything else comes from the adopted resources
+func (is InfraSpec) validateExisting() []error {
+	var errs []error
+
+	if is.Location != "" || is.ResourceGroup != "" || len(is.Cluster.Options) > 0 || len(is.Zones.Public) > 0 || len(is.Zones.Private) > 0 ||
+		len(is.Network.VnetAddressPrefixes) > 0 || len(is.Network.SubnetAddressPrefixes) > 0 {
+		errs = append(errs, errors.New("only externalDns can be set alongside existing"))
+	}
+
+	errs = append(errs, is.ExternalDns.validate()...)
+	// the identities other auth modes use are provisioned alongside the cluster. An unknown mode is already reported
+	if auth, ok := is.ExternalDns.authMode(); ok && auth != manifests.ManagedIdentityAuth {
+		errs = append(errs, fmt.Errorf("externalDns auth %s can't be used with existing", auth))
+	}
+
+	if _, err := parseExistingId(is.Existing.Cluster, "managedClusters"); err != nil {
+		errs = append(errs, fmt.Errorf("existing cluster: %w", err))
+	}
+
+	if len(is.Existing.PublicZones) == 0 || len(is.Existing.PrivateZones) == 0 {
+		errs = append(errs, errors.New("existing needs at least one public and one private zone"))
+	}
+
+	var group string
+	check := func(id, resourceType string) {
+		r, err := parseExistingId(id, resourceType)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("existing zone: %w", err))
+			return
+		}
+
+		g := strings.ToLower(r.SubscriptionID + "/" + r.ResourceGroup)
+		if group == "" {
+			group = g
+		} else if g != group {
+			errs = append(errs, fmt.Errorf("existing zone %s isn't in the same resource group as the other zones", id))
+		}
+	}
+	for _, id := range is.Existing.PublicZones {
+		check(id, "dnszones")
+	}
+	for _, id := range is.Existing.PrivateZones {
+		check(id, "privateDnsZones")
+	}
+
+	return errs
+}
+
+func parseExistingId(id, resourceType string) (azure.Resource, error) {
+	r, err := azure.ParseResourceID(id)
+	if err != nil {
+		return azure.Resource{}, fmt.Errorf("parsing resource id %q: %w", id, err)
+	}
+	if !strings.EqualFold(r.ResourceType, resourceType) {
+		return azure.Resource{}, fmt.Errorf("resource id %q is a %s, expected %s", id, r.ResourceType, resourceType)
+	}
+
+	return r, nil
+}
+
+func (e ExternalDnsSpec) validate() []error {
+	var errs []error
+
+	if e.SyncInterval != nil && e.SyncInterval.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("externalDns syncInterval %s must be positive", e.SyncInterval.Duration))
+	}
+
+	if _, ok := e.authMode(); !ok {
+		errs = append(errs, fmt.Errorf("externalDns auth %q is unknown", e.Auth))
+	}
+
+	return errs
+}
+
+// authMode returns the auth mode named by e, managed identity when it's left out, and whether the name is known
+func (e ExternalDnsSpec) authMode() (manifests.AuthMode, bool) {
+	if e.Auth == "" {
+		return manifests.ManagedIdentityAuth, true
+	}
+
+	auth, ok := authModes[e.Auth]
+	return auth, ok
+}
+
+func (n NetworkSpec) validate() []error {
+	if len(n.VnetAddressPrefixes) == 0 && len(n.SubnetAddressPrefixes) == 0 {
+		return nil
+	}
+	if len(n.VnetAddressPrefixes) == 0 || len(n.SubnetAddressPrefixes) == 0 {
+		return []error{errors.New("network needs both vnetAddressPrefixes and subnetAddressPrefixes")}
+	}
+
+	var errs []error
+	var vnets []*net.IPNet
+	for _, prefix := range n.VnetAddressPrefixes {
+		_, ipNet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("vnet address prefix: %w", err))
+			continue
+		}
+		vnets = append(vnets, ipNet)
+	}
+
+	// clusters are dual-stack so the subnet needs a range of each family
+	var ipv4, ipv6 bool
+	for _, prefix := range n.SubnetAddressPrefixes {
+		ip, ipNet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("subnet address prefix: %w", err))
+			continue
+		}
+
+		if ip.To4() != nil {
+			ipv4 = true
+		} else {
+			ipv6 = true
+		}
+
+		contained := false
+		for _, vnet := range vnets {
+			vnetOnes, vnetBits := vnet.Mask.Size()
+			ones, bits := ipNet.Mask.Size()
+			if vnet.Contains(ipNet.IP) && vnetBits == bits && vnetOnes <= ones {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			errs = append(errs, fmt.Errorf("subnet address prefix %s isn't inside any vnet address prefix", prefix))
+		}
+	}
+
+	if !ipv4 || !ipv6 {
+		errs = append(errs, errors.New("subnetAddressPrefixes need an IPv4 and an IPv6 range"))
+	}
+
+	return errs
+}
+
+func (n NetworkSpec) withDefaults() NetworkSpec {
+	if len(n.VnetAddressPrefixes) == 0 && len(n.SubnetAddressPrefixes) == 0 {
+		return NetworkSpec{
+			VnetAddressPrefixes:   clients.DefaultVnetAddressPrefixes,
+			SubnetAddressPrefixes: clients.DefaultSubnetAddressPrefixes,
+		}
+	}
+	return n
+}
+
+func (n NetworkSpec) equal(other NetworkSpec) bool {
+	return strings.Join(n.VnetAddressPrefixes, ",") == strings.Join(other.VnetAddressPrefixes, ",") &&
+		strings.Join(n.SubnetAddressPrefixes, ",") == strings.Join(other.SubnetAddressPrefixes, ",")
+}
+
+// ToInfras turns a validated spec into infrastructure configurations, filling in defaults for anything left out
+func (s Spec) ToInfras() infras {
+	ret := make(infras, len(s.Infras))
+	for i, is := range s.Infras {
+		if is.Existing != nil {
+			ret[i] = infra{
+				Name:                 is.Name,
+				Suffix:               uuid.New().String(),
+				ExistingCluster:      is.Existing.Cluster,
+				ExistingZones:        is.Existing.PublicZones,
+				ExistingPrivateZones: is.Existing.PrivateZones,
+				ExternalDnsRegistry:  is.ExternalDns.Registry,
+				ExternalDnsVersion:   is.ExternalDns.Version,
+			}
+			if is.ExternalDns.SyncInterval != nil {
+				ret[i].ExternalDnsSyncInterval = is.ExternalDns.SyncInterval.Duration
+			}
+			continue
+		}
+
+		inf := infra{
+			Name:                  is.Name,
+			Suffix:                uuid.New().String(),
+			ResourceGroup:         is.ResourceGroup,
+			Location:              is.Location,
+			PublicZones:           is.Zones.Public,
+			PrivateZones:          is.Zones.Private,
+			VnetAddressPrefixes:   is.Network.withDefaults().VnetAddressPrefixes,
+			SubnetAddressPrefixes: is.Network.withDefaults().SubnetAddressPrefixes,
+			ExternalDnsRegistry:   is.ExternalDns.Registry,
+			ExternalDnsVersion:    is.ExternalDns.Version,
+		}
+		inf.ExternalDnsAuth, _ = is.ExternalDns.authMode()
+
+		if inf.ResourceGroup == "" {
+			inf.ResourceGroup = rg
+		}
+		if len(inf.PublicZones) == 0 {
+			inf.PublicZones = []string{publicZoneName}
+		}
+		if len(inf.PrivateZones) == 0 {
+			inf.PrivateZones = []string{privateZoneName}
+		}
+		if is.ExternalDns.SyncInterval != nil {
+			inf.ExternalDnsSyncInterval = is.ExternalDns.SyncInterval.Duration
+		}
+		for _, opt := range is.Cluster.Options {
+			inf.McOpts = append(inf.McOpts, mcOpts[opt])
+		}
+		// workload identity needs the oidc issuer to federate with
+		if inf.ExternalDnsAuth == manifests.WorkloadIdentityAuth && !slices.Contains(is.Cluster.Options, clients.WorkloadIdentityOpt.Name) {
+			inf.McOpts = append(inf.McOpts, clients.WorkloadIdentityOpt)
+		}
+
+		ret[i] = inf
+	}
+
+	return ret
+}
@@ -0,0 +1,398 @@
+package infra
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	manifests "github.com/Azure/azure-provider-external-dns-e2e/pkgResources/pkgManifests"
+)
+
+const (
+	testClusterId     = "/subscriptions/sub/resourceGroups/cluster-rg/providers/Microsoft.ContainerService/managedClusters/dev"
+	testZoneId        = "/subscriptions/sub/resourceGroups/zones-rg/providers/Microsoft.Network/dnszones/example.com"
+	testPrivateZoneId = "/subscriptions/sub/resourceGroups/zones-rg/providers/Microsoft.Network/privateDnsZones/example.internal"
+)
+
+func TestLoadSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		wantErr  string
+	}{
+		{
+			name:     "yaml",
+			file:     "spec.yaml",
+			contents: "infras:\n  - name: basic\n    location: westus\n    externalDns:\n      syncInterval: 3m\n",
+		},
+		{
+			name:     "json",
+			file:     "spec.json",
+			contents: `{"infras": [{"name": "basic", "location": "westus", "zones": {"public": ["example"]}}]}`,
+		},
+		{
+			name:     "unknown field",
+			file:     "spec.yaml",
+			contents: "infras:\n  - name: basic\n    location: westus\n    cluster:\n      option: [\"private cluster\"]\n",
+			wantErr:  "unknown field",
+		},
+		{
+			name:     "malformed",
+			file:     "spec.yaml",
+			contents: "infras: [",
+			wantErr:  "parsing spec file",
+		},
+		{
+			name:     "invalid",
+			file:     "spec.yaml",
+			contents: "infras:\n  - name: basic\n",
+			wantErr:  "location is required",
+		},
+		{
+			name:    "missing file",
+			file:    "missing.yaml",
+			wantErr: "reading spec file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tt.file)
+			if tt.contents != "" {
+				if err := os.WriteFile(file, []byte(tt.contents), 0o644); err != nil {
+					t.Fatalf("writing spec: %s", err)
+				}
+			}
+
+			spec, err := LoadSpec(file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loading returned %v, expected an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loading spec: %s", err)
+			}
+
+			if len(spec.Infras) != 1 || spec.Infras[0].Name != "basic" || spec.Infras[0].Location != "westus" {
+				t.Errorf("loaded %+v, expected a single basic infra in westus", spec.Infras)
+			}
+		})
+	}
+
+	t.Run("syncInterval", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "spec.yaml")
+		if err := os.WriteFile(file, []byte(tests[0].contents), 0o644); err != nil {
+			t.Fatalf("writing spec: %s", err)
+		}
+		spec, err := LoadSpec(file)
+		if err != nil {
+			t.Fatalf("loading spec: %s", err)
+		}
+		if got := spec.Infras[0].ExternalDns.SyncInterval; got == nil || got.Duration != 3*time.Minute {
+			t.Errorf("syncInterval is %v, expected 3m", got)
+		}
+	})
+
+	// the example documents every field, so it has to stay valid
+	t.Run("example", func(t *testing.T) {
+		if _, err := LoadSpec("spec.example.yaml"); err != nil {
+			t.Errorf("loading the example spec: %s", err)
+		}
+	})
+}
+
+func TestDefaultSpec(t *testing.T) {
+	if err := DefaultSpec.Validate(); err != nil {
+		t.Fatalf("validating the default spec: %s", err)
+	}
+
+	var names []string
+	for _, is := range DefaultSpec.Infras {
+		names = append(names, is.Name)
+	}
+	if strings.Join(names, ",") != "basic cluster,private cluster" {
+		t.Errorf("default spec has infras %v, expected the basic and private cluster", names)
+	}
+}
+
+func mcOptNames(opts []clients.McOpt) string {
+	var names []string
+	for _, opt := range opts {
+		names = append(names, opt.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func networkOf(inf infra) NetworkSpec {
+	return NetworkSpec{VnetAddressPrefixes: inf.VnetAddressPrefixes, SubnetAddressPrefixes: inf.SubnetAddressPrefixes}
+}
+
+func TestSpecToInfras(t *testing.T) {
+	spec := Spec{Infras: []InfraSpec{
+		{Name: "defaults", Location: "westus"},
+		{
+			Name:          "overrides",
+			Location:      "eastus",
+			ResourceGroup: "my-rg",
+			Cluster:       ClusterSpec{Options: []string{clients.PrivateClusterOpt.Name}},
+			Zones:         ZonesSpec{Public: []string{"public-a", "public-b"}, Private: []string{"private-a"}},
+			Network: NetworkSpec{
+				VnetAddressPrefixes:   []string{"fd00:db8:beef::/48", "10.2.0.0/16"},
+				SubnetAddressPrefixes: []string{"fd00:db8:beef:1::/64", "10.2.0.0/24"},
+			},
+			ExternalDns: ExternalDnsSpec{
+				SyncInterval: &metav1.Duration{Duration: 3 * time.Minute},
+				Registry:     "example.azurecr.io",
+				Version:      "v0.14.0",
+				Auth:         manifests.ServicePrincipalSecretAuth.String(),
+			},
+		},
+		{Name: "workload identity", Location: "westus", ExternalDns: ExternalDnsSpec{Auth: manifests.WorkloadIdentityAuth.String()}},
+		{
+			Name:        "workload identity option",
+			Location:    "westus",
+			Cluster:     ClusterSpec{Options: []string{clients.WorkloadIdentityOpt.Name}},
+			ExternalDns: ExternalDnsSpec{Auth: manifests.WorkloadIdentityAuth.String()},
+		},
+		{
+			Name:        "existing",
+			Existing:    &ExistingSpec{Cluster: testClusterId, PublicZones: []string{testZoneId}, PrivateZones: []string{testPrivateZoneId}},
+			ExternalDns: ExternalDnsSpec{Version: "v0.13.6", SyncInterval: &metav1.Duration{Duration: time.Minute}},
+		},
+	}}
+	if err := spec.Validate(); err != nil {
+		t.Fatalf("validating spec: %s", err)
+	}
+
+	got := spec.ToInfras()
+	if len(got) != len(spec.Infras) {
+		t.Fatalf("got %d infras, expected %d", len(got), len(spec.Infras))
+	}
+	for i, inf := range got {
+		if inf.Name != spec.Infras[i].Name {
+			t.Errorf("infra %d is %s, expected %s", i, inf.Name, spec.Infras[i].Name)
+		}
+		if inf.Suffix == "" {
+			t.Errorf("infra %s has no suffix", inf.Name)
+		}
+	}
+
+	defaults := got[0]
+	if defaults.ResourceGroup != rg || defaults.Location != "westus" {
+		t.Errorf("defaults infra is in %s %s, expected the shared resource group in westus", defaults.ResourceGroup, defaults.Location)
+	}
+	if strings.Join(defaults.PublicZones, ",") != publicZoneName || strings.Join(defaults.PrivateZones, ",") != privateZoneName {
+		t.Errorf("defaults infra has zones %v and %v, expected the generated zone names", defaults.PublicZones, defaults.PrivateZones)
+	}
+	if !networkOf(defaults).equal(NetworkSpec{VnetAddressPrefixes: clients.DefaultVnetAddressPrefixes, SubnetAddressPrefixes: clients.DefaultSubnetAddressPrefixes}) {
+		t.Errorf("defaults infra has network %v, expected the default ranges", networkOf(defaults))
+	}
+	if defaults.ExternalDnsAuth != manifests.ManagedIdentityAuth || defaults.ExternalDnsSyncInterval != 0 || len(defaults.McOpts) != 0 {
+		t.Errorf("defaults infra has auth %s, sync interval %s and cluster options %q, expected none set", defaults.ExternalDnsAuth, defaults.ExternalDnsSyncInterval, mcOptNames(defaults.McOpts))
+	}
+
+	overrides := got[1]
+	if overrides.ResourceGroup != "my-rg" || overrides.Location != "eastus" {
+		t.Errorf("overrides infra is in %s %s, expected my-rg in eastus", overrides.ResourceGroup, overrides.Location)
+	}
+	if strings.Join(overrides.PublicZones, ",") != "public-a,public-b" || strings.Join(overrides.PrivateZones, ",") != "private-a" {
+		t.Errorf("overrides infra has zones %v and %v", overrides.PublicZones, overrides.PrivateZones)
+	}
+	if !networkOf(overrides).equal(spec.Infras[1].Network) {
+		t.Errorf("overrides infra has network %v, expected %v", networkOf(overrides), spec.Infras[1].Network)
+	}
+	if overrides.ExternalDnsSyncInterval != 3*time.Minute || overrides.ExternalDnsRegistry != "example.azurecr.io" || overrides.ExternalDnsVersion != "v0.14.0" {
+		t.Errorf("overrides infra has external-dns %s %s every %s", overrides.ExternalDnsRegistry, overrides.ExternalDnsVersion, overrides.ExternalDnsSyncInterval)
+	}
+	if overrides.ExternalDnsAuth != manifests.ServicePrincipalSecretAuth {
+		t.Errorf("overrides infra has auth %s, expected %s", overrides.ExternalDnsAuth, manifests.ServicePrincipalSecretAuth)
+	}
+	if names := mcOptNames(overrides.McOpts); names != clients.PrivateClusterOpt.Name {
+		t.Errorf("overrides infra has cluster options %q, expected %q", names, clients.PrivateClusterOpt.Name)
+	}
+
+	// workload identity needs the oidc issuer, which is only enabled once
+	for _, inf := range got[2:4] {
+		if inf.ExternalDnsAuth != manifests.WorkloadIdentityAuth {
+			t.Errorf("%s infra has auth %s, expected %s", inf.Name, inf.ExternalDnsAuth, manifests.WorkloadIdentityAuth)
+		}
+		if names := mcOptNames(inf.McOpts); names != clients.WorkloadIdentityOpt.Name {
+			t.Errorf("%s infra has cluster options %q, expected %q", inf.Name, names, clients.WorkloadIdentityOpt.Name)
+		}
+	}
+
+	existing := got[4]
+	if existing.ExistingCluster != testClusterId || strings.Join(existing.ExistingZones, ",") != testZoneId || strings.Join(existing.ExistingPrivateZones, ",") != testPrivateZoneId {
+		t.Errorf("existing infra adopts %s, %v and %v", existing.ExistingCluster, existing.ExistingZones, existing.ExistingPrivateZones)
+	}
+	if existing.ResourceGroup != "" || len(existing.PublicZones) > 0 || len(existing.PrivateZones) > 0 {
+		t.Errorf("existing infra provisions resource group %q and zones %v and %v, expected nothing", existing.ResourceGroup, existing.PublicZones, existing.PrivateZones)
+	}
+	if existing.ExternalDnsVersion != "v0.13.6" || existing.ExternalDnsSyncInterval != time.Minute {
+		t.Errorf("existing infra has external-dns %s every %s, expected v0.13.6 every 1m", existing.ExternalDnsVersion, existing.ExternalDnsSyncInterval)
+	}
+}
+
+func TestSpecValidate(t *testing.T) {
+	valid := func(name string) InfraSpec {
+		return InfraSpec{Name: name, Location: "westus"}
+	}
+	existing := func() *ExistingSpec {
+		return &ExistingSpec{Cluster: testClusterId, PublicZones: []string{testZoneId}, PrivateZones: []string{testPrivateZoneId}}
+	}
+	network := func(vnet, subnet []string) NetworkSpec {
+		return NetworkSpec{VnetAddressPrefixes: vnet, SubnetAddressPrefixes: subnet}
+	}
+
+	tests := []struct {
+		name   string
+		infras []InfraSpec
+		// wantErrs are contained in the joined errors, one per line in order
+		wantErrs []string
+	}{
+		{name: "valid", infras: []InfraSpec{valid("a"), valid("b")}},
+		{name: "valid existing", infras: []InfraSpec{{Name: "dev", Existing: existing()}}},
+		{name: "no infras", wantErrs: []string{"spec has no infras"}},
+		{name: "no name", infras: []InfraSpec{valid("")}, wantErrs: []string{"infras[0]: name is required"}},
+		{name: "duplicate name", infras: []InfraSpec{valid("a"), valid("a")}, wantErrs: []string{`infras[1]: name "a" is used by another infra`}},
+		{name: "no location", infras: []InfraSpec{{Name: "a"}}, wantErrs: []string{`infras[0] "a": location is required`}},
+		{
+			name:     "long resource group",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", ResourceGroup: strings.Repeat("r", 91)}},
+			wantErrs: []string{"is longer than 90 characters"},
+		},
+		{
+			name:     "unknown cluster option",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", Cluster: ClusterSpec{Options: []string{"tiny cluster"}}}},
+			wantErrs: []string{`cluster option "tiny cluster" is unknown`},
+		},
+		{
+			name:     "invalid zone",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", Zones: ZonesSpec{Public: []string{"example"}, Private: []string{"Bad_Zone"}}}},
+			wantErrs: []string{`zone "Bad_Zone": a lowercase RFC 1123 subdomain`},
+		},
+		{
+			name:     "vnet without subnet",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", Network: network([]string{"10.2.0.0/16"}, nil)}},
+			wantErrs: []string{"network needs both vnetAddressPrefixes and subnetAddressPrefixes"},
+		},
+		{
+			name:     "invalid vnet prefix",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", Network: network([]string{"10.2.0.0", "fd00:db8:beef::/48"}, []string{"fd00:db8:beef:1::/64"})}},
+			wantErrs: []string{"vnet address prefix: invalid CIDR address", "subnetAddressPrefixes need an IPv4 and an IPv6 range"},
+		},
+		{
+			name:     "invalid subnet prefix",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", Network: network([]string{"10.2.0.0/16", "fd00:db8:beef::/48"}, []string{"10.2.0.0/24", "fd00:db8:beef:1::"})}},
+			wantErrs: []string{"subnet address prefix: invalid CIDR address", "subnetAddressPrefixes need an IPv4 and an IPv6 range"},
+		},
+		{
+			name:     "subnet outside vnet",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", Network: network([]string{"10.2.0.0/16", "fd00:db8:beef::/48"}, []string{"10.3.0.0/24", "fd00:db8:beef:1::/64"})}},
+			wantErrs: []string{"subnet address prefix 10.3.0.0/24 isn't inside any vnet address prefix"},
+		},
+		{
+			name:     "single stack subnet",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", Network: network([]string{"10.2.0.0/16"}, []string{"10.2.0.0/24"})}},
+			wantErrs: []string{"subnetAddressPrefixes need an IPv4 and an IPv6 range"},
+		},
+		{
+			name: "different networks in a resource group",
+			infras: []InfraSpec{
+				valid("a"),
+				{Name: "b", Location: "westus", Network: network([]string{"10.2.0.0/16", "fd00:db8:beef::/48"}, []string{"10.2.0.0/24", "fd00:db8:beef:1::/64"})},
+			},
+			wantErrs: []string{`infras[1] "b": network differs from another infra in the same resource group`},
+		},
+		{
+			name: "different networks in different resource groups",
+			infras: []InfraSpec{
+				valid("a"),
+				{Name: "b", Location: "westus", ResourceGroup: "other", Network: network([]string{"10.2.0.0/16", "fd00:db8:beef::/48"}, []string{"10.2.0.0/24", "fd00:db8:beef:1::/64"})},
+			},
+		},
+		{
+			name:     "non-positive sync interval",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", ExternalDns: ExternalDnsSpec{SyncInterval: &metav1.Duration{}}}},
+			wantErrs: []string{"externalDns syncInterval 0s must be positive"},
+		},
+		{
+			name:     "unknown auth",
+			infras:   []InfraSpec{{Name: "a", Location: "westus", ExternalDns: ExternalDnsSpec{Auth: "password"}}},
+			wantErrs: []string{`externalDns auth "password" is unknown`},
+		},
+		{
+			name:     "existing with provisioned fields",
+			infras:   []InfraSpec{{Name: "dev", Location: "westus", Zones: ZonesSpec{Public: []string{"example"}}, Existing: existing()}},
+			wantErrs: []string{"only externalDns can be set alongside existing"},
+		},
+		{
+			name:     "existing with another auth mode",
+			infras:   []InfraSpec{{Name: "dev", Existing: existing(), ExternalDns: ExternalDnsSpec{Auth: manifests.WorkloadIdentityAuth.String()}}},
+			wantErrs: []string{"externalDns auth workloadIdentity can't be used with existing"},
+		},
+		{
+			// the unknown mode is reported once, not also as a mode existing can't use
+			name:     "existing with unknown auth",
+			infras:   []InfraSpec{{Name: "dev", Existing: existing(), ExternalDns: ExternalDnsSpec{Auth: "password"}}},
+			wantErrs: []string{`externalDns auth "password" is unknown`},
+		},
+		{
+			name:     "existing cluster of the wrong type",
+			infras:   []InfraSpec{{Name: "dev", Existing: &ExistingSpec{Cluster: testZoneId, PublicZones: []string{testZoneId}, PrivateZones: []string{testPrivateZoneId}}}},
+			wantErrs: []string{"existing cluster: resource id \"" + testZoneId + "\" is a dnszones, expected managedClusters"},
+		},
+		{
+			name:     "existing without zones",
+			infras:   []InfraSpec{{Name: "dev", Existing: &ExistingSpec{Cluster: testClusterId, PublicZones: []string{testZoneId}}}},
+			wantErrs: []string{"existing needs at least one public and one private zone"},
+		},
+		{
+			name:     "existing zone that isn't an id",
+			infras:   []InfraSpec{{Name: "dev", Existing: &ExistingSpec{Cluster: testClusterId, PublicZones: []string{"example.com"}, PrivateZones: []string{testPrivateZoneId}}}},
+			wantErrs: []string{`existing zone: parsing resource id "example.com"`},
+		},
+		{
+			name: "existing zones in different resource groups",
+			infras: []InfraSpec{{Name: "dev", Existing: &ExistingSpec{
+				Cluster:      testClusterId,
+				PublicZones:  []string{testZoneId},
+				PrivateZones: []string{strings.Replace(testPrivateZoneId, "zones-rg", "other-rg", 1)},
+			}}},
+			wantErrs: []string{"isn't in the same resource group as the other zones"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Spec{Infras: tt.infras}.Validate()
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("validating: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validating returned no error, expected %q", tt.wantErrs)
+			}
+
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(tt.wantErrs) {
+				t.Fatalf("validating returned %d errors %q, expected %d", len(lines), lines, len(tt.wantErrs))
+			}
+			for i, want := range tt.wantErrs {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("error %d is %q, expected it to contain %q", i, lines[i], want)
+				}
+			}
+		})
+	}
+}
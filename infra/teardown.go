@@ -0,0 +1,105 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+)
+
+// TeardownResult records what happened to one resource group during teardown
+type TeardownResult struct {
+	ResourceGroup string
+	Infras        []string
+	// ServicePrincipals are the app registrations of infras in the resource group, which live outside of it
+	ServicePrincipals []string
+	// Deleted is false when the resource group was already gone, belongs to a local infra with nothing in Azure, or
+	// holds existing resources the infra adopted
+	Deleted bool
+	Err     error
+}
+
+// Teardown deletes the resource groups holding provisioned infrastructure. Infras sharing a resource group only delete it once,
+// local infras are skipped since their zones only ever existed in the fake dns backend, and so are existing infras since
+// the infra command didn't create their resources
+func Teardown(ctx context.Context, provisioned []Provisioned) ([]TeardownResult, error) {
+	lgr := logger.FromContext(ctx)
+	lgr.Info("starting to tear down all infrastructure")
+	defer lgr.Info("finished tearing down all infrastructure")
+
+	var results []*TeardownResult
+	byId := map[string]*TeardownResult{}
+	subscriptions := map[*TeardownResult]string{}
+	keep := map[*TeardownResult]bool{}
+	servicePrincipals := map[*TeardownResult][]servicePrincipal{}
+	for _, p := range provisioned {
+		id := strings.ToLower(p.ResourceGroup.GetId())
+		result, ok := byId[id]
+		if !ok {
+			result = &TeardownResult{ResourceGroup: p.ResourceGroup.GetName()}
+			byId[id] = result
+			results = append(results, result)
+			subscriptions[result] = p.SubscriptionId
+		}
+		result.Infras = append(result.Infras, p.Name)
+		if p.ExternalDnsServicePrincipal != nil {
+			result.ServicePrincipals = append(result.ServicePrincipals, p.ExternalDnsServicePrincipal.GetName())
+			servicePrincipals[result] = append(servicePrincipals[result], p.ExternalDnsServicePrincipal)
+		}
+
+		// a resource group is kept if any infra in it wasn't provisioned in azure by the infra command
+		if p.FakeDnsUrl != "" || p.Existing {
+			keep[result] = true
+		}
+	}
+	for result := range keep {
+		delete(subscriptions, result)
+	}
+
+	var eg errgroup.Group
+	for _, result := range results {
+		subscriptionId, ok := subscriptions[result]
+		if !ok {
+			lgr.Info("skipping resource group of local or existing infrastructure", "resourceGroup", result.ResourceGroup)
+			continue
+		}
+
+		func(result *TeardownResult, subscriptionId string) {
+			eg.Go(func() error {
+				lgr := lgr.With("resourceGroup", result.ResourceGroup, "infras", result.Infras)
+				ctx := logger.WithContext(ctx, lgr)
+
+				// the resource group is deleted even when a service principal can't be, so the cluster and zones don't leak
+				var errs []error
+				for _, sp := range servicePrincipals[result] {
+					if _, err := sp.Delete(ctx); err != nil {
+						errs = append(errs, logger.Error(lgr, fmt.Errorf("deleting service principal %s: %w", sp.GetName(), err)))
+					}
+				}
+
+				deleted, err := clients.DeleteResourceGroup(ctx, subscriptionId, result.ResourceGroup)
+				result.Deleted = deleted
+				if err != nil {
+					errs = append(errs, logger.Error(lgr, fmt.Errorf("deleting resource group %s: %w", result.ResourceGroup, err)))
+				}
+
+				result.Err = errors.Join(errs...)
+				return result.Err
+			})
+		}(result, subscriptionId)
+	}
+
+	err := eg.Wait()
+
+	ret := make([]TeardownResult, len(results))
+	for i, result := range results {
+		ret[i] = *result
+	}
+
+	return ret, err
+}
@@ -2,6 +2,7 @@ package infra
 
 import (
 	"context"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
@@ -9,9 +10,12 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
 	"github.com/Azure/go-autorest/autorest/azure"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	manifests "github.com/Azure/azure-provider-external-dns-e2e/pkgResources/pkgManifests"
 )
 
 type infras []infra
@@ -23,6 +27,24 @@ type infra struct {
 	// for resources to be provisioned inside
 	ResourceGroup, Location string
 	McOpts                  []clients.McOpt
+	// PublicZones and PrivateZones are the names of the zones to create, the first of each is used by tests
+	PublicZones, PrivateZones []string
+	// VnetAddressPrefixes and SubnetAddressPrefixes are the dual-stack ranges of the network the cluster is created in
+	VnetAddressPrefixes, SubnetAddressPrefixes []string
+	// ExternalDnsSyncInterval, ExternalDnsRegistry and ExternalDnsVersion override the defaults of the deployed
+	// external-dns when set
+	ExternalDnsSyncInterval time.Duration
+	ExternalDnsRegistry     string
+	ExternalDnsVersion      string
+	// ExternalDnsAuth is how the deployed external-dns authenticates, workload identity creates an identity federated
+	// with its service accounts and service principal secret creates an app registration
+	ExternalDnsAuth manifests.AuthMode
+	// ExistingCluster, ExistingZones and ExistingPrivateZones are resource ids of resources to adopt instead of provisioning
+	ExistingCluster                     string
+	ExistingZones, ExistingPrivateZones []string
+	// Kubeconfig points at an existing cluster, like kind, to use instead of provisioning AKS. Zones for these infras
+	// live in the fake dns backend served on FakeDnsUrl which external-dns reaches through its webhook provider
+	Kubeconfig, FakeDnsUrl string
 }
 
 // McOpt specifies what kind of managed cluster to create
@@ -55,6 +77,14 @@ type Identifier interface {
 type cluster interface {
 	GetVnetId(ctx context.Context) (string, error)
 	Deploy(ctx context.Context, objs []client.Object) error
+	GetService(ctx context.Context, namespace, name string) (*corev1.Service, error)
+	GetDeployment(ctx context.Context, namespace, name string) (*appsv1.Deployment, error)
+	AnnotateService(ctx context.Context, namespace, name string, annotations map[string]string) error
+	RemoveServiceAnnotations(ctx context.Context, namespace, name string, keys []string) error
+	Delete(ctx context.Context, obj client.Object) error
+	DeleteAllOf(ctx context.Context, obj client.Object, labels map[string]string) error
+	Exists(ctx context.Context, obj client.Object) (bool, error)
+	GetName() string
 	GetPrincipalId() string
 	GetClientId() string
 	GetLocation() string
@@ -64,6 +94,22 @@ type cluster interface {
 	Identifier
 }
 
+// kubeconfigCluster is implemented by clusters reached through a kubeconfig instead of arm
+type kubeconfigCluster interface {
+	GetKubeconfig() string
+}
+
+// oidcCluster is implemented by clusters with an oidc issuer identities can federate with
+type oidcCluster interface {
+	GetOidcIssuerUrl(ctx context.Context) (string, error)
+}
+
+// nodeIdentityCluster is implemented by clusters whose nodes can be assigned user-assigned identities
+type nodeIdentityCluster interface {
+	AssignNodeIdentity(ctx context.Context, identityId string) error
+	ScaleSetsWithoutIdentity(ctx context.Context, identityId string) ([]string, error)
+}
+
 type zone interface {
 	GetDnsZone(ctx context.Context) (*armdns.Zone, error)
 	GetName() string
@@ -78,6 +124,29 @@ type privateZone interface {
 	Identifier
 }
 
+type identity interface {
+	GetName() string
+	GetClientId() string
+	GetPrincipalId() string
+	Identifier
+}
+
+// servicePrincipal is an app registration external-dns authenticates as with a client secret
+type servicePrincipal interface {
+	AddSecret(ctx context.Context) (string, string, error)
+	RemoveSecretsExcept(ctx context.Context, keyId string) error
+	Delete(ctx context.Context) (bool, error)
+	GetName() string
+	GetClientId() string
+	GetPrincipalId() string
+	Identifier
+}
+
+// federatedIdentity is implemented by identities that can trust tokens of an oidc issuer
+type federatedIdentity interface {
+	NewFederatedCredential(ctx context.Context, name, issuer, subject string) error
+}
+
 type resourceGroup interface {
 	GetName() string
 	Identifier
@@ -95,6 +164,24 @@ type Provisioned struct {
 	PrivateZones    []privateZone
 	Ipv4ServiceName string
 	Ipv6ServiceName string
+	// FakeDnsUrl is set when the zones live in the fake dns backend rather than Azure
+	FakeDnsUrl string
+	// Existing is set when the resources were adopted rather than provisioned, teardown leaves them alone
+	Existing bool
+	// ExternalDnsSyncInterval, ExternalDnsRegistry and ExternalDnsVersion override the defaults of the deployed
+	// external-dns when set
+	ExternalDnsSyncInterval time.Duration
+	ExternalDnsRegistry     string
+	ExternalDnsVersion      string
+	ExternalDnsAuth         manifests.AuthMode
+	// ExternalDnsIdentity is the identity external-dns uses with workload identity, or the node identity it uses with
+	// managed identity on provisioned clusters. Nil when external-dns uses the kubelet identity
+	ExternalDnsIdentity identity
+	// ExternalDnsServicePrincipal is the app registration external-dns uses with service principal secret auth, nil otherwise
+	ExternalDnsServicePrincipal servicePrincipal
+	// externalDnsSecret is the current client secret of ExternalDnsServicePrincipal. It's only known to the command that
+	// added it and is never saved, so it's empty once loaded from a file
+	externalDnsSecret string
 }
 
 type LoadableZone struct {
@@ -102,6 +189,16 @@ type LoadableZone struct {
 	Nameservers []string
 }
 
+type LoadableIdentity struct {
+	ResourceId            azure.Resource
+	ClientId, PrincipalId string
+}
+
+type LoadableServicePrincipal struct {
+	Name, AppObjectId     string
+	ClientId, PrincipalId string
+}
+
 // LoadableProvisioned is a struct that can be used to load a Provisioned struct from a file.
 // Ensure that all fields are exported so that they can properly be serialized/deserialized.
 type LoadableProvisioned struct {
@@ -109,6 +206,7 @@ type LoadableProvisioned struct {
 	Cluster                                                                   azure.Resource
 	ClusterLocation, ClusterDnsServiceIp, ClusterPrincipalId, ClusterClientId string
 	ClusterOptions                                                            map[string]struct{}
+	ClusterKubeconfig                                                         string         // only set for local clusters
 	ResourceGroup                                                             arm.ResourceID // rg id is a little weird and can't be correctly parsed by azure.Resource so we have to use arm.ResourceID
 	SubscriptionId                                                            string
 	TenantId                                                                  string
@@ -116,4 +214,12 @@ type LoadableProvisioned struct {
 	PrivateZones                                                              []azure.Resource
 	Ipv4ServiceName                                                           string
 	Ipv6ServiceName                                                           string
+	FakeDnsUrl                                                                string
+	ExternalDnsSyncInterval                                                   time.Duration
+	ExternalDnsRegistry                                                       string
+	ExternalDnsVersion                                                        string
+	ExternalDnsAuth                                                           manifests.AuthMode
+	ExternalDnsIdentity                                                       *LoadableIdentity
+	ExternalDnsServicePrincipal                                               *LoadableServicePrincipal
+	Existing                                                                  bool
 }
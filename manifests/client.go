@@ -0,0 +1,89 @@
+package manifests
+
+import (
+	_ "embed"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AzureDnsNameserver is the virtual ip of Azure DNS, which answers for the private zones linked to the vnet of the caller
+const AzureDnsNameserver = "168.63.129.16"
+
+const (
+	clientImage      = "mcr.microsoft.com/oss/go/microsoft/golang:1.20"
+	clientSourceFile = "client.go"
+	clientSourcePath = "/go/src"
+)
+
+//go:embed embedded/client.go
+var clientContents string
+
+// ClientJob returns a ConfigMap holding the source of the embedded client and a Job running it, which resolves the host
+// of url through nameserver and fails unless it can reach url. Both are named name and must be deployed together
+func ClientJob(name, namespace, nameserver, url string) []client.Object {
+	labels := map[string]string{"app": name, ManagedByKey: ManagedByVal}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Data: map[string]string{clientSourceFile: clientContents},
+	}
+
+	// the job fails on the first failed pod, the client retries until the record resolves itself
+	var backoffLimit int32 = 0
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:       "client",
+						Image:      clientImage,
+						Command:    []string{"go", "run", clientSourceFile},
+						WorkingDir: clientSourcePath,
+						Env: []corev1.EnvVar{
+							{Name: "NAMESERVER", Value: nameserver},
+							{Name: "URL", Value: url},
+						},
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      "source",
+							MountPath: clientSourcePath,
+							ReadOnly:  true,
+						}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "source",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: name},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	return []client.Object{cm, job}
+}
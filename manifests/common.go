@@ -6,6 +6,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,6 +29,7 @@ func init() {
 	// add any types used in this package
 	batchv1.AddToScheme(scheme)
 	corev1.AddToScheme(scheme)
+	networkingv1.AddToScheme(scheme)
 	metav1.AddMetaToScheme(scheme)
 	appsv1.AddToScheme(scheme)
 	policyv1.AddToScheme(scheme)
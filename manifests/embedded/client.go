@@ -3,67 +3,89 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
+// these need to be set in the container spec
+const (
+	nameserverEnv = "NAMESERVER"
+	urlEnv        = "URL"
+)
+
+const (
+	// the private record is created before the job runs, retries only cover it propagating to the nameserver
+	attempts = 24
+	interval = 5 * time.Second
+)
+
+// Resolves the host of URL through NAMESERVER only and sends a request to it, exiting non-zero when it never succeeds
+// so the job fails
 func main() {
-	nameserver := os.Getenv("NAMESERVER")
+	nameserver := os.Getenv(nameserverEnv)
+	if nameserver == "" {
+		log.Fatalf("missing env %s", nameserverEnv)
+	}
+	url := os.Getenv(urlEnv)
+	if url == "" {
+		log.Fatalf("missing env %s", urlEnv)
+	}
+	// azure dns returns nameservers with a trailing period, a single entry from the vnet doesn't have one
+	nameserver = strings.TrimSuffix(nameserver, ".")
 
-	dialer := &net.Dialer{Resolver: &net.Resolver{
+	resolver := &net.Resolver{
 		PreferGo: true,
 		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
 			d := net.Dialer{Timeout: time.Second}
-			var ns string
-			if nameserver[:len(nameserver)-1] == "." {
-				ns = nameserver[:len(ns)-1] // remove trailing period added for some reason by azure dns
-			} else {
-				ns = nameserver // no need to remove trailing period if single entry coming from k8s vnet ns server
-			}
-
-			return d.DialContext(ctx, "tcp", ns+":53")
+			return d.DialContext(ctx, "tcp", net.JoinHostPort(nameserver, "53"))
 		},
-	}}
-	client := &http.Client{Transport: &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		DialContext:     dialer.DialContext,
-	}}
+	}
+	dialer := &net.Dialer{Resolver: resolver}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext:     dialer.DialContext,
+		},
+	}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		resp, err := client.Get(os.Getenv("URL"))
-		if err != nil {
-			log.Printf("error sending request: %s", err)
-			w.WriteHeader(500)
+	for i := 1; i <= attempts; i++ {
+		err := get(client, url)
+		if err == nil {
+			log.Printf("reached %s resolving through %s", url, nameserver)
 			return
 		}
-		defer resp.Body.Close()
 
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Printf("error reading response body: %s", err)
-			w.WriteHeader(500)
-			return
-		}
-		log.Printf("received response %s from url %s", string(body), os.Getenv("URL"))
-		if string(body) != "hello world" {
-			log.Printf("unexpected response body: %s", body)
-			w.WriteHeader(500)
-			return
+		log.Printf("attempt %d of %d: %s", i, attempts, err)
+		if i < attempts {
+			time.Sleep(interval)
 		}
-		if val := resp.Header.Get("TestHeader"); val != "test-header-value" {
-			log.Printf("unexpected test header: %s", val)
-			w.WriteHeader(500)
-			return
-		}
-		if val := resp.Header.Get("OriginalForwardedFor"); val != os.Getenv("POD_IP") {
-			log.Printf("server replied with unexpected X-Forwarded-For header: %s", val)
-			w.WriteHeader(500)
-			return
-		}
-	})
-	panic(http.ListenAndServe(":8080", nil))
+	}
+
+	log.Fatalf("unable to reach %s resolving through %s", url, nameserver)
+}
+
+func get(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d with body %s", resp.StatusCode, body)
+	}
+
+	log.Printf("received status %d from url %s", resp.StatusCode, url)
+	return nil
 }
@@ -19,6 +19,8 @@ const (
 	PublicZoneType         = "dnszones"
 	PrivateZoneType        = "privatednszones"
 	defaultDnsSyncInterval = 3 * time.Minute
+	// DefaultExternalDnsVersion is the external-dns image tag deployed unless another is given
+	DefaultExternalDnsVersion = "v0.14.0"
 )
 
 var Flags = &Config{}
@@ -27,6 +29,7 @@ var dnsZonesString string
 func init() {
 	flag.StringVar(&Flags.NS, "namespace", DefaultNs, "namespace for managed resources")
 	flag.StringVar(&Flags.Registry, "registry", "mcr.microsoft.com", "container image registry to use for managed components")
+	flag.StringVar(&Flags.ExternalDnsVersion, "external-dns-version", DefaultExternalDnsVersion, "external-dns image tag to deploy")
 	flag.StringVar(&Flags.MSIClientID, "msi", "", "client ID of the MSI to use when accessing Azure resources")
 	flag.StringVar(&Flags.TenantID, "tenant-id", "", "AAD tenant ID to use when accessing Azure resources")
 	flag.StringVar(&Flags.Cloud, "cloud", "AzurePublicCloud", "azure cloud name")
@@ -53,6 +56,7 @@ type Config struct {
 	ServiceAccountTokenPath             string
 	MetricsAddr, ProbeAddr              string
 	NS, Registry                        string
+	ExternalDnsVersion                  string
 	DisableKeyvault                     bool
 	MSIClientID, TenantID               string
 	Cloud, Location                     string
@@ -71,6 +75,9 @@ func (c *Config) Validate() error {
 	if c.Registry == "" {
 		return errors.New("--registry is required")
 	}
+	if c.ExternalDnsVersion == "" {
+		return errors.New("--external-dns-version is required")
+	}
 	if c.MSIClientID == "" {
 		return errors.New("--msi is required")
 	}
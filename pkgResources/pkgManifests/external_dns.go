@@ -68,6 +68,42 @@ func (p Provider) ResourceName() string {
 	}
 }
 
+// AuthMode is how external-dns authenticates to Azure
+type AuthMode int
+
+const (
+	// ManagedIdentityAuth uses the identity assigned to the nodes through the instance metadata service
+	ManagedIdentityAuth AuthMode = iota
+	// WorkloadIdentityAuth exchanges a projected service account token for a token of the identity with MSIClientID,
+	// which needs a federated credential for the service account against the cluster oidc issuer
+	WorkloadIdentityAuth
+	// ServicePrincipalSecretAuth uses the client id and secret of an app registration, kept in a Secret instead of a ConfigMap
+	ServicePrincipalSecretAuth
+)
+
+const (
+	// WorkloadIdentityUseLabel marks the service account and pods of external-dns using workload identity
+	WorkloadIdentityUseLabel    = "azure.workload.identity/use"
+	workloadIdentityClientIdKey = "azure.workload.identity/client-id"
+	// the names and paths the workload identity webhook injects, matching them means the webhook leaves them alone where it runs
+	workloadIdentityTokenVolume = "azure-identity-token"
+	workloadIdentityTokenDir    = "/var/run/secrets/azure/tokens"
+	workloadIdentityAudience    = "api://AzureADTokenExchange"
+)
+
+func (a AuthMode) String() string {
+	switch a {
+	case ManagedIdentityAuth:
+		return "managedIdentity"
+	case WorkloadIdentityAuth:
+		return "workloadIdentity"
+	case ServicePrincipalSecretAuth:
+		return "servicePrincipalSecret"
+	default:
+		return ""
+	}
+}
+
 func (p Provider) Labels() map[string]string {
 	labels := map[string]string{
 		k8sNameKey: p.ResourceName(),
@@ -80,6 +116,11 @@ type ExternalDnsConfig struct {
 	TenantId, Subscription, ResourceGroup string
 	Provider                              Provider
 	DnsZoneResourceIDs                    []string
+	// WebhookUrl points external-dns at a webhook provider instead of Azure DNS when set, used with the fake dns backend
+	WebhookUrl string
+	AuthMode   AuthMode
+	// AadClientId and AadClientSecret are the app registration credentials used with ServicePrincipalSecretAuth
+	AadClientId, AadClientSecret string
 }
 
 // ExternalDnsResources returns Kubernetes objects required for external dns
@@ -109,11 +150,15 @@ func externalDnsResourcesFromConfig(conf *config.Config, externalDnsConfig *Exte
 	objs = append(objs, newExternalDNSClusterRole(conf, externalDnsConfig))
 	objs = append(objs, newExternalDNSClusterRoleBinding(conf, externalDnsConfig))
 
-	dnsCm, dnsCmHash := NewExternalDNSConfigMap(conf, externalDnsConfig)
-	objs = append(objs, dnsCm)
-	deployment := newExternalDNSDeployment(conf, externalDnsConfig, dnsCmHash)
-
-	objs = append(objs, deployment)
+	if externalDnsConfig.AuthMode == ServicePrincipalSecretAuth {
+		secret, secretHash := NewExternalDNSSecret(conf, externalDnsConfig)
+		objs = append(objs, secret)
+		objs = append(objs, newExternalDNSDeployment(conf, externalDnsConfig, secretHash))
+	} else {
+		dnsCm, dnsCmHash := NewExternalDNSConfigMap(conf, externalDnsConfig)
+		objs = append(objs, dnsCm)
+		objs = append(objs, newExternalDNSDeployment(conf, externalDnsConfig, dnsCmHash))
+	}
 
 	for _, obj := range objs {
 		l := util.MergeMaps(obj.GetLabels(), externalDnsConfig.Provider.Labels())
@@ -124,7 +169,7 @@ func externalDnsResourcesFromConfig(conf *config.Config, externalDnsConfig *Exte
 }
 
 func newExternalDNSServiceAccount(conf *config.Config, externalDnsConfig *ExternalDnsConfig) *corev1.ServiceAccount {
-	return &corev1.ServiceAccount{
+	sa := &corev1.ServiceAccount{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ServiceAccount",
 			APIVersion: "v1",
@@ -135,6 +180,13 @@ func newExternalDNSServiceAccount(conf *config.Config, externalDnsConfig *Extern
 			Labels:    GetTopLevelLabels(),
 		},
 	}
+
+	if externalDnsConfig.AuthMode == WorkloadIdentityAuth {
+		sa.Labels[WorkloadIdentityUseLabel] = "true"
+		sa.Annotations = map[string]string{workloadIdentityClientIdKey: conf.MSIClientID}
+	}
+
+	return sa
 }
 
 func newExternalDNSClusterRole(conf *config.Config, externalDnsConfig *ExternalDnsConfig) *rbacv1.ClusterRole {
@@ -190,21 +242,37 @@ func newExternalDNSClusterRoleBinding(conf *config.Config, externalDnsConfig *Ex
 	}
 }
 
-func NewExternalDNSConfigMap(conf *config.Config, externalDnsConfig *ExternalDnsConfig) (*corev1.ConfigMap, string) {
+// Returns the azure.json external-dns reads its credentials from and its hash
+func newAzureJson(conf *config.Config, externalDnsConfig *ExternalDnsConfig) ([]byte, string) {
+	azureJson := map[string]interface{}{
+		"tenantId":       externalDnsConfig.TenantId,
+		"subscriptionId": externalDnsConfig.Subscription,
+		"resourceGroup":  externalDnsConfig.ResourceGroup,
+		"cloud":          conf.Cloud,
+		"location":       conf.Location,
+	}
+	switch externalDnsConfig.AuthMode {
+	case WorkloadIdentityAuth:
+		azureJson["userAssignedIdentityID"] = conf.MSIClientID
+		azureJson["useWorkloadIdentityExtension"] = true
+	case ServicePrincipalSecretAuth:
+		azureJson["aadClientId"] = externalDnsConfig.AadClientId
+		azureJson["aadClientSecret"] = externalDnsConfig.AadClientSecret
+	default:
+		azureJson["userAssignedIdentityID"] = conf.MSIClientID
+		azureJson["useManagedIdentityExtension"] = true
+	}
 
-	js, err := json.Marshal(&map[string]interface{}{
-		"tenantId":                    externalDnsConfig.TenantId,
-		"subscriptionId":              externalDnsConfig.Subscription,
-		"resourceGroup":               externalDnsConfig.ResourceGroup,
-		"userAssignedIdentityID":      conf.MSIClientID,
-		"useManagedIdentityExtension": true,
-		"cloud":                       conf.Cloud,
-		"location":                    conf.Location,
-	})
+	js, err := json.Marshal(&azureJson)
 	if err != nil {
 		panic(err)
 	}
 	hash := sha256.Sum256(js)
+	return js, hex.EncodeToString(hash[:])
+}
+
+func NewExternalDNSConfigMap(conf *config.Config, externalDnsConfig *ExternalDnsConfig) (*corev1.ConfigMap, string) {
+	js, hash := newAzureJson(conf, externalDnsConfig)
 	return &corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ConfigMap",
@@ -218,10 +286,37 @@ func NewExternalDNSConfigMap(conf *config.Config, externalDnsConfig *ExternalDns
 		Data: map[string]string{
 			"azure.json": string(js),
 		},
-	}, hex.EncodeToString(hash[:])
+	}, hash
+}
+
+// NewExternalDNSSecret holds azure.json in a Secret since it contains the client secret with ServicePrincipalSecretAuth
+func NewExternalDNSSecret(conf *config.Config, externalDnsConfig *ExternalDnsConfig) (*corev1.Secret, string) {
+	js, hash := newAzureJson(conf, externalDnsConfig)
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      externalDnsConfig.Provider.ResourceName(),
+			Namespace: conf.NS,
+			Labels:    GetTopLevelLabels(),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"azure.json": js,
+		},
+	}, hash
 }
 
-func newExternalDNSDeployment(conf *config.Config, externalDnsConfig *ExternalDnsConfig, configMapHash string) *appsv1.Deployment {
+func providerArgs(externalDnsConfig *ExternalDnsConfig) []string {
+	if externalDnsConfig.WebhookUrl != "" {
+		return []string{"--provider=webhook", "--webhook-provider-url=" + externalDnsConfig.WebhookUrl}
+	}
+	return []string{"--provider=" + externalDnsConfig.Provider.String()}
+}
+
+func newExternalDNSDeployment(conf *config.Config, externalDnsConfig *ExternalDnsConfig, configHash string) *appsv1.Deployment {
 	domainFilters := []string{}
 
 	for _, zoneId := range externalDnsConfig.DnsZoneResourceIDs {
@@ -234,9 +329,25 @@ func newExternalDNSDeployment(conf *config.Config, externalDnsConfig *ExternalDn
 
 	podLabels := make(map[string]string)
 	podLabels["app"] = externalDnsConfig.Provider.ResourceName()
-	podLabels["checksum/configmap"] = configMapHash[:16]
+	// rolls the pods when azure.json changes, like when a secret is rotated
+	podLabels["checksum/configmap"] = configHash[:16]
 
-	return &appsv1.Deployment{
+	azureConfig := corev1.VolumeSource{
+		ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{
+				Name: externalDnsConfig.Provider.ResourceName(),
+			},
+		},
+	}
+	if externalDnsConfig.AuthMode == ServicePrincipalSecretAuth {
+		azureConfig = corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: externalDnsConfig.Provider.ResourceName(),
+			},
+		}
+	}
+
+	deployment := &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Deployment",
 			APIVersion: "apps/v1",
@@ -258,14 +369,13 @@ func newExternalDNSDeployment(conf *config.Config, externalDnsConfig *ExternalDn
 					ServiceAccountName: externalDnsConfig.Provider.ResourceName(),
 					Containers: []corev1.Container{*withLivenessProbeMatchingReadiness(withTypicalReadinessProbe(7979, &corev1.Container{
 						Name:  "controller",
-						Image: path.Join(conf.Registry, "/oss/kubernetes/external-dns:v0.14.0"),
-						Args: append([]string{
-							"--provider=" + externalDnsConfig.Provider.String(),
+						Image: path.Join(conf.Registry, "/oss/kubernetes/external-dns:"+conf.ExternalDnsVersion),
+						Args: append(append(providerArgs(externalDnsConfig),
 							"--source=ingress",
 							"--source=service",
-							"--interval=" + conf.DnsSyncInterval.String(),
-							"--txt-owner-id=" + conf.ClusterUid,
-						}, domainFilters...),
+							"--interval="+conf.DnsSyncInterval.String(),
+							"--txt-owner-id="+conf.ClusterUid,
+						), domainFilters...),
 						VolumeMounts: []corev1.VolumeMount{{
 							Name:      "azure-config",
 							MountPath: "/etc/kubernetes",
@@ -283,17 +393,53 @@ func newExternalDNSDeployment(conf *config.Config, externalDnsConfig *ExternalDn
 						},
 					}))},
 					Volumes: []corev1.Volume{{
-						Name: "azure-config",
-						VolumeSource: corev1.VolumeSource{
-							ConfigMap: &corev1.ConfigMapVolumeSource{
-								LocalObjectReference: corev1.LocalObjectReference{
-									Name: externalDnsConfig.Provider.ResourceName(),
-								},
-							},
-						},
+						Name:         "azure-config",
+						VolumeSource: azureConfig,
 					}},
 				}),
 			},
 		},
 	}
+
+	if externalDnsConfig.AuthMode == WorkloadIdentityAuth {
+		withWorkloadIdentity(conf, externalDnsConfig, &deployment.Spec.Template)
+	}
+
+	return deployment
+}
+
+// Labels the pods for workload identity and adds the token and environment the workload identity webhook would inject,
+// since the webhook isn't enabled on provisioned clusters
+func withWorkloadIdentity(conf *config.Config, externalDnsConfig *ExternalDnsConfig, template *corev1.PodTemplateSpec) {
+	template.Labels[WorkloadIdentityUseLabel] = "true"
+
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name: workloadIdentityTokenVolume,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{
+					ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+						Audience:          workloadIdentityAudience,
+						ExpirationSeconds: util.Int64Ptr(3600),
+						Path:              workloadIdentityTokenVolume,
+					},
+				}},
+			},
+		},
+	})
+
+	for i := range template.Spec.Containers {
+		c := &template.Spec.Containers[i]
+		c.Env = append(c.Env,
+			corev1.EnvVar{Name: "AZURE_CLIENT_ID", Value: conf.MSIClientID},
+			corev1.EnvVar{Name: "AZURE_TENANT_ID", Value: externalDnsConfig.TenantId},
+			corev1.EnvVar{Name: "AZURE_FEDERATED_TOKEN_FILE", Value: path.Join(workloadIdentityTokenDir, workloadIdentityTokenVolume)},
+			corev1.EnvVar{Name: "AZURE_AUTHORITY_HOST", Value: "https://login.microsoftonline.com/"},
+		)
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      workloadIdentityTokenVolume,
+			MountPath: workloadIdentityTokenDir,
+			ReadOnly:  true,
+		})
+	}
 }
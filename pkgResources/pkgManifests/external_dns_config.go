@@ -9,7 +9,8 @@ import (
 	"github.com/Azure/azure-provider-external-dns-e2e/pkgResources/config"
 )
 
-type configStruct struct {
+// ConfigStruct is a named ExternalDNS configuration shared by the public and private deployments
+type ConfigStruct struct {
 	Name       string
 	Conf       *config.Config
 	Deploy     *appsv1.Deployment
@@ -17,15 +18,15 @@ type configStruct struct {
 }
 
 // Sets public dns configuration above with values from provisioned infra
-func GetPublicDnsConfig(tenantId, subId, rg string, publicZone string) *ExternalDnsConfig {
+func GetPublicDnsConfig(tenantId, subId, rg string, publicZones ...string) *ExternalDnsConfig {
 
 	publicDnsConfig := &ExternalDnsConfig{}
 	var publicZonePaths []string
-	i := 0
 
-	path := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/dnszones/%s", subId, rg, publicZone)
-	publicZonePaths = append(publicZonePaths, path)
-	i++
+	for _, publicZone := range publicZones {
+		path := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/dnszones/%s", subId, rg, publicZone)
+		publicZonePaths = append(publicZonePaths, path)
+	}
 
 	publicDnsConfig.TenantId = tenantId
 	publicDnsConfig.Subscription = subId
@@ -38,16 +39,15 @@ func GetPublicDnsConfig(tenantId, subId, rg string, publicZone string) *External
 }
 
 // Sets private dns configuration above with values from provisioned infra
-func GetPrivateDnsConfig(tenantId, subId, rg string, privateZone string) *ExternalDnsConfig {
+func GetPrivateDnsConfig(tenantId, subId, rg string, privateZones ...string) *ExternalDnsConfig {
 
 	privateDnsConfig := &ExternalDnsConfig{}
 
 	var privateZonePaths []string
-	i := 0
-
-	path := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/privatednszones/%s", subId, rg, privateZone)
-	privateZonePaths = append(privateZonePaths, path)
-	i++
+	for _, privateZone := range privateZones {
+		path := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/privatednszones/%s", subId, rg, privateZone)
+		privateZonePaths = append(privateZonePaths, path)
+	}
 
 	privateDnsConfig.TenantId = tenantId
 	privateDnsConfig.Subscription = subId
@@ -59,12 +59,12 @@ func GetPrivateDnsConfig(tenantId, subId, rg string, privateZone string) *Extern
 }
 
 // Initializes Example configuration with public and private dns config. Called from Provision.go
-func SetExampleConfig(clientId, clusterUid string, publicDnsConfig, privateDnsConfig *ExternalDnsConfig) []configStruct {
-	//for now, we have one configuration, returning an array of configStructs allows us to rotate between configs if necessary
-	exampleConfigs := []configStruct{
+func SetExampleConfig(clientId, clusterUid string, publicDnsConfig, privateDnsConfig *ExternalDnsConfig) []ConfigStruct {
+	//for now, we have one configuration, returning an array of ConfigStructs allows us to rotate between configs if necessary
+	exampleConfigs := []ConfigStruct{
 		{
 			Name:       "full",
-			Conf:       &config.Config{NS: "kube-system", MSIClientID: clientId, ClusterUid: clusterUid, DnsSyncInterval: time.Minute * 3, Registry: "mcr.microsoft.com"},
+			Conf:       &config.Config{NS: "kube-system", MSIClientID: clientId, ClusterUid: clusterUid, DnsSyncInterval: time.Minute * 3, Registry: "mcr.microsoft.com", ExternalDnsVersion: config.DefaultExternalDnsVersion},
 			Deploy:     nil,
 			DnsConfigs: []*ExternalDnsConfig{publicDnsConfig, privateDnsConfig},
 		},
@@ -8,22 +8,45 @@ import (
 	"github.com/Azure/azure-provider-external-dns-e2e/tests"
 )
 
+// Options configure the suites
+type Options struct {
+	// ScaleServices is the number of services the scale suite deploys at once, the suite is skipped when it's 0
+	ScaleServices int
+}
+
 // All returns all test in all suites
-func All(infra infra.Provisioned) []tests.Ts {
+func All(infra infra.Provisioned, opts Options) []tests.Suite {
 
 	//Add new testing suites here:
-	var allSuites [][]test
-	allSuites = append(allSuites, basicSuite(infra))
-	allSuites = append(allSuites, privateDnsSuite(infra))
+	allSuites := []struct {
+		name      string
+		tests     []test
+		exclusive bool
+	}{
+		{name: "public dns", tests: basicSuite(infra)},
+		{name: "private dns", tests: privateDnsSuite(infra)},
+		{name: "cname", tests: cnameSuite(infra)},
+		{name: "multi target", tests: multiTargetSuite(infra)},
+		{name: "ttl", tests: ttlSuite(infra)},
+		{name: "txt registry", tests: registrySuite(infra)},
+		{name: "ingress", tests: ingressSuite(infra)},
+		{name: "resolution", tests: resolutionSuite(infra)},
+		{name: "lifecycle", tests: lifecycleSuite(infra)},
+		{name: "scale", tests: scaleSuite(infra, opts.ScaleServices)},
+		{name: "upgrade", tests: upgradeSuite(infra), exclusive: true},
+		{name: "node identity", tests: nodeIdentitySuite(infra)},
+		{name: "workload identity", tests: workloadIdentitySuite(infra)},
+		{name: "service principal", tests: servicePrincipalSuite(infra)},
+	}
 
-	final := make([]tests.Ts, len(allSuites))
+	final := make([]tests.Suite, 0, len(allSuites))
 
 	for _, suite := range allSuites {
-		ret := make(tests.Ts, len(suite))
-		for j, w := range suite {
+		ret := make(tests.Ts, len(suite.tests))
+		for j, w := range suite.tests {
 			ret[j] = w
 		}
-		final = append(final, ret)
+		final = append(final, tests.Suite{Name: suite.name, Tests: ret, Exclusive: suite.exclusive})
 	}
 
 	return final
@@ -32,12 +55,18 @@ func All(infra infra.Provisioned) []tests.Ts {
 type test struct {
 	name string
 	run  func(ctx context.Context) error
+	// exclusive tests change the external-dns other tests run against, so they run alone
+	exclusive bool
 }
 
 func (t test) GetName() string {
 	return t.name
 }
 
+func (t test) Exclusive() bool {
+	return t.exclusive
+}
+
 func (t test) Run(ctx context.Context) error {
 	if t.run == nil {
 		return fmt.Errorf("no run function provided for test %s", t.GetName())
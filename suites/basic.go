@@ -3,13 +3,10 @@ package suites
 import (
 	"context"
 	"fmt"
-	"log"
-	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
 
-	"github.com/Azure/azure-provider-external-dns-e2e/clients"
 	"github.com/Azure/azure-provider-external-dns-e2e/infra"
 	"github.com/Azure/azure-provider-external-dns-e2e/logger"
 	"github.com/Azure/azure-provider-external-dns-e2e/tests"
@@ -24,11 +21,9 @@ func basicSuite(in infra.Provisioned) []test {
 				lgr := logger.FromContext(ctx)
 
 				if err := ARecordTest(ctx, in); err != nil {
-					tests.ClearAnnotations(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, tests.Ipv4Service.Name)
 					return err
 				}
-				lgr.Info("\n ======== Public Dns ipv4 test finished successfully, clearing service annotations ======== \n")
-				tests.ClearAnnotations(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, tests.Ipv4Service.Name)
+				lgr.Info("\n ======== Public Dns ipv4 test finished successfully ======== \n")
 				return nil
 			},
 		},
@@ -37,14 +32,9 @@ func basicSuite(in infra.Provisioned) []test {
 			run: func(ctx context.Context) error {
 				lgr := logger.FromContext(ctx)
 				if err := AAAARecordTest(ctx, in); err != nil {
-					tests.ClearAnnotations(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, tests.Ipv4Service.Name)
-					tests.ClearAnnotations(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, tests.Ipv6Service.Name)
 					return err
 				}
-				lgr.Info("\n ======== Public Dns ipv6 test finished successfully, clearing service annotations ======== \n")
-				tests.ClearAnnotations(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, tests.Ipv4Service.Name)
-				tests.ClearAnnotations(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, tests.Ipv6Service.Name)
-
+				lgr.Info("\n ======== Public Dns ipv6 test finished successfully ======== \n")
 				return nil
 			},
 		},
@@ -55,27 +45,34 @@ var ARecordTest = func(ctx context.Context, infra infra.Provisioned) error {
 	lgr := logger.FromContext(ctx)
 	lgr.Info("starting public dns + A record test")
 
-	ipv4ServiceName := infra.Ipv4ServiceName
+	zone := testZone(infra, false)
+	name := tests.UniqueName("a-record")
+	hostname := name + "." + zone
 
 	annotationMap := map[string]string{
-		"external-dns.alpha.kubernetes.io/hostname": tests.PublicZone,
+		"external-dns.alpha.kubernetes.io/hostname": hostname,
 	}
-	err := tests.AnnotateService(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, ipv4ServiceName, annotationMap)
+	svc, annotated, err := tests.NewTimedTestService(ctx, infra.Cluster, name, tests.Ipv4, annotationMap, 300)
 	if err != nil {
-		lgr.Error("Error annotating service with zone name", err)
+		lgr.Error("Error creating service annotated with hostname", err)
 		return fmt.Errorf("error: %s", err)
 	}
+	defer tests.DeleteTestService(ctx, infra.Cluster, name)
 
 	//checking to see if A record was created in Azure DNS
-	err = validateRecord(ctx, armdns.RecordTypeA, tests.ResourceGroup, tests.SubId, *tests.ClusterName, tests.PublicZone, 150, tests.Ipv4Service.Status.LoadBalancer.Ingress[0].IP)
+	err = validateRecord(ctx, infra.Cluster, armdns.RecordTypeA, infra.ResourceGroup.GetName(), infra.SubscriptionId, zone, hostname, 150, svc.Status.LoadBalancer.Ingress[0].IP)
 	if err != nil {
-		return fmt.Errorf("%s Record not created in Azure DNS", armdns.RecordTypeA)
-	} else {
-		lgr.Info("Test Passed: Public dns + A record")
+		return fmt.Errorf("%s Record not created in Azure DNS: %w", armdns.RecordTypeA, err)
+	}
+	observeLatency(ctx, annotated, string(armdns.RecordTypeA), hostname, tests.ArmStage)
+
+	if err := observeResolvable(ctx, infra, annotated, armdns.RecordTypeA, hostname); err != nil {
+		return fmt.Errorf("%s Record not resolvable: %w", armdns.RecordTypeA, err)
 	}
+	lgr.Info("Test Passed: Public dns + A record")
 
 	//test passed, deleting created record set
-	err = tests.DeleteRecordSet(ctx, *tests.ClusterName, tests.SubId, tests.ResourceGroup, tests.PublicZone, armdns.RecordTypeA, "")
+	err = tests.DeleteRecordSet(ctx, infra.Cluster.GetName(), infra.SubscriptionId, infra.ResourceGroup.GetName(), zone, name, armdns.RecordTypeA, "")
 	if err != nil {
 		lgr.Error("Error deleting A record set")
 		return fmt.Errorf("error deleting A record set")
@@ -87,41 +84,48 @@ var AAAARecordTest = func(ctx context.Context, infra infra.Provisioned) error {
 	lgr := logger.FromContext(ctx)
 	lgr.Info("starting public dns + AAAA test")
 
-	ipv6ServiceName := infra.Ipv6ServiceName
-	ipv4ServiceName := infra.Ipv4ServiceName
+	zone := testZone(infra, false)
+	name := tests.UniqueName("aaaa-record")
+	hostname := name + "." + zone
 
 	annotationMap := map[string]string{
-		"external-dns.alpha.kubernetes.io/hostname": tests.PublicZone,
+		"external-dns.alpha.kubernetes.io/hostname": hostname,
 	}
 
-	err := tests.AnnotateService(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, ipv6ServiceName, annotationMap)
+	ipv6Svc, annotated, err := tests.NewTimedTestService(ctx, infra.Cluster, name+"-ipv6", tests.Ipv6, annotationMap, 300)
 	if err != nil {
-		lgr.Error("Error annotating service", err)
+		lgr.Error("Error creating ipv6 service", err)
 		return fmt.Errorf("error: %s", err)
 	}
+	defer tests.DeleteTestService(ctx, infra.Cluster, ipv6Svc.Name)
 
-	err = tests.AnnotateService(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, ipv4ServiceName, annotationMap)
+	ipv4Svc, err := tests.NewTestService(ctx, infra.Cluster, name+"-ipv4", tests.Ipv4, annotationMap, 300)
 	if err != nil {
-		lgr.Error("Error annotating service", err)
+		lgr.Error("Error creating ipv4 service", err)
 		return fmt.Errorf("error: %s", err)
 	}
+	defer tests.DeleteTestService(ctx, infra.Cluster, ipv4Svc.Name)
 
 	// Checking Azure DNS for AAAA record
-	err = validateRecord(ctx, armdns.RecordTypeAAAA, tests.ResourceGroup, tests.SubId, *tests.ClusterName, tests.PublicZone, 100, tests.Ipv6Service.Status.LoadBalancer.Ingress[0].IP)
+	err = validateRecord(ctx, infra.Cluster, armdns.RecordTypeAAAA, infra.ResourceGroup.GetName(), infra.SubscriptionId, zone, hostname, 100, ipv6Svc.Status.LoadBalancer.Ingress[0].IP)
 
 	if err != nil {
-		return fmt.Errorf("AAAA Record not created in Azure DNS")
-	} else {
-		lgr.Info("Test Passed: public dns + AAAA record test")
+		return fmt.Errorf("AAAA Record not created in Azure DNS: %w", err)
 	}
+	observeLatency(ctx, annotated, string(armdns.RecordTypeAAAA), hostname, tests.ArmStage)
+
+	if err := observeResolvable(ctx, infra, annotated, armdns.RecordTypeAAAA, hostname); err != nil {
+		return fmt.Errorf("AAAA Record not resolvable: %w", err)
+	}
+	lgr.Info("Test Passed: public dns + AAAA record test")
 
 	// Test passed, deleting created record sets
-	err = tests.DeleteRecordSet(ctx, *tests.ClusterName, tests.SubId, tests.ResourceGroup, tests.PublicZone, armdns.RecordTypeA, "")
+	err = tests.DeleteRecordSet(ctx, infra.Cluster.GetName(), infra.SubscriptionId, infra.ResourceGroup.GetName(), zone, name, armdns.RecordTypeA, "")
 	if err != nil {
 		lgr.Error("Error deleting A record set")
 		return fmt.Errorf("error deleting A record set")
 	}
-	err = tests.DeleteRecordSet(ctx, *tests.ClusterName, tests.SubId, tests.ResourceGroup, tests.PublicZone, armdns.RecordTypeAAAA, "")
+	err = tests.DeleteRecordSet(ctx, infra.Cluster.GetName(), infra.SubscriptionId, infra.ResourceGroup.GetName(), zone, name, armdns.RecordTypeAAAA, "")
 	if err != nil {
 		lgr.Error("Error deleting AAAA record set")
 		return fmt.Errorf("error deleting AAAA record set")
@@ -131,72 +135,27 @@ var AAAARecordTest = func(ctx context.Context, infra infra.Provisioned) error {
 
 }
 
-// Checks to see whether record is created in Azure DNS
-func validateRecord(ctx context.Context, recordType armdns.RecordType, rg, subscriptionId, clusterName, serviceDnsZoneName string, numSeconds time.Duration, svcIp string) error {
+// Checks to see whether a record for hostname pointing at svcIp is created in Azure DNS
+func validateRecord(ctx context.Context, c tests.Cluster, recordType armdns.RecordType, rg, subscriptionId, serviceDnsZoneName, hostname string, numSeconds time.Duration, svcIp string) error {
 	lgr := logger.FromContext(ctx)
 	lgr.Info("Checking that Record was created in Azure DNS")
 
-	err := tests.WaitForExternalDns(ctx, 10, subscriptionId, rg, clusterName, "external-dns")
-	if err != nil {
-		return fmt.Errorf("error waiting for ExternalDNS to start running %w", err)
-	}
-
-	cred, err := clients.GetAzCred()
-	if err != nil {
-		return fmt.Errorf("getting az credentials: %w", err)
+	if recordType != armdns.RecordTypeA && recordType != armdns.RecordTypeAAAA {
+		return fmt.Errorf("unable to match record type")
 	}
 
-	clientFactory, err := armdns.NewClientFactory(subscriptionId, cred, nil)
+	err := tests.WaitForExternalDns(ctx, c, 10, "external-dns")
 	if err != nil {
-		log.Fatal("failed to create client: ", err)
-		return fmt.Errorf("failed to create armdns.ClientFactory")
-	}
-
-	timeout := time.Now().Add(numSeconds * time.Second)
-
-	var pageValue []*armdns.RecordSet
-	for {
-		if time.Now().After(timeout) {
-			return fmt.Errorf("record not created within %s seconds", numSeconds)
-		}
-
-		pager := clientFactory.NewRecordSetsClient().NewListByTypePager(rg, serviceDnsZoneName, recordType, &armdns.RecordSetsClientListByTypeOptions{Top: nil,
-			Recordsetnamesuffix: nil,
-		})
-
-		if pager.More() {
-			page, err := pager.NextPage(ctx)
-			if err != nil {
-				log.Fatal("failed to advance page: ", err)
-				return fmt.Errorf("failed to advance page for record sets")
-			}
-			if len(page.Value) > 0 {
-				pageValue = page.Value
-				break
-			}
-
-		}
-		time.Sleep(2 * time.Second) //waiting 2 seconds before checking again
+		return fmt.Errorf("error waiting for ExternalDNS to start running %w", err)
 	}
 
-	var ipAddr string
-	for _, v := range pageValue {
-		currZoneName := strings.Trim(*(v.Properties.Fqdn), ".") //removing trailing '.'
-
-		if recordType == armdns.RecordTypeA {
-			ipAddr = *(v.Properties.ARecords[0].IPv4Address)
-		} else if recordType == armdns.RecordTypeAAAA {
-			ipAddr = *(v.Properties.AaaaRecords[0].IPv6Address)
-		} else {
-			return fmt.Errorf("unable to match record type")
-		}
-
-		if currZoneName == serviceDnsZoneName && ipAddr == svcIp {
-			return nil
+	return pollRecord(ctx, numSeconds, fmt.Sprintf("%s record %s created", recordType, hostname), func(ctx context.Context) (bool, error) {
+		rs, err := findRecord(ctx, subscriptionId, rg, serviceDnsZoneName, false, string(recordType), hostname)
+		if err != nil || rs == nil {
+			return false, err
 		}
 
-	}
-	//test failed
-	return fmt.Errorf("record not created %s", recordType)
-
+		missing, extra := rs.Diff([]string{svcIp})
+		return len(missing) == 0 && len(extra) == 0, nil
+	})
 }
@@ -0,0 +1,164 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+const (
+	hostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	targetAnnotation   = "external-dns.alpha.kubernetes.io/target"
+	ttlAnnotation      = "external-dns.alpha.kubernetes.io/ttl"
+)
+
+// Tests pointing hostnames in the public and private zones at a target through the target annotation, creating CNAME records
+func cnameSuite(in infra.Provisioned) []test {
+	return []test{
+		{
+			name: "service + CNAME Record",
+			run: func(ctx context.Context) error {
+				lgr := logger.FromContext(ctx)
+				if err := CnameServiceTest(ctx, in); err != nil {
+					return err
+				}
+				lgr.Info("\n ======== Service CNAME test finished successfully ======== \n")
+				return nil
+			},
+		},
+		{
+			name: "ingress + CNAME Record",
+			run: func(ctx context.Context) error {
+				lgr := logger.FromContext(ctx)
+				if err := CnameIngressTest(ctx, in); err != nil {
+					return err
+				}
+				lgr.Info("\n ======== Ingress CNAME test finished successfully ======== \n")
+				return nil
+			},
+		},
+	}
+}
+
+var CnameServiceTest = func(ctx context.Context, infra infra.Provisioned) error {
+	lgr := logger.FromContext(ctx)
+	lgr.Info("starting service + CNAME record test")
+
+	name := tests.UniqueName("cname-svc")
+	target := name + "-target.example.com"
+
+	// a service using the target annotation doesn't need an ip, so it's a ClusterIP service without a load balancer
+	svc := newTargetService(name, cnameHostnames(infra, name), []string{target})
+	if err := infra.Cluster.Deploy(ctx, []client.Object{svc}); err != nil {
+		return fmt.Errorf("deploying service %s: %w", name, err)
+	}
+	defer tests.DeleteTestService(ctx, infra.Cluster, name)
+
+	if err := validateCnames(ctx, infra, name, target, 150); err != nil {
+		return fmt.Errorf("CNAME records not created: %w", err)
+	}
+
+	if err := tests.ClearAnnotations(ctx, infra.Cluster, name); err != nil {
+		return fmt.Errorf("clearing annotations of service %s: %w", name, err)
+	}
+
+	if err := validateCnames(ctx, infra, name, "", syncSeconds(infra)); err != nil {
+		return fmt.Errorf("CNAME records not removed: %w", err)
+	}
+
+	lgr.Info("Test Passed: service + CNAME record")
+	return nil
+}
+
+var CnameIngressTest = func(ctx context.Context, infra infra.Provisioned) error {
+	lgr := logger.FromContext(ctx)
+	lgr.Info("starting ingress + CNAME record test")
+
+	name := tests.UniqueName("cname-ing")
+	target := name + "-target.example.com"
+	hosts := strings.Split(cnameHostnames(infra, name), ",")
+
+	// the target annotation replaces the address an ingress controller would publish, so no controller is needed
+	ing := clients.NewNginxIngress(name, infra.Ipv4ServiceName, hosts, map[string]string{targetAnnotation: target})
+	if err := infra.Cluster.Deploy(ctx, []client.Object{ing}); err != nil {
+		return fmt.Errorf("deploying ingress %s: %w", name, err)
+	}
+	defer tests.DeleteTestIngress(ctx, infra.Cluster, name)
+
+	if err := validateCnames(ctx, infra, name, target, 150); err != nil {
+		return fmt.Errorf("CNAME records not created: %w", err)
+	}
+
+	// reapplying without annotations clears the target, leaving the ingress without any address to publish
+	if err := infra.Cluster.Deploy(ctx, []client.Object{clients.NewNginxIngress(name, infra.Ipv4ServiceName, hosts, nil)}); err != nil {
+		return fmt.Errorf("clearing annotations of ingress %s: %w", name, err)
+	}
+
+	if err := validateCnames(ctx, infra, name, "", syncSeconds(infra)); err != nil {
+		return fmt.Errorf("CNAME records not removed: %w", err)
+	}
+
+	lgr.Info("Test Passed: ingress + CNAME record")
+	return nil
+}
+
+// Returns the hostname annotation value publishing name in both the public and private zone
+func cnameHostnames(in infra.Provisioned, name string) string {
+	return name + "." + testZone(in, false) + "," + name + "." + testZone(in, true)
+}
+
+// Checks that name has a CNAME record pointing at target in both the public and private zone, an empty target checks
+// that neither zone has one
+func validateCnames(ctx context.Context, in infra.Provisioned, name, target string, numSeconds time.Duration) error {
+	lgr := logger.FromContext(ctx).With("record", name, "target", target)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to validate CNAME records")
+	defer lgr.Info("finished validating CNAME records")
+
+	if err := tests.WaitForExternalDns(ctx, in.Cluster, 10, "external-dns"); err != nil {
+		return fmt.Errorf("error waiting for ExternalDNS to start running %w", err)
+	}
+	if err := tests.WaitForExternalDns(ctx, in.Cluster, 10, "external-dns-private"); err != nil {
+		return fmt.Errorf("error waiting for private ExternalDNS to start running %w", err)
+	}
+
+	description := fmt.Sprintf("CNAME records %s pointing at %s", name, target)
+	if target == "" {
+		description = fmt.Sprintf("CNAME records %s removed", name)
+	}
+
+	publicZone := testZone(in, false)
+	privateZone := testZone(in, true)
+	return pollRecord(ctx, numSeconds, description, func(ctx context.Context) (bool, error) {
+		public, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), publicZone, false, string(armdns.RecordTypeCNAME), name+"."+publicZone)
+		if err != nil {
+			return false, fmt.Errorf("finding public CNAME record: %w", err)
+		}
+
+		private, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), privateZone, true, string(armprivatedns.RecordTypeCNAME), name+"."+privateZone)
+		if err != nil {
+			return false, fmt.Errorf("finding private CNAME record: %w", err)
+		}
+
+		var publicCname, privateCname string
+		if public != nil {
+			publicCname = strings.Join(public.Values, ",")
+		}
+		if private != nil {
+			privateCname = strings.Join(private.Values, ",")
+		}
+
+		lgr.Info("found CNAME records", "public", publicCname, "private", privateCname)
+		return publicCname == target && privateCname == target, nil
+	})
+}
@@ -0,0 +1,99 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+// Tests the ingress source, publishing every host of an ingress served by a real ingress controller
+func ingressSuite(in infra.Provisioned) []test {
+	return []test{
+		{
+			name: "ingress + A Records for every host",
+			run: func(ctx context.Context) error {
+				lgr := logger.FromContext(ctx)
+				if err := IngressMultiHostTest(ctx, in); err != nil {
+					return err
+				}
+				lgr.Info("\n ======== Ingress multiple hosts test finished successfully ======== \n")
+				return nil
+			},
+		},
+	}
+}
+
+var IngressMultiHostTest = func(ctx context.Context, infra infra.Provisioned) error {
+	lgr := logger.FromContext(ctx)
+	lgr.Info("starting ingress + multiple hosts test")
+
+	// applying the controller is idempotent, so it's left running for later runs against the same cluster
+	if err := infra.Cluster.Deploy(ctx, clients.NewIngressNginxController()); err != nil {
+		return fmt.Errorf("deploying ingress controller: %w", err)
+	}
+
+	controllerSvc, err := tests.WaitForServiceIp(ctx, infra.Cluster, clients.IngressNginxServiceName, 300)
+	if err != nil {
+		return fmt.Errorf("waiting for ingress controller ip: %w", err)
+	}
+	ip := controllerSvc.Status.LoadBalancer.Ingress[0].IP
+
+	publicZone := testZone(infra, false)
+	privateZone := testZone(infra, true)
+	name := tests.UniqueName("ingress")
+	publicNames := []string{name + "-a", name + "-b"}
+	privateNames := []string{name + "-c", name + "-d"}
+
+	var hosts []string
+	for _, n := range publicNames {
+		hosts = append(hosts, n+"."+publicZone)
+	}
+	for _, n := range privateNames {
+		hosts = append(hosts, n+"."+privateZone)
+	}
+
+	ing := clients.NewNginxIngress(name, infra.Ipv4ServiceName, hosts, nil)
+	ing.Spec.IngressClassName = to.Ptr(clients.IngressNginxClassName)
+	if err := infra.Cluster.Deploy(ctx, []client.Object{ing}); err != nil {
+		return fmt.Errorf("deploying ingress %s: %w", name, err)
+	}
+	defer tests.DeleteTestIngress(ctx, infra.Cluster, name)
+
+	for _, n := range publicNames {
+		hostname := n + "." + publicZone
+		if err := validateRecord(ctx, infra.Cluster, armdns.RecordTypeA, infra.ResourceGroup.GetName(), infra.SubscriptionId, publicZone, hostname, 150, ip); err != nil {
+			return fmt.Errorf("A Record for ingress host %s not created in Azure DNS: %w", hostname, err)
+		}
+	}
+
+	for _, n := range privateNames {
+		hostname := n + "." + privateZone
+		if err := validatePrivateRecords(ctx, infra.Cluster, armprivatedns.RecordTypeA, infra.ResourceGroup.GetName(), infra.SubscriptionId, privateZone, hostname, 150, ip); err != nil {
+			return fmt.Errorf("A Record for ingress host %s not created in private zone: %w", hostname, err)
+		}
+	}
+	lgr.Info("Test Passed: ingress + multiple hosts")
+
+	//test passed, deleting created record sets
+	for _, n := range publicNames {
+		if err := tests.DeleteRecordSet(ctx, infra.Cluster.GetName(), infra.SubscriptionId, infra.ResourceGroup.GetName(), publicZone, n, armdns.RecordTypeA, ""); err != nil {
+			return fmt.Errorf("deleting A record set %s: %w", n, err)
+		}
+	}
+	for _, n := range privateNames {
+		if err := tests.DeleteRecordSet(ctx, infra.Cluster.GetName(), infra.SubscriptionId, infra.ResourceGroup.GetName(), privateZone, n, "", armprivatedns.RecordTypeA); err != nil {
+			return fmt.Errorf("deleting private A record set %s: %w", n, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+// Tests that external-dns updates and cleans up the records it owns, rather than the test deleting them itself
+func lifecycleSuite(in infra.Provisioned) []test {
+	return []test{
+		{
+			name: "public DNS + hostname change",
+			run: func(ctx context.Context) error {
+				return hostnameChangeTest(ctx, in, false)
+			},
+		},
+		{
+			name: "private DNS + hostname change",
+			run: func(ctx context.Context) error {
+				return hostnameChangeTest(ctx, in, true)
+			},
+		},
+		{
+			name: "public DNS + service delete",
+			run: func(ctx context.Context) error {
+				return serviceDeleteTest(ctx, in, false)
+			},
+		},
+		{
+			name: "private DNS + service delete",
+			run: func(ctx context.Context) error {
+				return serviceDeleteTest(ctx, in, true)
+			},
+		},
+	}
+}
+
+// Changes the hostname of a service and checks the old A record is removed and the new one created within a sync interval
+func hostnameChangeTest(ctx context.Context, in infra.Provisioned, private bool) error {
+	zone := testZone(in, private)
+	name := tests.UniqueName("lifecycle-change")
+	oldHostname := name + "-old." + zone
+	newHostname := name + "-new." + zone
+
+	lgr := logger.FromContext(ctx).With("private", private, "oldHostname", oldHostname, "newHostname", newHostname)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting hostname change test")
+
+	ip, err := newLifecycleService(ctx, in, name, private, oldHostname)
+	if err != nil {
+		return err
+	}
+	defer tests.DeleteTestService(ctx, in.Cluster, name)
+
+	if err := tests.AnnotateService(ctx, in.Cluster, name, map[string]string{hostnameAnnotation: newHostname}); err != nil {
+		return err
+	}
+	changed := time.Now()
+
+	err = pollRecord(ctx, syncSeconds(in), fmt.Sprintf("A record moved from %s to %s", oldHostname, newHostname), func(ctx context.Context) (bool, error) {
+		oldIp, err := findARecordIp(ctx, in, private, zone, oldHostname)
+		if err != nil {
+			return false, err
+		}
+		newIp, err := findARecordIp(ctx, in, private, zone, newHostname)
+		if err != nil {
+			return false, err
+		}
+
+		return oldIp == "" && newIp == ip, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	lgr.Info("Test Passed: hostname change", "syncInterval", in.DnsSyncInterval(), "took", time.Since(changed))
+	return nil
+}
+
+// Deletes a service and checks its A record is removed within a sync interval
+func serviceDeleteTest(ctx context.Context, in infra.Provisioned, private bool) error {
+	zone := testZone(in, private)
+	name := tests.UniqueName("lifecycle-delete")
+	hostname := name + "." + zone
+
+	lgr := logger.FromContext(ctx).With("private", private, "hostname", hostname)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting service delete test")
+
+	if _, err := newLifecycleService(ctx, in, name, private, hostname); err != nil {
+		return err
+	}
+	// deleting a missing service isn't an error, so this only matters when the test fails before deleting it
+	defer tests.DeleteTestService(ctx, in.Cluster, name)
+
+	if err := tests.DeleteTestService(ctx, in.Cluster, name); err != nil {
+		return err
+	}
+	deleted := time.Now()
+
+	err := pollRecord(ctx, syncSeconds(in), fmt.Sprintf("A record %s removed", hostname), func(ctx context.Context) (bool, error) {
+		ip, err := findARecordIp(ctx, in, private, zone, hostname)
+		return ip == "", err
+	})
+	if err != nil {
+		return err
+	}
+
+	lgr.Info("Test Passed: service delete", "syncInterval", in.DnsSyncInterval(), "took", time.Since(deleted))
+	return nil
+}
+
+// Creates a service publishing hostname and waits for its A record, returning the ip of the service
+func newLifecycleService(ctx context.Context, in infra.Provisioned, name string, private bool, hostname string) (string, error) {
+	annotations := map[string]string{hostnameAnnotation: hostname}
+	if private {
+		annotations = tests.PrivateDnsAnnotations(hostname)
+	}
+
+	svc, err := tests.NewTestService(ctx, in.Cluster, name, tests.Ipv4, annotations, 300)
+	if err != nil {
+		return "", fmt.Errorf("creating service %s: %w", name, err)
+	}
+	ip := svc.Status.LoadBalancer.Ingress[0].IP
+
+	zone := testZone(in, private)
+	if private {
+		err = validatePrivateRecords(ctx, in.Cluster, armprivatedns.RecordTypeA, in.ResourceGroup.GetName(), in.SubscriptionId, zone, hostname, 150, ip)
+	} else {
+		err = validateRecord(ctx, in.Cluster, armdns.RecordTypeA, in.ResourceGroup.GetName(), in.SubscriptionId, zone, hostname, 150, ip)
+	}
+	if err != nil {
+		tests.DeleteTestService(ctx, in.Cluster, name)
+		return "", fmt.Errorf("A record %s not created: %w", hostname, err)
+	}
+
+	return ip, nil
+}
+
+// Returns the first ip of the A record with fqdn hostname, or an empty string if there's none
+func findARecordIp(ctx context.Context, in infra.Provisioned, private bool, zone, hostname string) (string, error) {
+	rs, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), zone, private, string(armdns.RecordTypeA), hostname)
+	if err != nil || rs == nil || len(rs.Values) == 0 {
+		return "", err
+	}
+	return rs.Values[0], nil
+}
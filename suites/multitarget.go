@@ -0,0 +1,132 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+// Tests records published with several targets hold exactly those targets, including after the targets change
+func multiTargetSuite(in infra.Provisioned) []test {
+	return []test{
+		{
+			name: "public DNS + A Record multiple targets",
+			run: func(ctx context.Context) error {
+				return multiTargetTest(ctx, in, false)
+			},
+		},
+		{
+			name: "private DNS + A Record multiple targets",
+			run: func(ctx context.Context) error {
+				return multiTargetTest(ctx, in, true)
+			},
+		},
+		{
+			name: "public DNS + A and AAAA Record mixed targets",
+			run: func(ctx context.Context) error {
+				return mixedTargetTest(ctx, in)
+			},
+		},
+	}
+}
+
+// Publishes an A record with two targets, then swaps one target and drops another, checking the record holds exactly
+// the current targets each time so no stale target is left behind
+func multiTargetTest(ctx context.Context, in infra.Provisioned, private bool) error {
+	zone := testZone(in, private)
+	name := tests.UniqueName("multi-target")
+	hostname := name + "." + zone
+
+	lgr := logger.FromContext(ctx).With("private", private, "hostname", hostname)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting multiple targets test")
+
+	// addresses of the 192.0.2.0/24 documentation range, nothing needs to answer on them
+	steps := [][]string{
+		{"192.0.2.1", "192.0.2.2"},
+		{"192.0.2.2", "192.0.2.3"},
+		{"192.0.2.3"},
+	}
+
+	if err := in.Cluster.Deploy(ctx, []client.Object{newTargetService(name, hostname, steps[0])}); err != nil {
+		return fmt.Errorf("deploying service %s: %w", name, err)
+	}
+	defer tests.DeleteTestService(ctx, in.Cluster, name)
+
+	for i, targets := range steps {
+		if i > 0 {
+			if err := tests.AnnotateService(ctx, in.Cluster, name, map[string]string{targetAnnotation: strings.Join(targets, ",")}); err != nil {
+				return fmt.Errorf("changing targets of %s to %v: %w", name, targets, err)
+			}
+		}
+
+		if err := validateTargets(ctx, in, private, string(armdns.RecordTypeA), hostname, targets, syncSeconds(in)); err != nil {
+			return err
+		}
+	}
+
+	lgr.Info("Test Passed: multiple targets")
+	return nil
+}
+
+// Publishes ipv4 and ipv6 targets for one hostname, checking they're split into an A and an AAAA record, then drops the
+// ipv6 targets and checks the AAAA record is removed while the A record keeps only its remaining target
+func mixedTargetTest(ctx context.Context, in infra.Provisioned) error {
+	zone := testZone(in, false)
+	name := tests.UniqueName("mixed-target")
+	hostname := name + "." + zone
+
+	lgr := logger.FromContext(ctx).With("hostname", hostname)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting mixed targets test")
+
+	ipv4 := []string{"192.0.2.10", "192.0.2.11"}
+	ipv6 := []string{"2001:db8::10", "2001:db8::11"}
+
+	if err := in.Cluster.Deploy(ctx, []client.Object{newTargetService(name, hostname, append(append([]string{}, ipv4...), ipv6...))}); err != nil {
+		return fmt.Errorf("deploying service %s: %w", name, err)
+	}
+	defer tests.DeleteTestService(ctx, in.Cluster, name)
+
+	if err := validateTargets(ctx, in, false, string(armdns.RecordTypeA), hostname, ipv4, syncSeconds(in)); err != nil {
+		return err
+	}
+	if err := validateTargets(ctx, in, false, string(armdns.RecordTypeAAAA), hostname, ipv6, syncSeconds(in)); err != nil {
+		return err
+	}
+
+	if err := tests.AnnotateService(ctx, in.Cluster, name, map[string]string{targetAnnotation: ipv4[1]}); err != nil {
+		return fmt.Errorf("changing targets of %s to %s: %w", name, ipv4[1], err)
+	}
+
+	if err := validateTargets(ctx, in, false, string(armdns.RecordTypeA), hostname, ipv4[1:], syncSeconds(in)); err != nil {
+		return err
+	}
+	if err := validateTargets(ctx, in, false, string(armdns.RecordTypeAAAA), hostname, nil, syncSeconds(in)); err != nil {
+		return err
+	}
+
+	lgr.Info("Test Passed: mixed targets")
+	return nil
+}
+
+// Returns a ClusterIP service publishing hostname with targets through the target annotation, which doesn't need a
+// load balancer ip
+func newTargetService(name, hostname string, targets []string) *corev1.Service {
+	svc := clients.NewNginxService(name, "", map[string]string{
+		hostnameAnnotation: hostname,
+		targetAnnotation:   strings.Join(targets, ","),
+	})
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Spec.ExternalTrafficPolicy = "" // only allowed on services exposed outside the cluster
+	return svc
+}
@@ -0,0 +1,79 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	manifests "github.com/Azure/azure-provider-external-dns-e2e/pkgResources/pkgManifests"
+)
+
+// Tests the user-assigned identity on the nodes is the one external-dns can write records with, and that it does. Only
+// provisioned clusters get a node identity, existing ones keep using the kubelet identity
+func nodeIdentitySuite(in infra.Provisioned) []test {
+	if in.ExternalDnsAuth != manifests.ManagedIdentityAuth || in.ExternalDnsIdentity == nil {
+		return nil
+	}
+
+	return []test{
+		{
+			name: "node identity + least privilege roles",
+			run: func(ctx context.Context) error {
+				return validateNodeIdentityRoles(ctx, in)
+			},
+		},
+		{
+			name: "node identity + public and private DNS A Records",
+			run: func(ctx context.Context) error {
+				return authRecordsTest(ctx, in)
+			},
+		},
+	}
+}
+
+// Checks the node identity, and not the kubelet identity, has the dns roles on the zones and is still on every scale set
+func validateNodeIdentityRoles(ctx context.Context, in infra.Provisioned) error {
+	lgr := logger.FromContext(ctx)
+	lgr.Info("starting to validate node identity roles")
+	defer lgr.Info("finished validating node identity roles")
+
+	scopes := make(map[string]clients.Role)
+	for _, z := range in.Zones {
+		scopes[z.GetId()] = clients.DnsContributorRole
+	}
+	for _, pz := range in.PrivateZones {
+		scopes[pz.GetId()] = clients.PrivateDnsContributorRole
+	}
+
+	for scope, role := range scopes {
+		ok, err := clients.HasRoleAssignment(ctx, in.SubscriptionId, scope, in.ExternalDnsIdentity.GetPrincipalId(), role)
+		if err != nil {
+			return fmt.Errorf("checking node identity role on %s: %w", scope, err)
+		}
+		if !ok {
+			return fmt.Errorf("node identity is missing %s on %s", role.Name, scope)
+		}
+
+		ok, err = clients.HasRoleAssignment(ctx, in.SubscriptionId, scope, in.Cluster.GetPrincipalId(), role)
+		if err != nil {
+			return fmt.Errorf("checking kubelet identity role on %s: %w", scope, err)
+		}
+		if ok {
+			return fmt.Errorf("kubelet identity has %s on %s", role.Name, scope)
+		}
+	}
+
+	// the identity is assigned to the scale sets directly, which AKS undoes when it recreates one
+	missing, err := in.NodeIdentityMissing(ctx)
+	if err != nil {
+		return fmt.Errorf("checking node identity is assigned: %w", err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("node identity isn't assigned to scale sets %v, node pools were upgraded, reimaged or added after provisioning", missing)
+	}
+
+	lgr.Info("Test Passed: only the node identity has dns roles")
+	return nil
+}
@@ -3,32 +3,28 @@ package suites
 import (
 	"context"
 	"fmt"
-	"log"
-	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
 
-	"github.com/Azure/azure-provider-external-dns-e2e/clients"
 	"github.com/Azure/azure-provider-external-dns-e2e/infra"
 	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/manifests"
 	"github.com/Azure/azure-provider-external-dns-e2e/tests"
 )
 
 // Tests using the provisioned private dns zone for creating A and AAAA records
 func privateDnsSuite(in infra.Provisioned) []test {
-	return []test{
+	ret := []test{
 		{
 			name: "private DNS +  A Record",
 			run: func(ctx context.Context) error {
 				lgr := logger.FromContext(ctx)
 				if err := PrivateARecordTest(ctx, in); err != nil {
-					tests.ClearAnnotations(ctx, in.SubscriptionId, *tests.ClusterName, in.ResourceGroup.GetName(), tests.Ipv4Service.Name)
 					return err
 				}
-				lgr.Info("\n ======== Private Dns ipv4 test finished successfully, clearing service annotations ======== \n")
-				tests.ClearAnnotations(ctx, in.SubscriptionId, *tests.ClusterName, in.ResourceGroup.GetName(), tests.Ipv4Service.Name)
+				lgr.Info("\n ======== Private Dns ipv4 test finished successfully ======== \n")
 				return nil
 			},
 		},
@@ -37,43 +33,83 @@ func privateDnsSuite(in infra.Provisioned) []test {
 			run: func(ctx context.Context) error {
 				lgr := logger.FromContext(ctx)
 				if err := PrivateAAAATest(ctx, in); err != nil {
-					tests.ClearAnnotations(ctx, in.SubscriptionId, *tests.ClusterName, in.ResourceGroup.GetName(), tests.Ipv4Service.Name)
-					tests.ClearAnnotations(ctx, in.SubscriptionId, *tests.ClusterName, in.ResourceGroup.GetName(), tests.Ipv6Service.Name)
 					return err
 				}
-				lgr.Info("\n ======== Private Dns ipv6 test finished successfully, clearing service annotations ======== \n ")
-				tests.ClearAnnotations(ctx, in.SubscriptionId, *tests.ClusterName, in.ResourceGroup.GetName(), tests.Ipv4Service.Name)
-				tests.ClearAnnotations(ctx, in.SubscriptionId, *tests.ClusterName, in.ResourceGroup.GetName(), tests.Ipv6Service.Name)
+				lgr.Info("\n ======== Private Dns ipv6 test finished successfully ======== \n ")
 				return nil
 			},
 		},
 	}
+
+	// only provisioned clusters are known to be in the vnet linked to the private zones, and local ones can't reach Azure DNS
+	if !in.Existing && in.FakeDnsUrl == "" {
+		ret = append(ret, test{
+			name: "private DNS + in-cluster resolution",
+			run: func(ctx context.Context) error {
+				return PrivateResolutionTest(ctx, in)
+			},
+		})
+	}
+
+	return ret
+}
+
+// Creates a private A record for an internal service and checks a job in the cluster resolves it through Azure DNS and
+// reaches nginx behind it, which only works when the private zone is linked to the cluster vnet
+var PrivateResolutionTest = func(ctx context.Context, infra infra.Provisioned) error {
+	zone := testZone(infra, true)
+	name := tests.UniqueName("private-resolve")
+	hostname := name + "." + zone
+
+	lgr := logger.FromContext(ctx).With("hostname", hostname)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting test")
+
+	svc, err := tests.NewTestService(ctx, infra.Cluster, name, tests.Ipv4, tests.PrivateDnsAnnotations(hostname), 300)
+	if err != nil {
+		return fmt.Errorf("creating service with private dns annotations: %w", err)
+	}
+	defer tests.DeleteTestService(ctx, infra.Cluster, name)
+
+	if err := validatePrivateRecords(ctx, infra.Cluster, armprivatedns.RecordTypeA, infra.ResourceGroup.GetName(), infra.SubscriptionId, zone, hostname, 150, svc.Status.LoadBalancer.Ingress[0].IP); err != nil {
+		return fmt.Errorf("%s Private Record not created in Azure DNS: %w", armdns.RecordTypeA, err)
+	}
+
+	if err := tests.RunClientJob(ctx, infra.Cluster, name, manifests.AzureDnsNameserver, "http://"+hostname); err != nil {
+		return fmt.Errorf("resolving %s from the cluster: %w", hostname, err)
+	}
+
+	lgr.Info("Test Passed: Private Dns + in-cluster resolution")
+	return nil
 }
 
 var PrivateARecordTest = func(ctx context.Context, infra infra.Provisioned) error {
 	lgr := logger.FromContext(ctx)
 	lgr.Info("starting test")
 
-	ipv4ServiceName := infra.Ipv4ServiceName
+	zone := testZone(infra, true)
+	name := tests.UniqueName("private-a-record")
+	hostname := name + "." + zone
 
-	err := tests.PrivateDnsAnnotations(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, ipv4ServiceName)
+	svc, annotated, err := tests.NewTimedTestService(ctx, infra.Cluster, name, tests.Ipv4, tests.PrivateDnsAnnotations(hostname), 300)
 	if err != nil {
-		lgr.Error("Error annotating service with private dns annotations", err)
+		lgr.Error("Error creating service with private dns annotations", err)
 		return fmt.Errorf("error: %s", err)
 	}
+	defer tests.DeleteTestService(ctx, infra.Cluster, name)
 
 	//Validating Records
-	err = validatePrivateRecords(ctx, armprivatedns.RecordTypeA, tests.ResourceGroup, tests.SubId, *tests.ClusterName, tests.PrivateZone, 150, tests.Ipv4Service.Status.LoadBalancer.Ingress[0].IP)
+	err = validatePrivateRecords(ctx, infra.Cluster, armprivatedns.RecordTypeA, infra.ResourceGroup.GetName(), infra.SubscriptionId, zone, hostname, 150, svc.Status.LoadBalancer.Ingress[0].IP)
 	if err != nil {
-		return fmt.Errorf("%s Private Record not created in Azure DNS", armdns.RecordTypeA)
-	} else {
-		lgr.Info("Test Passed: Private Dns + A record test successfully")
+		return fmt.Errorf("%s Private Record not created in Azure DNS: %w", armdns.RecordTypeA, err)
 	}
+	observeLatency(ctx, annotated, string(armprivatedns.RecordTypeA), hostname, tests.ArmStage)
+	lgr.Info("Test Passed: Private Dns + A record test successfully")
 
-	err = tests.DeleteRecordSet(ctx, *tests.ClusterName, tests.SubId, tests.ResourceGroup, tests.PrivateZone, "", armprivatedns.RecordTypeA)
+	err = tests.DeleteRecordSet(ctx, infra.Cluster.GetName(), infra.SubscriptionId, infra.ResourceGroup.GetName(), zone, name, "", armprivatedns.RecordTypeA)
 	if err != nil {
-		lgr.Error("Error deleting AAAA record set")
-		return fmt.Errorf("error deleting AAAA record set")
+		lgr.Error("Error deleting A record set")
+		return fmt.Errorf("error deleting A record set")
 	}
 
 	return nil
@@ -83,24 +119,27 @@ var PrivateAAAATest = func(ctx context.Context, infra infra.Provisioned) error {
 	lgr := logger.FromContext(ctx)
 	lgr.Info("starting test")
 
-	ipv6ServiceName := infra.Ipv6ServiceName
+	zone := testZone(infra, true)
+	name := tests.UniqueName("private-aaaa-record")
+	hostname := name + "." + zone
 
-	err := tests.PrivateDnsAnnotations(ctx, tests.SubId, *tests.ClusterName, tests.ResourceGroup, ipv6ServiceName)
+	svc, annotated, err := tests.NewTimedTestService(ctx, infra.Cluster, name, tests.Ipv6, tests.PrivateDnsAnnotations(hostname), 300)
 	if err != nil {
-		lgr.Error("Error annotating service with private dns annotations", err)
+		lgr.Error("Error creating service with private dns annotations", err)
 		return fmt.Errorf("error: %s", err)
 	}
+	defer tests.DeleteTestService(ctx, infra.Cluster, name)
 
 	//Validating records
-	err = validatePrivateRecords(ctx, armprivatedns.RecordTypeAAAA, tests.ResourceGroup, tests.SubId, *tests.ClusterName, tests.PrivateZone, 150, tests.Ipv6Service.Status.LoadBalancer.Ingress[0].IP)
+	err = validatePrivateRecords(ctx, infra.Cluster, armprivatedns.RecordTypeAAAA, infra.ResourceGroup.GetName(), infra.SubscriptionId, zone, hostname, 150, svc.Status.LoadBalancer.Ingress[0].IP)
 	if err != nil {
-		return fmt.Errorf("%s Private Record not created in Azure DNS", armdns.RecordTypeAAAA)
-	} else {
-		lgr.Info("Test Passed: Private Dns + AAAA record test successfully")
+		return fmt.Errorf("%s Private Record not created in Azure DNS: %w", armdns.RecordTypeAAAA, err)
 	}
+	observeLatency(ctx, annotated, string(armprivatedns.RecordTypeAAAA), hostname, tests.ArmStage)
+	lgr.Info("Test Passed: Private Dns + AAAA record test successfully")
 
-	//Deleting A and AAAA record sets
-	err = tests.DeleteRecordSet(ctx, *tests.ClusterName, tests.SubId, tests.ResourceGroup, tests.PrivateZone, "", armprivatedns.RecordTypeAAAA)
+	//Deleting AAAA record set
+	err = tests.DeleteRecordSet(ctx, infra.Cluster.GetName(), infra.SubscriptionId, infra.ResourceGroup.GetName(), zone, name, "", armprivatedns.RecordTypeAAAA)
 	if err != nil {
 		lgr.Error("Error deleting AAAA record set")
 		return fmt.Errorf("error deleting AAAA record set")
@@ -110,69 +149,28 @@ var PrivateAAAATest = func(ctx context.Context, infra infra.Provisioned) error {
 
 }
 
-func validatePrivateRecords(ctx context.Context, recordType armprivatedns.RecordType, rg, subscriptionId, clusterName, serviceDnsZoneName string, numSeconds time.Duration, svcIp string) error {
+// Checks to see whether a record for hostname pointing at svcIp is created in the private zone
+func validatePrivateRecords(ctx context.Context, c tests.Cluster, recordType armprivatedns.RecordType, rg, subscriptionId, serviceDnsZoneName, hostname string, numSeconds time.Duration, svcIp string) error {
 	lgr := logger.FromContext(ctx)
 	lgr.Info("Checking that Record was created in Azure DNS")
 
-	//Default 10 seconds to wait for external dns pod to start running, can be modified in the future if needed
-	err := tests.WaitForExternalDns(ctx, 10, subscriptionId, rg, clusterName, "external-dns-private")
-	if err != nil {
-		return fmt.Errorf("error waiting for ExternalDNS to start running %w", err)
-	}
-
-	cred, err := clients.GetAzCred()
-	if err != nil {
-		return fmt.Errorf("getting az credentials: %w", err)
+	if recordType != armprivatedns.RecordTypeA && recordType != armprivatedns.RecordTypeAAAA {
+		return fmt.Errorf("unable to match record type")
 	}
 
-	clientFactory, err := armprivatedns.NewClientFactory(subscriptionId, cred, nil)
+	//Default 10 seconds to wait for external dns pod to start running, can be modified in the future if needed
+	err := tests.WaitForExternalDns(ctx, c, 10, "external-dns-private")
 	if err != nil {
-		log.Fatal(err)
-	}
-
-	timeout := time.Now().Add(numSeconds * time.Second)
-	var pageValue []*armprivatedns.RecordSet
-	for {
-		if time.Now().After(timeout) {
-			return fmt.Errorf("record not created within %s seconds", numSeconds)
-		}
-		pager := clientFactory.NewRecordSetsClient().NewListByTypePager(rg, serviceDnsZoneName, recordType, &armprivatedns.RecordSetsClientListByTypeOptions{Top: nil,
-			Recordsetnamesuffix: nil,
-		})
-
-		if pager.More() {
-			page, err := pager.NextPage(ctx)
-			if err != nil {
-				log.Fatal("failed to advance page", err)
-				return fmt.Errorf("failed to advance page for record sets")
-			}
-			if len(page.Value) > 0 {
-				pageValue = page.Value
-				break
-			}
-
-		}
-		time.Sleep(2 * time.Second)
+		return fmt.Errorf("error waiting for ExternalDNS to start running %w", err)
 	}
 
-	var ipAddr string
-
-	for _, v := range pageValue {
-		currZoneName := strings.Trim(*(v.Properties.Fqdn), ".") //removing trailing '.'
-
-		if recordType == armprivatedns.RecordTypeA {
-			ipAddr = *(v.Properties.ARecords[0].IPv4Address)
-		} else if recordType == armprivatedns.RecordTypeAAAA {
-			ipAddr = *(v.Properties.AaaaRecords[0].IPv6Address)
-		} else {
-			return fmt.Errorf("unable to match record type")
-		}
-
-		if currZoneName == serviceDnsZoneName && ipAddr == svcIp {
-			return nil
+	return pollRecord(ctx, numSeconds, fmt.Sprintf("private %s record %s created", recordType, hostname), func(ctx context.Context) (bool, error) {
+		rs, err := findRecord(ctx, subscriptionId, rg, serviceDnsZoneName, true, string(recordType), hostname)
+		if err != nil || rs == nil {
+			return false, err
 		}
 
-	}
-
-	return fmt.Errorf("record not created %s", recordType) //test failed
+		missing, extra := rs.Diff([]string{svcIp})
+		return len(missing) == 0 && len(extra) == 0, nil
+	})
 }
@@ -0,0 +1,99 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+// time allowed on top of the sync interval for external-dns to finish a sync and for the change to be listed
+const syncGrace = time.Minute
+
+// Returns how many seconds external-dns deployed onto in may take to pick up a change
+func syncSeconds(in infra.Provisioned) time.Duration {
+	return time.Duration((in.DnsSyncInterval() + syncGrace).Seconds())
+}
+
+// Returns the name of the zone tests publish records in, the first public or private zone of the infrastructure
+func testZone(in infra.Provisioned, private bool) string {
+	if private {
+		return in.PrivateZones[0].GetName()
+	}
+	return in.Zones[0].GetName()
+}
+
+// Records that the recordType record for hostname reached stage now, measured from the local time annotated that the
+// annotation publishing it was applied
+func observeLatency(ctx context.Context, annotated time.Time, recordType, hostname string, stage tests.Stage) {
+	tests.ObserveLatency(ctx, recordType+" "+hostname, stage, annotated, time.Now())
+}
+
+// Calls check every 2 seconds until it reports done, returns an error if that doesn't happen within numSeconds
+func pollRecord(ctx context.Context, numSeconds time.Duration, description string, check func(ctx context.Context) (bool, error)) error {
+	timeout := time.Now().Add(numSeconds * time.Second)
+	for {
+		done, err := check(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		if time.Now().After(timeout) {
+			return fmt.Errorf("%s not within %d seconds", description, numSeconds)
+		}
+		time.Sleep(2 * time.Second) //waiting 2 seconds before checking again
+	}
+}
+
+// Checks the recordType record set with fqdn hostname in the public or private zone holds exactly targets, no more and no
+// less, within numSeconds. No targets checks the record set is removed
+func validateTargets(ctx context.Context, in infra.Provisioned, private bool, recordType, hostname string, targets []string, numSeconds time.Duration) error {
+	lgr := logger.FromContext(ctx).With("recordType", recordType, "hostname", hostname, "targets", targets)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to validate record targets")
+	defer lgr.Info("finished validating record targets")
+
+	zone := testZone(in, private)
+	var mismatch error
+	err := pollRecord(ctx, numSeconds, fmt.Sprintf("%s record %s holding %v", recordType, hostname, targets), func(ctx context.Context) (bool, error) {
+		rs, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), zone, private, recordType, hostname)
+		if err != nil {
+			return false, fmt.Errorf("finding %s record %s: %w", recordType, hostname, err)
+		}
+		if rs == nil {
+			mismatch = fmt.Errorf("%s record %s doesn't exist", recordType, hostname)
+			return len(targets) == 0, nil
+		}
+
+		missing, extra := rs.Diff(targets)
+		if len(missing) > 0 || len(extra) > 0 {
+			mismatch = fmt.Errorf("%s record %s holds %v, missing %v and extra %v", recordType, hostname, rs.Values, missing, extra)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil && mismatch != nil {
+		return fmt.Errorf("%w: %s", err, mismatch)
+	}
+	return err
+}
+
+// Returns the record set of recordType with the fqdn hostname in the public or private zone, or nil if there's none.
+// It's read by name so it's found however many records the tests running concurrently create in the same zone
+func findRecord(ctx context.Context, subscriptionId, rg, zone string, private bool, recordType, hostname string) (*clients.RecordSet, error) {
+	return clients.GetRecordSet(ctx, clients.RecordQuery{
+		SubscriptionId: subscriptionId,
+		ResourceGroup:  rg,
+		Zone:           zone,
+		Private:        private,
+		Type:           recordType,
+		Name:           clients.RelativeName(hostname, zone),
+	})
+}
@@ -0,0 +1,179 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+	"github.com/Azure/go-autorest/autorest/azure"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+)
+
+const (
+	testSubscriptionId = "00000000-0000-0000-0000-000000000000"
+	testResourceGroup  = "rg"
+	testPublicZone     = "example.com"
+	testPrivateZone    = "example.internal"
+)
+
+// newFakeProvisioned returns an infra whose zones live in a new fake, which every arm client is pointed at until the
+// test ends. The fake returns 2 record sets a page so record sets are spread over several pages
+func newFakeProvisioned(t *testing.T) infra.Provisioned {
+	t.Helper()
+
+	fake := clients.NewFakeDns()
+	fake.SetPageSize(2)
+	nameservers := fake.AddZone(testSubscriptionId, testResourceGroup, testPublicZone)
+	fake.AddPrivateZone(testSubscriptionId, testResourceGroup, testPrivateZone)
+	clients.UseFakeDns(fake)
+	t.Cleanup(clients.UseAzureDns)
+
+	rgId, err := arm.ParseResourceID(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", testSubscriptionId, testResourceGroup))
+	if err != nil {
+		t.Fatalf("parsing resource group id: %s", err)
+	}
+	zoneId, err := azure.ParseResourceID(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/dnszones/%s", testSubscriptionId, testResourceGroup, testPublicZone))
+	if err != nil {
+		t.Fatalf("parsing zone id: %s", err)
+	}
+	privateZoneId, err := azure.ParseResourceID(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/privateDnsZones/%s", testSubscriptionId, testResourceGroup, testPrivateZone))
+	if err != nil {
+		t.Fatalf("parsing private zone id: %s", err)
+	}
+
+	in := infra.Provisioned{
+		Name:           "fake",
+		SubscriptionId: testSubscriptionId,
+		ResourceGroup:  clients.LoadRg(*rgId),
+	}
+	in.Zones = append(in.Zones, clients.LoadZone(zoneId, nameservers))
+	in.PrivateZones = append(in.PrivateZones, clients.LoadPrivateZone(privateZoneId))
+	return in
+}
+
+// putARecord writes an A record set for name in the public or private test zone holding ips
+func putARecord(t *testing.T, private bool, name string, ips ...string) {
+	t.Helper()
+	ctx := context.Background()
+
+	cred, err := clients.GetAzCred()
+	if err != nil {
+		t.Fatalf("getting credentials: %s", err)
+	}
+
+	if private {
+		c, err := armprivatedns.NewRecordSetsClient(testSubscriptionId, cred, clients.GetClientOptions())
+		if err != nil {
+			t.Fatalf("creating private record sets client: %s", err)
+		}
+		rs := armprivatedns.RecordSet{Properties: &armprivatedns.RecordSetProperties{TTL: to.Ptr[int64](300)}}
+		for _, ip := range ips {
+			rs.Properties.ARecords = append(rs.Properties.ARecords, &armprivatedns.ARecord{IPv4Address: to.Ptr(ip)})
+		}
+		if _, err := c.CreateOrUpdate(ctx, testResourceGroup, testPrivateZone, armprivatedns.RecordTypeA, name, rs, nil); err != nil {
+			t.Fatalf("creating private record set %s: %s", name, err)
+		}
+		return
+	}
+
+	c, err := armdns.NewRecordSetsClient(testSubscriptionId, cred, clients.GetClientOptions())
+	if err != nil {
+		t.Fatalf("creating record sets client: %s", err)
+	}
+	rs := armdns.RecordSet{Properties: &armdns.RecordSetProperties{TTL: to.Ptr[int64](300)}}
+	for _, ip := range ips {
+		rs.Properties.ARecords = append(rs.Properties.ARecords, &armdns.ARecord{IPv4Address: to.Ptr(ip)})
+	}
+	if _, err := c.CreateOrUpdate(ctx, testResourceGroup, testPublicZone, name, armdns.RecordTypeA, rs, nil); err != nil {
+		t.Fatalf("creating record set %s: %s", name, err)
+	}
+}
+
+func TestFindRecord(t *testing.T) {
+	in := newFakeProvisioned(t)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		putARecord(t, false, name, "192.0.2.1")
+	}
+	putARecord(t, true, "e", "192.0.2.2")
+
+	tests := []struct {
+		name     string
+		private  bool
+		hostname string
+		wantIp   string
+	}{
+		{name: "beyond the first page", hostname: "e." + testPublicZone, wantIp: "192.0.2.1"},
+		{name: "case insensitive", hostname: "E." + strings.ToUpper(testPublicZone), wantIp: "192.0.2.1"},
+		{name: "private", private: true, hostname: "e." + testPrivateZone, wantIp: "192.0.2.2"},
+		{name: "missing", hostname: "f." + testPublicZone},
+		{name: "private missing", private: true, hostname: "a." + testPrivateZone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := findRecord(context.Background(), testSubscriptionId, testResourceGroup, testZone(in, tt.private), tt.private, "A", tt.hostname)
+			if err != nil {
+				t.Fatalf("finding record: %s", err)
+			}
+
+			if tt.wantIp == "" {
+				if rs != nil {
+					t.Errorf("found record %+v, expected none", rs)
+				}
+				return
+			}
+			if rs == nil {
+				t.Fatal("found no record")
+			}
+			if missing, extra := rs.Diff([]string{tt.wantIp}); len(missing) > 0 || len(extra) > 0 {
+				t.Errorf("record holds %v, expected %s", rs.Values, tt.wantIp)
+			}
+		})
+	}
+}
+
+func TestValidateTargets(t *testing.T) {
+	in := newFakeProvisioned(t)
+	putARecord(t, false, "multi", "192.0.2.1", "192.0.2.2")
+	putARecord(t, true, "multi", "192.0.2.3")
+
+	tests := []struct {
+		name     string
+		private  bool
+		hostname string
+		targets  []string
+		wantErr  string
+	}{
+		{name: "exact", hostname: "multi." + testPublicZone, targets: []string{"192.0.2.2", "192.0.2.1"}},
+		{name: "private exact", private: true, hostname: "multi." + testPrivateZone, targets: []string{"192.0.2.3"}},
+		{name: "missing target", hostname: "multi." + testPublicZone, targets: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}, wantErr: "missing [192.0.2.3]"},
+		{name: "stale target", hostname: "multi." + testPublicZone, targets: []string{"192.0.2.1"}, wantErr: "extra [192.0.2.2]"},
+		{name: "removed", hostname: "gone." + testPublicZone},
+		{name: "not removed", private: true, hostname: "multi." + testPrivateZone, wantErr: "holds [192.0.2.3]"},
+		{name: "not created", hostname: "gone." + testPublicZone, targets: []string{"192.0.2.1"}, wantErr: "doesn't exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// no time to poll, so the first check decides
+			err := validateTargets(context.Background(), in, tt.private, "A", tt.hostname, tt.targets, 0)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validating targets: %s", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validating targets returned %v, expected an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+const (
+	txtHeritage      = "external-dns"
+	txtHeritageLabel = "heritage"
+	txtOwnerLabel    = "external-dns/owner"
+)
+
+// Tests the TXT records external-dns registers beside every record it creates to track ownership
+func registrySuite(in infra.Provisioned) []test {
+	return []test{
+		{
+			name: "public DNS + A Record TXT registry",
+			run: func(ctx context.Context) error {
+				return registryTest(ctx, in, false, tests.Ipv4)
+			},
+		},
+		{
+			name: "public DNS + AAAA Record TXT registry",
+			run: func(ctx context.Context) error {
+				return registryTest(ctx, in, false, tests.Ipv6)
+			},
+		},
+		{
+			name: "private DNS + A Record TXT registry",
+			run: func(ctx context.Context) error {
+				return registryTest(ctx, in, true, tests.Ipv4)
+			},
+		},
+	}
+}
+
+// Creates a record through a service, checks its TXT records are owned by the cluster, then removes the hostname and
+// checks the TXT records are removed together with the record
+func registryTest(ctx context.Context, in infra.Provisioned, private bool, ipFamily tests.IpFamily) error {
+	recordType := "A"
+	if ipFamily == tests.Ipv6 {
+		recordType = "AAAA"
+	}
+
+	zone := testZone(in, private)
+
+	name := tests.UniqueName("txt-" + strings.ToLower(recordType))
+	hostname := name + "." + zone
+
+	lgr := logger.FromContext(ctx).With("recordType", recordType, "private", private, "hostname", hostname)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting TXT registry test")
+
+	annotations := map[string]string{hostnameAnnotation: hostname}
+	if private {
+		annotations = tests.PrivateDnsAnnotations(hostname)
+	}
+
+	svc, err := tests.NewTestService(ctx, in.Cluster, name, ipFamily, annotations, 300)
+	if err != nil {
+		return fmt.Errorf("creating service %s: %w", name, err)
+	}
+	defer tests.DeleteTestService(ctx, in.Cluster, name)
+
+	ip := svc.Status.LoadBalancer.Ingress[0].IP
+	if private {
+		err = validatePrivateRecords(ctx, in.Cluster, armprivatedns.RecordType(recordType), in.ResourceGroup.GetName(), in.SubscriptionId, zone, hostname, 150, ip)
+	} else {
+		err = validateRecord(ctx, in.Cluster, armdns.RecordType(recordType), in.ResourceGroup.GetName(), in.SubscriptionId, zone, hostname, 150, ip)
+	}
+	if err != nil {
+		return fmt.Errorf("%s record not created: %w", recordType, err)
+	}
+
+	if err := validateTxtOwner(ctx, in, private, zone, hostname, recordType, in.Cluster.GetId(), 60); err != nil {
+		return fmt.Errorf("validating TXT registry records: %w", err)
+	}
+
+	if err := tests.RemoveAnnotations(ctx, in.Cluster, name, hostnameAnnotation); err != nil {
+		return err
+	}
+
+	if err := validateRegisteredRecordRemoved(ctx, in, private, zone, hostname, recordType, syncSeconds(in)); err != nil {
+		return fmt.Errorf("validating records were removed: %w", err)
+	}
+
+	lgr.Info("Test Passed: TXT registry")
+	return nil
+}
+
+// Returns the names of the TXT records external-dns registers beside a record. The current format prefixes the first
+// label with the record type, the old format shares the record's name and isn't written for AAAA records
+func txtRegistryNames(hostname, recordType string) []string {
+	names := []string{strings.ToLower(recordType) + "-" + hostname}
+	if recordType != "AAAA" {
+		names = append(names, hostname)
+	}
+	return names
+}
+
+// Parses a registry TXT value like "heritage=external-dns,external-dns/owner=<id>,external-dns/resource=service/ns/name"
+// and returns the owner
+func txtRegistryOwner(value string) (string, error) {
+	labels := map[string]string{}
+	for _, label := range strings.Split(strings.Trim(value, `"`), ",") {
+		key, val, ok := strings.Cut(label, "=")
+		if !ok {
+			return "", fmt.Errorf("invalid label %s in TXT record %s", label, value)
+		}
+		labels[key] = val
+	}
+
+	if labels[txtHeritageLabel] != txtHeritage {
+		return "", fmt.Errorf("TXT record %s doesn't have heritage %s", value, txtHeritage)
+	}
+
+	owner, ok := labels[txtOwnerLabel]
+	if !ok {
+		return "", fmt.Errorf("TXT record %s doesn't have an owner", value)
+	}
+
+	return owner, nil
+}
+
+// Returns the values of the TXT record set with fqdn hostname, or nil if there's none
+func findTxtValues(ctx context.Context, in infra.Provisioned, private bool, zone, hostname string) ([]string, error) {
+	rs, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), zone, private, string(armdns.RecordTypeTXT), hostname)
+	if err != nil || rs == nil {
+		return nil, err
+	}
+	return rs.Values, nil
+}
+
+// Checks the TXT records registered beside the record with hostname exist and are all owned by owner
+func validateTxtOwner(ctx context.Context, in infra.Provisioned, private bool, zone, hostname, recordType, owner string, numSeconds time.Duration) error {
+	lgr := logger.FromContext(ctx).With("owner", owner)
+	lgr.Info("starting to validate TXT registry records")
+	defer lgr.Info("finished validating TXT registry records")
+
+	names := txtRegistryNames(hostname, recordType)
+	return pollRecord(ctx, numSeconds, fmt.Sprintf("TXT registry record %s created", names[0]), func(ctx context.Context) (bool, error) {
+		found := false
+		for i, name := range names {
+			values, err := findTxtValues(ctx, in, private, zone, name)
+			if err != nil {
+				return false, fmt.Errorf("finding TXT record %s: %w", name, err)
+			}
+			if len(values) == 0 {
+				if i == 0 {
+					// the current format is always written, keep waiting for it
+					return false, nil
+				}
+				continue
+			}
+
+			for _, value := range values {
+				got, err := txtRegistryOwner(value)
+				if err != nil {
+					return false, err
+				}
+				// an owner mismatch won't fix itself, so fail right away instead of polling
+				if got != owner {
+					return false, fmt.Errorf("TXT record %s is owned by %s, expected %s", name, got, owner)
+				}
+			}
+			found = true
+		}
+
+		return found, nil
+	})
+}
+
+// Checks the record with hostname and every TXT record registered beside it are removed
+func validateRegisteredRecordRemoved(ctx context.Context, in infra.Provisioned, private bool, zone, hostname, recordType string, numSeconds time.Duration) error {
+	lgr := logger.FromContext(ctx)
+	lgr.Info("starting to validate records were removed")
+	defer lgr.Info("finished validating records were removed")
+
+	return pollRecord(ctx, numSeconds, fmt.Sprintf("%s record %s and its TXT records removed", recordType, hostname), func(ctx context.Context) (bool, error) {
+		rs, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), zone, private, recordType, hostname)
+		if err != nil || rs != nil {
+			return false, err
+		}
+
+		for _, name := range txtRegistryNames(hostname, recordType) {
+			values, err := findTxtValues(ctx, in, private, zone, name)
+			if err != nil || len(values) > 0 {
+				return false, err
+			}
+		}
+
+		return true, nil
+	})
+}
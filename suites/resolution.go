@@ -0,0 +1,179 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+// networks records are resolved over, tcp is what resolvers fall back to for large answers so both must serve the record
+var resolveNetworks = []string{"udp", "tcp"}
+
+// Tests records published in the public zone resolve through its nameservers, not only show up in arm. Zones without
+// nameservers, like ones loaded from an older infra file, don't get these tests
+func resolutionSuite(in infra.Provisioned) []test {
+	if len(in.Zones) == 0 || len(in.Zones[0].GetNameservers()) == 0 {
+		return nil
+	}
+
+	return []test{
+		{
+			name: "public DNS + A and TXT Record resolution",
+			run: func(ctx context.Context) error {
+				return addressResolutionTest(ctx, in, tests.Ipv4)
+			},
+		},
+		{
+			name: "public DNS + AAAA Record resolution",
+			run: func(ctx context.Context) error {
+				return addressResolutionTest(ctx, in, tests.Ipv6)
+			},
+		},
+		{
+			name: "public DNS + CNAME Record resolution",
+			run: func(ctx context.Context) error {
+				return cnameResolutionTest(ctx, in)
+			},
+		},
+	}
+}
+
+// Creates an A or AAAA record through a service and checks it and the TXT record registered beside it resolve
+func addressResolutionTest(ctx context.Context, in infra.Provisioned, ipFamily tests.IpFamily) error {
+	recordType := armdns.RecordTypeA
+	if ipFamily == tests.Ipv6 {
+		recordType = armdns.RecordTypeAAAA
+	}
+
+	zone := testZone(in, false)
+	name := tests.UniqueName("resolve-" + strings.ToLower(string(recordType)))
+	hostname := name + "." + zone
+
+	lgr := logger.FromContext(ctx).With("recordType", recordType, "hostname", hostname)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting resolution test")
+
+	svc, annotated, err := tests.NewTimedTestService(ctx, in.Cluster, name, ipFamily, map[string]string{hostnameAnnotation: hostname}, 300)
+	if err != nil {
+		return fmt.Errorf("creating service %s: %w", name, err)
+	}
+	defer tests.DeleteTestService(ctx, in.Cluster, name)
+
+	if err := validateRecord(ctx, in.Cluster, recordType, in.ResourceGroup.GetName(), in.SubscriptionId, zone, hostname, syncSeconds(in), svc.Status.LoadBalancer.Ingress[0].IP); err != nil {
+		return fmt.Errorf("%s record not created: %w", recordType, err)
+	}
+	observeLatency(ctx, annotated, string(recordType), hostname, tests.ArmStage)
+
+	if err := observeResolvable(ctx, in, annotated, recordType, hostname); err != nil {
+		return err
+	}
+	if err := validateResolution(ctx, in, armdns.RecordTypeTXT, txtRegistryNames(hostname, string(recordType))[0], syncSeconds(in)); err != nil {
+		return err
+	}
+
+	lgr.Info("Test Passed: resolution")
+	return nil
+}
+
+// Points a hostname at a target through the target annotation and checks the CNAME record resolves
+func cnameResolutionTest(ctx context.Context, in infra.Provisioned) error {
+	zone := testZone(in, false)
+	name := tests.UniqueName("resolve-cname")
+	hostname := name + "." + zone
+	target := name + "-target.example.com"
+
+	lgr := logger.FromContext(ctx).With("recordType", armdns.RecordTypeCNAME, "hostname", hostname)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting resolution test")
+
+	svc := newTargetService(name, hostname, []string{target})
+	if err := in.Cluster.Deploy(ctx, []client.Object{svc}); err != nil {
+		return fmt.Errorf("deploying service %s: %w", name, err)
+	}
+	defer tests.DeleteTestService(ctx, in.Cluster, name)
+
+	if err := validateResolution(ctx, in, armdns.RecordTypeCNAME, hostname, syncSeconds(in)); err != nil {
+		return err
+	}
+
+	lgr.Info("Test Passed: resolution")
+	return nil
+}
+
+// Waits for the public record to resolve when the zone has nameservers and records how long it took since the service
+// publishing it was annotated
+func observeResolvable(ctx context.Context, in infra.Provisioned, annotated time.Time, recordType armdns.RecordType, hostname string) error {
+	if len(in.Zones[0].GetNameservers()) == 0 {
+		return nil
+	}
+
+	if err := validateResolution(ctx, in, recordType, hostname, syncSeconds(in)); err != nil {
+		return err
+	}
+	observeLatency(ctx, annotated, string(recordType), hostname, tests.ResolvableStage)
+	return nil
+}
+
+// Checks every nameserver of the public zone answers for the record set of recordType with fqdn hostname over udp and
+// tcp with the same records and ttl the record set holds in arm
+func validateResolution(ctx context.Context, in infra.Provisioned, recordType armdns.RecordType, hostname string, numSeconds time.Duration) error {
+	lgr := logger.FromContext(ctx).With("resolving", hostname, "resolvingType", recordType)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to validate resolution")
+	defer lgr.Info("finished validating resolution")
+
+	zone := testZone(in, false)
+	nameservers := in.Zones[0].GetNameservers()
+
+	var mismatch error
+	err := pollRecord(ctx, numSeconds, fmt.Sprintf("%s record %s resolved", recordType, hostname), func(ctx context.Context) (bool, error) {
+		rs, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), zone, false, string(recordType), hostname)
+		if err != nil {
+			return false, fmt.Errorf("finding %s record %s: %w", recordType, hostname, err)
+		}
+		if rs == nil {
+			mismatch = fmt.Errorf("%s record %s isn't in arm", recordType, hostname)
+			return false, nil
+		}
+
+		// records propagate to the nameservers after arm returns them, and a query can be dropped or time out, so both
+		// mismatches and resolve errors are retried
+		for _, nameserver := range nameservers {
+			for _, network := range resolveNetworks {
+				answers, err := clients.Resolve(ctx, nameserver, network, hostname, string(recordType))
+				if err != nil {
+					mismatch = fmt.Errorf("resolving %s record %s: %w", recordType, hostname, err)
+					return false, nil
+				}
+
+				got := make([]string, len(answers))
+				for i, answer := range answers {
+					got[i] = answer.Value
+					if int64(answer.Ttl) != rs.Ttl {
+						mismatch = fmt.Errorf("%s over %s answered ttl %d, expected %d", nameserver, network, answer.Ttl, rs.Ttl)
+						return false, nil
+					}
+				}
+				if missing, extra := rs.Diff(got); len(missing) > 0 || len(extra) > 0 {
+					mismatch = fmt.Errorf("%s over %s answered %v, expected %v", nameserver, network, got, rs.Values)
+					return false, nil
+				}
+			}
+		}
+
+		return true, nil
+	})
+	if err != nil && mismatch != nil {
+		return fmt.Errorf("%w: %s", err, mismatch)
+	}
+	return err
+}
@@ -0,0 +1,181 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+// labels the services of one scale test so they're deleted together
+const scaleLabel = "external-dns-e2e/scale"
+
+// most services 198.18.0.0/15 has a target address for
+const maxScaleServices = 1 << 17
+
+// Tests external-dns publishes many records at once, enough to be listed across several pages and to hit arm throttling
+func scaleSuite(in infra.Provisioned, services int) []test {
+	if services <= 0 {
+		return nil
+	}
+
+	return []test{
+		{
+			name: "public DNS + A Records at scale",
+			run: func(ctx context.Context) error {
+				return scaleTest(ctx, in, services)
+			},
+		},
+	}
+}
+
+// Deploys services in a single batch, each with its own hostname and target, and checks every A record and its TXT
+// registry records are created with the right target, then removed once the services are deleted. The services are
+// ClusterIP services published through the target annotation so they don't each need a load balancer ip
+func scaleTest(ctx context.Context, in infra.Provisioned, services int) error {
+	if services > maxScaleServices {
+		return fmt.Errorf("scale test supports at most %d services, got %d", maxScaleServices, services)
+	}
+
+	zone := testZone(in, false)
+	prefix := tests.UniqueName("scale")
+
+	lgr := logger.FromContext(ctx).With("prefix", prefix, "services", services)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting scale test")
+
+	targets := map[string]string{} // target of every hostname
+	objs := make([]client.Object, services)
+	for i := range objs {
+		name := fmt.Sprintf("%s-%d", prefix, i)
+		hostname := name + "." + zone
+		targets[hostname] = scaleTarget(i)
+
+		svc := newTargetService(name, hostname, []string{targets[hostname]})
+		svc.Labels = map[string]string{scaleLabel: prefix}
+		objs[i] = svc
+	}
+
+	if err := in.Cluster.Deploy(ctx, objs); err != nil {
+		return fmt.Errorf("deploying %d services: %w", services, err)
+	}
+	applied := time.Now()
+	deleteServices := func() error {
+		return in.Cluster.DeleteAllOf(ctx, clients.NewNginxService("", "", nil), map[string]string{scaleLabel: prefix})
+	}
+	defer deleteServices()
+
+	// external-dns writes records one request at a time, so larger batches get longer on top of a sync
+	numSeconds := syncSeconds(in) + time.Duration(services)
+
+	var mismatch error
+	err := pollRecord(ctx, numSeconds, fmt.Sprintf("%d A records converged", services), func(ctx context.Context) (bool, error) {
+		aSets, aPages, err := listScaleRecordSets(ctx, in, zone, armdns.RecordTypeA, prefix)
+		if err != nil {
+			return false, err
+		}
+		txtSets, txtPages, err := listScaleRecordSets(ctx, in, zone, armdns.RecordTypeTXT, prefix)
+		if err != nil {
+			return false, err
+		}
+		lgr.Info("listed scale record sets", "aRecordSets", len(aSets), "aPages", aPages, "txtRecordSets", len(txtSets), "txtPages", txtPages)
+
+		for hostname, rs := range aSets {
+			target, ok := targets[hostname]
+			if !ok {
+				return false, fmt.Errorf("unexpected A record %s", hostname)
+			}
+
+			if missing, extra := rs.Diff([]string{target}); len(missing) > 0 || len(extra) > 0 {
+				return false, fmt.Errorf("A record %s has targets %v, expected %s", hostname, rs.Values, target)
+			}
+		}
+
+		for hostname := range targets {
+			if _, ok := aSets[hostname]; !ok {
+				mismatch = fmt.Errorf("A record %s missing, %d of %d created", hostname, len(aSets), services)
+				return false, nil
+			}
+			for _, txtName := range txtRegistryNames(hostname, "A") {
+				if _, ok := txtSets[txtName]; !ok {
+					mismatch = fmt.Errorf("TXT record %s missing, %d of %d created", txtName, len(txtSets), 2*services)
+					return false, nil
+				}
+			}
+		}
+		if len(txtSets) != 2*services {
+			return false, fmt.Errorf("%d TXT records, expected %d", len(txtSets), 2*services)
+		}
+
+		lgr.Info("scale records converged", "convergence", time.Since(applied), "recordSets", len(aSets)+len(txtSets), "pages", aPages+txtPages)
+		return true, nil
+	})
+	if err != nil {
+		if mismatch != nil {
+			return fmt.Errorf("%w: %s", err, mismatch)
+		}
+		return err
+	}
+	tests.ObserveLatency(ctx, fmt.Sprintf("%d A records in %s", services, zone), tests.ConvergedStage, applied, time.Now())
+
+	if err := deleteServices(); err != nil {
+		return fmt.Errorf("deleting %d services: %w", services, err)
+	}
+
+	err = pollRecord(ctx, numSeconds, fmt.Sprintf("%d A records removed", services), func(ctx context.Context) (bool, error) {
+		aSets, _, err := listScaleRecordSets(ctx, in, zone, armdns.RecordTypeA, prefix)
+		if err != nil {
+			return false, err
+		}
+		txtSets, _, err := listScaleRecordSets(ctx, in, zone, armdns.RecordTypeTXT, prefix)
+		if err != nil {
+			return false, err
+		}
+
+		mismatch = fmt.Errorf("%d A and %d TXT records left", len(aSets), len(txtSets))
+		return len(aSets) == 0 && len(txtSets) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, mismatch)
+	}
+
+	lgr.Info("Test Passed: scale")
+	return nil
+}
+
+// Returns the record sets of recordType in the public zone created for the scale test with prefix keyed by fqdn, and
+// the number of pages listed
+func listScaleRecordSets(ctx context.Context, in infra.Provisioned, zone string, recordType armdns.RecordType, prefix string) (map[string]clients.RecordSet, int, error) {
+	recordSets, err := clients.QueryRecordSets(ctx, clients.RecordQuery{
+		SubscriptionId: in.SubscriptionId,
+		ResourceGroup:  in.ResourceGroup.GetName(),
+		Zone:           zone,
+		Type:           string(recordType),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing %s records: %w", recordType, err)
+	}
+
+	ret := map[string]clients.RecordSet{}
+	for _, rs := range recordSets.RecordSets {
+		// TXT registry records are prefixed with the type of their record
+		if strings.HasPrefix(rs.Name, prefix+"-") || strings.HasPrefix(rs.Name, "a-"+prefix+"-") {
+			ret[rs.Fqdn] = rs
+		}
+	}
+
+	return ret, recordSets.Pages, nil
+}
+
+// Returns the target of the service at index i, a distinct address in the 198.18.0.0/15 benchmarking range
+func scaleTarget(i int) string {
+	return fmt.Sprintf("198.%d.%d.%d", 18+i>>16, i>>8&0xff, i&0xff)
+}
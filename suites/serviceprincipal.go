@@ -0,0 +1,87 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	manifests "github.com/Azure/azure-provider-external-dns-e2e/pkgResources/pkgManifests"
+)
+
+// Tests external-dns reads its service principal secret from a Secret, writes records with it and keeps syncing once
+// the secret is rotated. Infras deployed with another auth mode have no app registration to rotate
+func servicePrincipalSuite(in infra.Provisioned) []test {
+	if in.ExternalDnsAuth != manifests.ServicePrincipalSecretAuth {
+		return nil
+	}
+
+	return []test{
+		{
+			name: "service principal + external-dns deployments",
+			run: func(ctx context.Context) error {
+				return validateServicePrincipal(ctx, in)
+			},
+		},
+		{
+			name: "service principal + public and private DNS A Records",
+			run: func(ctx context.Context) error {
+				return authRecordsTest(ctx, in)
+			},
+		},
+		{
+			name: "service principal + secret rotation",
+			run: func(ctx context.Context) error {
+				return secretRotationTest(ctx, in)
+			},
+			// external-dns restarts and the old secrets are removed, which records other tests wait on could time out over
+			exclusive: true,
+		},
+	}
+}
+
+// Checks azure.json is mounted from a Secret, so the client secret isn't readable from a ConfigMap
+func validateServicePrincipal(ctx context.Context, in infra.Provisioned) error {
+	lgr := logger.FromContext(ctx)
+	lgr.Info("starting to validate external-dns uses a service principal secret")
+	defer lgr.Info("finished validating external-dns uses a service principal secret")
+
+	for _, provider := range manifests.Providers {
+		deployment, err := in.Cluster.GetDeployment(ctx, "kube-system", provider.ResourceName())
+		if err != nil {
+			return fmt.Errorf("getting deployment %s: %w", provider.ResourceName(), err)
+		}
+
+		fromSecret := false
+		for _, volume := range deployment.Spec.Template.Spec.Volumes {
+			if volume.Name == "azure-config" && volume.Secret != nil {
+				fromSecret = true
+			}
+		}
+		if !fromSecret {
+			return fmt.Errorf("deployment %s doesn't mount azure-config from a secret", provider.ResourceName())
+		}
+	}
+
+	lgr.Info("Test Passed: external-dns uses a service principal secret")
+	return nil
+}
+
+// Rotates the client secret and checks external-dns still syncs records, which it can only do with the new secret since
+// the old ones are removed
+func secretRotationTest(ctx context.Context, in infra.Provisioned) error {
+	lgr := logger.FromContext(ctx)
+	lgr.Info("starting secret rotation test")
+	defer lgr.Info("finished secret rotation test")
+
+	if err := in.RotateExternalDnsSecret(ctx); err != nil {
+		return fmt.Errorf("rotating secret: %w", err)
+	}
+
+	if err := ARecordTest(ctx, in); err != nil {
+		return fmt.Errorf("syncing after rotation: %w", err)
+	}
+
+	lgr.Info("Test Passed: external-dns syncs after secret rotation")
+	return nil
+}
@@ -0,0 +1,170 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+// ttl the azure providers give records whose service has no valid ttl annotation
+const defaultRecordTtl = 300
+
+// ttlStep sets the ttl annotation of a service, an empty annotation leaves it unset, and expects the record to get ttl
+type ttlStep struct {
+	annotation string
+	ttl        int64
+}
+
+// external-dns only updates a ttl that's configured, so each case starts from a new service rather than clearing or
+// breaking the annotation of an existing one, which would leave the old ttl in place
+var ttlCases = []struct {
+	name  string
+	steps []ttlStep
+}{
+	{name: "default TTL", steps: []ttlStep{{"", defaultRecordTtl}}},
+	{name: "custom TTL", steps: []ttlStep{{"600", 600}, {"120", 120}}},
+	// over the largest ttl external-dns accepts, so it's ignored and the record is still created with the default
+	{name: "out of range TTL", steps: []ttlStep{{"2147483648", defaultRecordTtl}}},
+}
+
+// Tests the ttl annotation sets the ttl of A records in the public and private zones, and changing it updates the
+// record in place
+func ttlSuite(in infra.Provisioned) []test {
+	var ret []test
+	for _, private := range []bool{false, true} {
+		zoneKind := "public"
+		if private {
+			zoneKind = "private"
+		}
+
+		for _, c := range ttlCases {
+			private, steps := private, c.steps
+			ret = append(ret, test{
+				name: fmt.Sprintf("%s DNS + A Record %s", zoneKind, c.name),
+				run: func(ctx context.Context) error {
+					return ttlTest(ctx, in, private, steps)
+				},
+			})
+		}
+	}
+
+	return ret
+}
+
+// Publishes an A record with the ttl annotation of the first step, then changes the annotation for every later step.
+// Each step checks the record set ttl, and changes check the record set stays the same record set with the same target
+// throughout instead of being removed and created again
+func ttlTest(ctx context.Context, in infra.Provisioned, private bool, steps []ttlStep) error {
+	zone := testZone(in, private)
+	name := tests.UniqueName("ttl")
+	hostname := name + "." + zone
+	target := "192.0.2.20" // 192.0.2.0/24 is a documentation range, nothing needs to answer on it
+
+	lgr := logger.FromContext(ctx).With("private", private, "hostname", hostname)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting ttl test")
+
+	svc := newTargetService(name, hostname, []string{target})
+	if steps[0].annotation != "" {
+		svc.Annotations[ttlAnnotation] = steps[0].annotation
+	}
+	if err := in.Cluster.Deploy(ctx, []client.Object{svc}); err != nil {
+		return fmt.Errorf("deploying service %s: %w", name, err)
+	}
+	defer tests.DeleteTestService(ctx, in.Cluster, name)
+
+	if err := validateTargets(ctx, in, private, string(armdns.RecordTypeA), hostname, []string{target}, syncSeconds(in)); err != nil {
+		return err
+	}
+
+	var previous *clients.RecordSet
+	for i, step := range steps {
+		if i > 0 {
+			if err := tests.AnnotateService(ctx, in.Cluster, name, map[string]string{ttlAnnotation: step.annotation}); err != nil {
+				return fmt.Errorf("changing ttl of %s to %s: %w", name, step.annotation, err)
+			}
+		}
+
+		rs, err := validateTtl(ctx, in, private, hostname, target, step.ttl, previous)
+		if err != nil {
+			return fmt.Errorf("ttl annotation %q: %w", step.annotation, err)
+		}
+		if previous != nil {
+			lgr.Info("ttl updated in place", "oldTtl", previous.Ttl, "ttl", rs.Ttl)
+		}
+		previous = rs
+	}
+
+	lgr.Info("Test Passed: ttl")
+	return nil
+}
+
+// Checks the A record for hostname gets ttl within a sync interval and returns it. When previous is set the ttl is
+// changing, so the record set must exist with the name, fqdn and type of previous and hold target on every check. One
+// being removed before being created with the new ttl fails
+func validateTtl(ctx context.Context, in infra.Provisioned, private bool, hostname, target string, ttl int64, previous *clients.RecordSet) (*clients.RecordSet, error) {
+	lgr := logger.FromContext(ctx).With("ttl", ttl)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to validate record ttl")
+	defer lgr.Info("finished validating record ttl")
+
+	zone := testZone(in, private)
+	var ret *clients.RecordSet
+	var mismatch error
+	err := pollRecord(ctx, syncSeconds(in), fmt.Sprintf("A record %s with ttl %d", hostname, ttl), func(ctx context.Context) (bool, error) {
+		rs, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), zone, private, string(armdns.RecordTypeA), hostname)
+		if err != nil {
+			return false, fmt.Errorf("finding A record %s: %w", hostname, err)
+		}
+
+		if previous != nil {
+			if rs == nil {
+				return false, fmt.Errorf("A record %s wasn't updated in place, it was missing while its ttl changed", hostname)
+			}
+			if !strings.EqualFold(rs.Name, previous.Name) || !strings.EqualFold(rs.Fqdn, previous.Fqdn) || rs.Type != previous.Type {
+				return false, fmt.Errorf("A record %s wasn't updated in place, record set %s %s became %s %s while its ttl changed", hostname, previous.Type, previous.Fqdn, rs.Type, rs.Fqdn)
+			}
+		}
+
+		missing, extra := rs.Diff([]string{target})
+		if len(missing) > 0 || len(extra) > 0 {
+			if previous != nil {
+				return false, fmt.Errorf("A record %s wasn't updated in place, it held %v instead of %s while its ttl changed", hostname, recordValues(rs), target)
+			}
+			mismatch = fmt.Errorf("A record %s holds %v instead of %s", hostname, recordValues(rs), target)
+			return false, nil
+		}
+
+		if rs.Ttl != ttl {
+			mismatch = fmt.Errorf("A record %s has ttl %d", hostname, rs.Ttl)
+			return false, nil
+		}
+
+		ret = rs
+		return true, nil
+	})
+	if err != nil && mismatch != nil {
+		return nil, fmt.Errorf("%w: %s", err, mismatch)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Returns the values of rs, none when the record set doesn't exist
+func recordValues(rs *clients.RecordSet) []string {
+	if rs == nil {
+		return nil
+	}
+	return rs.Values
+}
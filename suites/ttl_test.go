@@ -0,0 +1,54 @@
+package suites
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/clients"
+)
+
+func TestValidateTtl(t *testing.T) {
+	in := newFakeProvisioned(t)
+	in.Cluster = clients.LoadLocal("fake", "", nil) // only read for the sync interval
+	putARecord(t, false, "ttl", "192.0.2.20")
+	hostname := "ttl." + testPublicZone
+
+	current, err := findRecord(context.Background(), testSubscriptionId, testResourceGroup, testPublicZone, false, "A", hostname)
+	if err != nil || current == nil {
+		t.Fatalf("finding record: %v %v", current, err)
+	}
+	replaced := *current
+	replaced.Fqdn = "other." + testPublicZone
+
+	tests := []struct {
+		name     string
+		hostname string
+		previous *clients.RecordSet
+		wantErr  string
+	}{
+		{name: "created", hostname: hostname},
+		{name: "updated in place", hostname: hostname, previous: current},
+		{name: "missing while changing", hostname: "gone." + testPublicZone, previous: current, wantErr: "it was missing"},
+		{name: "replaced while changing", hostname: hostname, previous: &replaced, wantErr: "record set A other." + testPublicZone + " became A ttl." + testPublicZone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// putARecord writes a 300 second ttl, so every check is decided on the first poll
+			rs, err := validateTtl(context.Background(), in, false, tt.hostname, "192.0.2.20", 300, tt.previous)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validating ttl returned %v, expected an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validating ttl: %s", err)
+			}
+			if rs == nil || rs.Ttl != 300 {
+				t.Errorf("validated record set %+v, expected a ttl of 300", rs)
+			}
+		})
+	}
+}
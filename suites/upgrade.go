@@ -0,0 +1,198 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	manifests "github.com/Azure/azure-provider-external-dns-e2e/pkgResources/pkgManifests"
+	"github.com/Azure/azure-provider-external-dns-e2e/tests"
+)
+
+// upgradeFromVersion is the external-dns release records are created with before upgrading to the deployed version
+const upgradeFromVersion = "v0.13.6"
+
+// Tests records created by an older external-dns are left alone by the deployed version after an upgrade. Existing
+// clusters keep their external-dns, and the service principal secret isn't saved to the infra file so external-dns
+// can't be redeployed with it, so neither get these tests. Local infras don't either, upgradeFromVersion predates the
+// webhook provider they use. The suite is exclusive since it replaces the external-dns every other test relies on
+func upgradeSuite(in infra.Provisioned) []test {
+	if in.Existing || in.ExternalDnsAuth == manifests.ServicePrincipalSecretAuth || in.FakeDnsUrl != "" {
+		return nil
+	}
+	if !newerVersion(in.DeployedExternalDnsVersion(), upgradeFromVersion) {
+		return nil
+	}
+
+	// one test covers both zones since the tests share the external-dns they redeploy
+	return []test{
+		{
+			name: "public and private DNS + external-dns upgrade",
+			run: func(ctx context.Context) error {
+				return upgradeTest(ctx, in)
+			},
+		},
+	}
+}
+
+// Deploys upgradeFromVersion, creates records in the public and private zone with it, then upgrades back to the deployed
+// version and checks neither the records nor their TXT registry records are rewritten or duplicated during the rollout
+// and the sync after it
+func upgradeTest(ctx context.Context, in infra.Provisioned) error {
+	lgr := logger.FromContext(ctx).With("from", upgradeFromVersion, "to", in.DeployedExternalDnsVersion())
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting upgrade test")
+
+	old := in
+	old.ExternalDnsVersion = upgradeFromVersion
+	if err := old.DeployExternalDns(ctx); err != nil {
+		return fmt.Errorf("deploying external-dns %s: %w", upgradeFromVersion, err)
+	}
+	upgraded := false
+	// exclusive tests run after this one against the same external-dns, so it's upgraded even when this test fails early
+	defer func() {
+		if !upgraded {
+			if err := in.DeployExternalDns(ctx); err != nil {
+				lgr.Error("failed to redeploy external-dns " + in.DeployedExternalDnsVersion() + ": " + err.Error())
+			}
+		}
+	}()
+
+	var records []upgradeRecord
+	for _, private := range []bool{false, true} {
+		zone := testZone(in, private)
+		name := tests.UniqueName("upgrade")
+		hostname := name + "." + zone
+
+		ip, err := newLifecycleService(ctx, in, name, private, hostname)
+		if err != nil {
+			return err
+		}
+		defer tests.DeleteTestService(ctx, in.Cluster, name)
+
+		if err := validateTxtOwner(ctx, in, private, zone, hostname, "A", in.Cluster.GetId(), 60); err != nil {
+			return fmt.Errorf("validating TXT registry records of %s before upgrade: %w", hostname, err)
+		}
+
+		etags, err := registeredEtags(ctx, in, private, zone, hostname, ip)
+		if err != nil {
+			return fmt.Errorf("getting records of %s before upgrade: %w", hostname, err)
+		}
+		records = append(records, upgradeRecord{private: private, zone: zone, hostname: hostname, ip: ip, etags: etags})
+	}
+
+	// checked while the pods roll as well as after, a duplicate written mid rollout could be cleaned up by the end
+	deployed := make(chan error, 1)
+	go func() {
+		deployed <- in.DeployExternalDns(ctx)
+	}()
+	upgraded = true
+
+	var deadline time.Time
+	for rolling := true; rolling || time.Now().Before(deadline); {
+		select {
+		case err := <-deployed:
+			if err != nil {
+				return fmt.Errorf("upgrading external-dns: %w", err)
+			}
+			rolling = false
+			// the upgraded external-dns has synced at least once by then
+			deadline = time.Now().Add(syncSeconds(in) * time.Second)
+		case <-time.After(2 * time.Second):
+		}
+
+		for _, record := range records {
+			if err := record.unchanged(ctx, in); err != nil {
+				return err
+			}
+		}
+	}
+
+	lgr.Info("Test Passed: external-dns upgrade")
+	return nil
+}
+
+// upgradeRecord is a record created before an upgrade and the etags of it and its TXT registry records
+type upgradeRecord struct {
+	private        bool
+	zone, hostname string
+	ip             string
+	etags          map[string]string
+}
+
+// Errors if the record or its TXT registry records were changed, removed or added to since they were created
+func (r upgradeRecord) unchanged(ctx context.Context, in infra.Provisioned) error {
+	etags, err := registeredEtags(ctx, in, r.private, r.zone, r.hostname, r.ip)
+	if err != nil {
+		return fmt.Errorf("getting records of %s during upgrade: %w", r.hostname, err)
+	}
+
+	for name, etag := range r.etags {
+		if etags[name] != etag {
+			return fmt.Errorf("record %s changed during upgrade, etag %s became %s", name, etag, etags[name])
+		}
+	}
+	for name := range etags {
+		if _, ok := r.etags[name]; !ok {
+			return fmt.Errorf("record %s was created during upgrade", name)
+		}
+	}
+
+	return nil
+}
+
+// Returns the etags of the A record with fqdn hostname and the TXT records registered beside it, keyed by record type
+// and name. Errors if the A record doesn't hold only ip or a TXT record holds more than one owner, which is how
+// duplicates show up since record sets are unique by name and type
+func registeredEtags(ctx context.Context, in infra.Provisioned, private bool, zone, hostname, ip string) (map[string]string, error) {
+	etags := make(map[string]string)
+
+	rs, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), zone, private, string(armdns.RecordTypeA), hostname)
+	if err != nil {
+		return nil, fmt.Errorf("finding A record %s: %w", hostname, err)
+	}
+	if missing, extra := rs.Diff([]string{ip}); len(missing) > 0 || len(extra) > 0 {
+		return nil, fmt.Errorf("A record %s doesn't hold only %s", hostname, ip)
+	}
+	etags["A "+hostname] = rs.Etag
+
+	for _, name := range txtRegistryNames(hostname, "A") {
+		rs, err := findRecord(ctx, in.SubscriptionId, in.ResourceGroup.GetName(), zone, private, string(armdns.RecordTypeTXT), name)
+		if err != nil {
+			return nil, fmt.Errorf("finding TXT record %s: %w", name, err)
+		}
+
+		if rs == nil {
+			continue
+		}
+		if len(rs.Values) != 1 {
+			return nil, fmt.Errorf("TXT record %s holds %d values", name, len(rs.Values))
+		}
+		etags["TXT "+name] = rs.Etag
+	}
+
+	return etags, nil
+}
+
+// Returns whether version a, like v0.14.0, is newer than version b
+func newerVersion(a, b string) bool {
+	var aMajor, aMinor, aPatch, bMajor, bMinor, bPatch int
+	if _, err := fmt.Sscanf(a, "v%d.%d.%d", &aMajor, &aMinor, &aPatch); err != nil {
+		return false
+	}
+	if _, err := fmt.Sscanf(b, "v%d.%d.%d", &bMajor, &bMinor, &bPatch); err != nil {
+		return false
+	}
+
+	if aMajor != bMajor {
+		return aMajor > bMajor
+	}
+	if aMinor != bMinor {
+		return aMinor > bMinor
+	}
+	return aPatch > bPatch
+}
@@ -0,0 +1,68 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	manifests "github.com/Azure/azure-provider-external-dns-e2e/pkgResources/pkgManifests"
+)
+
+// Tests external-dns is deployed to authenticate with workload identity and writes records with it. The auth mode is
+// chosen when the infra is provisioned, so only infras deployed in it get this suite
+func workloadIdentitySuite(in infra.Provisioned) []test {
+	if in.ExternalDnsAuth != manifests.WorkloadIdentityAuth {
+		return nil
+	}
+
+	return []test{
+		{
+			name: "workload identity + external-dns deployments",
+			run: func(ctx context.Context) error {
+				return validateWorkloadIdentity(ctx, in)
+			},
+		},
+		{
+			name: "workload identity + public and private DNS A Records",
+			run: func(ctx context.Context) error {
+				return authRecordsTest(ctx, in)
+			},
+		},
+	}
+}
+
+// Checks external-dns writes A records in the public and private zone with the auth mode of in. Auth suites run it
+// so each auth mode has a record test of its own, whichever other suites run against the infra
+func authRecordsTest(ctx context.Context, in infra.Provisioned) error {
+	if err := ARecordTest(ctx, in); err != nil {
+		return fmt.Errorf("public A record with %s auth: %w", in.ExternalDnsAuth, err)
+	}
+	if err := PrivateARecordTest(ctx, in); err != nil {
+		return fmt.Errorf("private A record with %s auth: %w", in.ExternalDnsAuth, err)
+	}
+
+	return nil
+}
+
+// Checks the external-dns pods are labeled for workload identity, which is what gets them a projected token of the
+// federated identity
+func validateWorkloadIdentity(ctx context.Context, in infra.Provisioned) error {
+	lgr := logger.FromContext(ctx)
+	lgr.Info("starting to validate external-dns uses workload identity")
+	defer lgr.Info("finished validating external-dns uses workload identity")
+
+	for _, provider := range manifests.Providers {
+		deployment, err := in.Cluster.GetDeployment(ctx, "kube-system", provider.ResourceName())
+		if err != nil {
+			return fmt.Errorf("getting deployment %s: %w", provider.ResourceName(), err)
+		}
+
+		if deployment.Spec.Template.Labels[manifests.WorkloadIdentityUseLabel] != "true" {
+			return fmt.Errorf("pods of deployment %s aren't labeled %s", provider.ResourceName(), manifests.WorkloadIdentityUseLabel)
+		}
+	}
+
+	lgr.Info("Test Passed: external-dns uses workload identity")
+	return nil
+}
@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stage is a point a record reaches on its way from the annotation of a service to being served
+type Stage string
+
+const (
+	// ArmStage is the record being returned by the arm api of its zone
+	ArmStage Stage = "arm"
+	// ResolvableStage is the record being answered by the nameservers of its zone
+	ResolvableStage Stage = "resolvable"
+	// ConvergedStage is every record of a batch of services deployed together being returned by the arm api
+	ConvergedStage Stage = "converged"
+)
+
+var stages = []Stage{ArmStage, ResolvableStage, ConvergedStage}
+
+// upper bounds of the histogram buckets, external-dns syncs every few minutes so most records land in the later ones
+var latencyBuckets = []time.Duration{
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	3 * time.Minute,
+	4 * time.Minute,
+	5 * time.Minute,
+	7 * time.Minute,
+	10 * time.Minute,
+}
+
+// Latency is how long a record took to reach a stage after the service publishing it was annotated
+type Latency struct {
+	Record    string        `json:"record"`
+	Stage     Stage         `json:"stage"`
+	Annotated time.Time     `json:"annotated"`
+	Reached   time.Time     `json:"reached"`
+	Latency   time.Duration `json:"latency"`
+}
+
+type latencyKey struct{}
+
+// latencies collects the latencies observed by one test, tests may observe records concurrently
+type latencies struct {
+	mu        sync.Mutex
+	latencies []Latency
+}
+
+func withLatencies(ctx context.Context, l *latencies) context.Context {
+	return context.WithValue(ctx, latencyKey{}, l)
+}
+
+// ObserveLatency records that record reached stage at reached, measured from when its service was annotated. The
+// latency is added to the result of the test running with ctx, it's dropped outside of a test
+func ObserveLatency(ctx context.Context, record string, stage Stage, annotated, reached time.Time) {
+	l, ok := ctx.Value(latencyKey{}).(*latencies)
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.latencies = append(l.latencies, Latency{
+		Record:    record,
+		Stage:     stage,
+		Annotated: annotated,
+		Reached:   reached,
+		Latency:   reached.Sub(annotated),
+	})
+}
+
+// Bucket counts the latencies up to Le, buckets are cumulative and the last one has no upper bound
+type Bucket struct {
+	Le    time.Duration `json:"le,omitempty"`
+	Count int           `json:"count"`
+}
+
+// Histogram summarizes the latencies of a stage across every test run against one infrastructure
+type Histogram struct {
+	Infra   string        `json:"infra"`
+	Stage   Stage         `json:"stage"`
+	Count   int           `json:"count"`
+	Min     time.Duration `json:"min"`
+	Max     time.Duration `json:"max"`
+	P50     time.Duration `json:"p50"`
+	P95     time.Duration `json:"p95"`
+	Buckets []Bucket      `json:"buckets"`
+}
+
+// Histograms returns a histogram for every infrastructure and stage with latencies, in the order infrastructures first
+// appear in results
+func (r Results) Histograms() []Histogram {
+	var histograms []Histogram
+	for _, summary := range r.ByInfra() {
+		for _, stage := range stages {
+			var observed []time.Duration
+			for _, result := range r {
+				if result.Infra != summary.Infra {
+					continue
+				}
+				for _, l := range result.Latencies {
+					if l.Stage == stage {
+						observed = append(observed, l.Latency)
+					}
+				}
+			}
+			if len(observed) == 0 {
+				continue
+			}
+
+			histograms = append(histograms, newHistogram(summary.Infra, stage, observed))
+		}
+	}
+
+	return histograms
+}
+
+func newHistogram(infra string, stage Stage, observed []time.Duration) Histogram {
+	sort.Slice(observed, func(i, j int) bool { return observed[i] < observed[j] })
+
+	h := Histogram{
+		Infra: infra,
+		Stage: stage,
+		Count: len(observed),
+		Min:   observed[0],
+		Max:   observed[len(observed)-1],
+		P50:   percentile(observed, 50),
+		P95:   percentile(observed, 95),
+	}
+
+	for _, le := range latencyBuckets {
+		count := sort.Search(len(observed), func(i int) bool { return observed[i] > le })
+		h.Buckets = append(h.Buckets, Bucket{Le: le, Count: count})
+	}
+	h.Buckets = append(h.Buckets, Bucket{Count: len(observed)})
+
+	return h
+}
+
+// percentile returns the nearest rank percentile p of sorted
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// WriteLatencyJson writes the latency histograms of results as a json array to file
+func (r Results) WriteLatencyJson(file string) error {
+	histograms := r.Histograms()
+	if histograms == nil {
+		histograms = []Histogram{}
+	}
+
+	bytes, err := json.MarshalIndent(histograms, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling latency histograms: %w", err)
+	}
+
+	if err := writeFile(file, bytes); err != nil {
+		return fmt.Errorf("writing latency histograms to %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// Slos are the highest 95th percentile latency allowed for each stage
+type Slos map[Stage]time.Duration
+
+// ParseSlos parses thresholds formatted as stage=duration, like arm=4m. Empty thresholds are skipped
+func ParseSlos(thresholds []string) (Slos, error) {
+	slos := Slos{}
+	for _, threshold := range thresholds {
+		if threshold == "" {
+			continue
+		}
+
+		stage, value, ok := strings.Cut(threshold, "=")
+		if !ok {
+			return nil, fmt.Errorf("latency slo %s isn't formatted as stage=duration", threshold)
+		}
+
+		known := false
+		for _, s := range stages {
+			if Stage(stage) == s {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("latency slo %s has unknown stage %s, expected one of %v", threshold, stage, stages)
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing latency slo %s: %w", threshold, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("latency slo %s must be positive", threshold)
+		}
+		slos[Stage(stage)] = d
+	}
+
+	return slos, nil
+}
+
+// Check returns a result for every infrastructure of results and every stage with an slo, failed when the 95th
+// percentile of the stage is over the slo or when no latencies of the stage were observed, since a missing measurement
+// can't be within the slo. The results belong to a "latency slo" suite so they're reported and counted like tests
+func (s Slos) Check(results Results) Results {
+	type key struct {
+		infra string
+		stage Stage
+	}
+	histograms := map[key]Histogram{}
+	for _, h := range results.Histograms() {
+		histograms[key{h.Infra, h.Stage}] = h
+	}
+
+	var ret Results
+	for _, summary := range results.ByInfra() {
+		for _, stage := range stages {
+			slo, ok := s[stage]
+			if !ok {
+				continue
+			}
+
+			result := Result{
+				Name:   fmt.Sprintf("%s p95 latency within %s", stage, slo),
+				Suite:  "latency slo",
+				Infra:  summary.Infra,
+				Start:  time.Now(),
+				Status: Passed,
+			}
+			h, ok := histograms[key{summary.Infra, stage}]
+			switch {
+			case !ok:
+				result.Status = Failed
+				result.Error = fmt.Sprintf("no %s latencies were observed to check against the slo of %s", stage, slo)
+			case h.P95 > slo:
+				result.Status = Failed
+				result.Error = fmt.Sprintf("%s p95 latency %s over %d records is over the slo of %s", stage, h.P95, h.Count, slo)
+			}
+			ret = append(ret, result)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSlos(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds []string
+		want       Slos
+		wantErr    string
+	}{
+		{name: "none", want: Slos{}},
+		{name: "empty skipped", thresholds: []string{""}, want: Slos{}},
+		{name: "one", thresholds: []string{"arm=4m"}, want: Slos{ArmStage: 4 * time.Minute}},
+		{
+			name:       "every stage",
+			thresholds: []string{"arm=6m", "resolvable=7m30s", "converged=10m"},
+			want:       Slos{ArmStage: 6 * time.Minute, ResolvableStage: 7*time.Minute + 30*time.Second, ConvergedStage: 10 * time.Minute},
+		},
+		{name: "later wins", thresholds: []string{"arm=4m", "arm=5m"}, want: Slos{ArmStage: 5 * time.Minute}},
+		{name: "no separator", thresholds: []string{"arm"}, wantErr: "isn't formatted as stage=duration"},
+		{name: "unknown stage", thresholds: []string{"dns=4m"}, wantErr: "unknown stage dns"},
+		{name: "bad duration", thresholds: []string{"arm=4"}, wantErr: "parsing latency slo"},
+		{name: "zero", thresholds: []string{"arm=0s"}, wantErr: "must be positive"},
+		{name: "negative", thresholds: []string{"arm=-1m"}, wantErr: "must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSlos(tt.thresholds)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parsing returned %v, expected an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsing: %s", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, expected %v", got, tt.want)
+			}
+			for stage, d := range tt.want {
+				if got[stage] != d {
+					t.Errorf("stage %s is %s, expected %s", stage, got[stage], d)
+				}
+			}
+		})
+	}
+}
+
+func seconds(values ...int) []time.Duration {
+	ret := make([]time.Duration, len(values))
+	for i, v := range values {
+		ret[i] = time.Duration(v) * time.Second
+	}
+	return ret
+}
+
+func TestNewHistogram(t *testing.T) {
+	tests := []struct {
+		name     string
+		observed []time.Duration
+		wantMin  time.Duration
+		wantMax  time.Duration
+		wantP50  time.Duration
+		wantP95  time.Duration
+	}{
+		{name: "one", observed: seconds(42), wantMin: 42 * time.Second, wantMax: 42 * time.Second, wantP50: 42 * time.Second, wantP95: 42 * time.Second},
+		{name: "two", observed: seconds(20, 10), wantMin: 10 * time.Second, wantMax: 20 * time.Second, wantP50: 10 * time.Second, wantP95: 20 * time.Second},
+		{name: "three", observed: seconds(30, 10, 20), wantMin: 10 * time.Second, wantMax: 30 * time.Second, wantP50: 20 * time.Second, wantP95: 30 * time.Second},
+		{name: "four", observed: seconds(40, 10, 30, 20), wantMin: 10 * time.Second, wantMax: 40 * time.Second, wantP50: 20 * time.Second, wantP95: 40 * time.Second},
+		{
+			// the 95th percentile of 20 samples is the 19th, so one outlier doesn't count
+			name:     "outlier",
+			observed: seconds(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 600),
+			wantMin:  time.Second,
+			wantMax:  600 * time.Second,
+			wantP50:  10 * time.Second,
+			wantP95:  19 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHistogram("infra", ArmStage, tt.observed)
+
+			if h.Count != len(tt.observed) {
+				t.Errorf("count is %d, expected %d", h.Count, len(tt.observed))
+			}
+			if h.Min != tt.wantMin || h.Max != tt.wantMax {
+				t.Errorf("min and max are %s and %s, expected %s and %s", h.Min, h.Max, tt.wantMin, tt.wantMax)
+			}
+			if h.P50 != tt.wantP50 || h.P95 != tt.wantP95 {
+				t.Errorf("p50 and p95 are %s and %s, expected %s and %s", h.P50, h.P95, tt.wantP50, tt.wantP95)
+			}
+
+			last := h.Buckets[len(h.Buckets)-1]
+			if last.Le != 0 || last.Count != len(tt.observed) {
+				t.Errorf("last bucket is %+v, expected an unbounded bucket of every sample", last)
+			}
+			for i := 1; i < len(h.Buckets); i++ {
+				if h.Buckets[i].Count < h.Buckets[i-1].Count {
+					t.Errorf("bucket %d counts %d, fewer than the %d of the bucket before it", i, h.Buckets[i].Count, h.Buckets[i-1].Count)
+				}
+			}
+		})
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	// 15s is inclusive, 16s falls in the 30s bucket and 11m only in the unbounded one
+	h := newHistogram("infra", ArmStage, seconds(15, 16, 660))
+
+	want := map[time.Duration]int{
+		15 * time.Second: 1,
+		30 * time.Second: 2,
+		10 * time.Minute: 2,
+		0:                3,
+	}
+	for _, b := range h.Buckets {
+		if count, ok := want[b.Le]; ok && b.Count != count {
+			t.Errorf("bucket up to %s counts %d, expected %d", b.Le, b.Count, count)
+		}
+	}
+}
+
+func TestSlosCheck(t *testing.T) {
+	start := time.Now()
+	latency := func(stage Stage, d time.Duration) Latency {
+		return Latency{Record: "A www", Stage: stage, Annotated: start, Reached: start.Add(d), Latency: d}
+	}
+
+	results := Results{
+		{Name: "a", Suite: "public dns", Infra: "basic", Latencies: []Latency{latency(ArmStage, time.Minute), latency(ResolvableStage, 2*time.Minute)}},
+		{Name: "b", Suite: "public dns", Infra: "basic", Latencies: []Latency{latency(ArmStage, 5*time.Minute)}},
+		{Name: "a", Suite: "public dns", Infra: "private", Latencies: []Latency{latency(ArmStage, 2*time.Minute)}},
+	}
+	slos := Slos{ArmStage: 4 * time.Minute, ResolvableStage: 3 * time.Minute}
+
+	checked := slos.Check(results)
+
+	type key struct {
+		infra string
+		name  string
+	}
+	got := map[key]Result{}
+	for _, r := range checked {
+		if r.Suite != "latency slo" {
+			t.Errorf("result %s is in suite %s, expected latency slo", r.Name, r.Suite)
+		}
+		got[key{r.Infra, r.Name}] = r
+	}
+
+	want := map[key]Status{
+		{"basic", "arm p95 latency within 4m0s"}:          Failed,
+		{"basic", "resolvable p95 latency within 3m0s"}:   Passed,
+		{"private", "arm p95 latency within 4m0s"}:        Passed,
+		{"private", "resolvable p95 latency within 3m0s"}: Failed,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, expected %d: %+v", len(got), len(want), checked)
+	}
+	for k, status := range want {
+		r, ok := got[k]
+		if !ok {
+			t.Errorf("no result %s for %s", k.name, k.infra)
+			continue
+		}
+		if r.Status != status {
+			t.Errorf("result %s for %s is %s, expected %s", k.name, k.infra, r.Status, status)
+		}
+	}
+
+	if r := got[key{"basic", "arm p95 latency within 4m0s"}]; !strings.Contains(r.Error, "over the slo") {
+		t.Errorf("slo over the p95 has error %q", r.Error)
+	}
+	// private observed no resolvable latencies, which fails the slo rather than passing it unchecked
+	if r := got[key{"private", "resolvable p95 latency within 3m0s"}]; !strings.Contains(r.Error, "no resolvable latencies were observed") {
+		t.Errorf("slo without latencies has error %q", r.Error)
+	}
+
+	// stages without an slo aren't checked
+	if checked := (Slos{}).Check(results); len(checked) != 0 {
+		t.Errorf("got %d results without slos, expected none", len(checked))
+	}
+}
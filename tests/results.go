@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Status is the outcome of a single test
+type Status string
+
+const (
+	Passed Status = "passed"
+	Failed Status = "failed"
+)
+
+// Result records the outcome of running one test against one infrastructure
+type Result struct {
+	Name     string        `json:"name"`
+	Suite    string        `json:"suite"`
+	Infra    string        `json:"infra"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Status   Status        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	// Latencies are how long the records the test published took to reach each stage
+	Latencies []Latency `json:"latencies,omitempty"`
+}
+
+// Results is a slice of Result
+type Results []Result
+
+// Failed returns the number of failed tests
+func (r Results) Failed() int {
+	failed := 0
+	for _, result := range r {
+		if result.Status == Failed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// InfraSummary counts the tests run against one infrastructure
+type InfraSummary struct {
+	Infra  string
+	Tests  int
+	Failed int
+}
+
+// ByInfra returns a summary for every infrastructure in results, in the order they first appear
+func (r Results) ByInfra() []InfraSummary {
+	var summaries []InfraSummary
+	index := map[string]int{}
+
+	for _, result := range r {
+		i, ok := index[result.Infra]
+		if !ok {
+			i = len(summaries)
+			index[result.Infra] = i
+			summaries = append(summaries, InfraSummary{Infra: result.Infra})
+		}
+
+		summaries[i].Tests++
+		if result.Status == Failed {
+			summaries[i].Failed++
+		}
+	}
+
+	return summaries
+}
+
+// WriteJson writes results as a json array to file
+func (r Results) WriteJson(file string) error {
+	if r == nil {
+		r = Results{}
+	}
+
+	bytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling results: %w", err)
+	}
+
+	if err := writeFile(file, bytes); err != nil {
+		return fmt.Errorf("writing results to %s: %w", file, err)
+	}
+
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJunit writes results as JUnit XML to file, with one testsuite per infrastructure and suite pair
+func (r Results) WriteJunit(file string) error {
+	root := junitTestSuites{}
+	index := map[string]int{}
+
+	for _, result := range r {
+		name := result.Infra + "/" + result.Suite
+		i, ok := index[name]
+		if !ok {
+			i = len(root.Suites)
+			index[name] = i
+			root.Suites = append(root.Suites, junitTestSuite{
+				Name:      name,
+				Timestamp: result.Start.UTC().Format(time.RFC3339),
+			})
+		}
+
+		tc := junitTestCase{
+			Name:      result.Name,
+			Classname: name,
+			Time:      result.Duration.Seconds(),
+		}
+		if result.Status == Failed {
+			tc.Failure = &junitFailure{Message: result.Error, Text: result.Error}
+			root.Suites[i].Failures++
+			root.Failures++
+		}
+
+		root.Suites[i].Cases = append(root.Suites[i].Cases, tc)
+		root.Suites[i].Tests++
+		root.Suites[i].Time += tc.Time
+		root.Tests++
+		root.Time += tc.Time
+	}
+
+	bytes, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling junit results: %w", err)
+	}
+
+	if err := writeFile(file, append([]byte(xml.Header), bytes...)); err != nil {
+		return fmt.Errorf("writing junit results to %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// writeFile creates any missing parent directories so results can be collected into a folder
+func writeFile(file string, bytes []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	return os.WriteFile(file, bytes, 0644)
+}
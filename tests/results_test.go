@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteJunit(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	results := Results{
+		{Name: "A Record", Suite: "public dns", Infra: "basic", Start: start, Duration: 2 * time.Second, Status: Passed},
+		{Name: "A Record", Suite: "private dns", Infra: "basic", Start: start, Duration: time.Second, Status: Failed, Error: "record not created"},
+		{Name: "A Record", Suite: "public dns", Infra: "private", Start: start, Duration: 3 * time.Second, Status: Passed},
+		{Name: "AAAA Record", Suite: "public dns", Infra: "basic", Start: start, Duration: 4 * time.Second, Status: Passed},
+	}
+
+	// a missing directory is created
+	file := filepath.Join(t.TempDir(), "results", "junit.xml")
+	if err := results.WriteJunit(file); err != nil {
+		t.Fatalf("writing junit: %s", err)
+	}
+
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("reading junit: %s", err)
+	}
+	var root junitTestSuites
+	if err := xml.Unmarshal(bytes, &root); err != nil {
+		t.Fatalf("unmarshalling junit: %s", err)
+	}
+
+	if root.Tests != 4 || root.Failures != 1 || root.Time != 10 {
+		t.Errorf("testsuites has %d tests, %d failures and time %v, expected 4, 1 and 10", root.Tests, root.Failures, root.Time)
+	}
+
+	// a testsuite per infra and suite, in the order they first appear
+	want := []struct {
+		name     string
+		cases    []string
+		failures int
+		time     float64
+	}{
+		{name: "basic/public dns", cases: []string{"A Record", "AAAA Record"}, time: 6},
+		{name: "basic/private dns", cases: []string{"A Record"}, failures: 1, time: 1},
+		{name: "private/public dns", cases: []string{"A Record"}, time: 3},
+	}
+	if len(root.Suites) != len(want) {
+		t.Fatalf("got %d testsuites, expected %d", len(root.Suites), len(want))
+	}
+	for i, w := range want {
+		s := root.Suites[i]
+		if s.Name != w.name {
+			t.Errorf("testsuite %d is %s, expected %s", i, s.Name, w.name)
+			continue
+		}
+		if s.Tests != len(w.cases) || s.Failures != w.failures || s.Time != w.time {
+			t.Errorf("testsuite %s has %d tests, %d failures and time %v, expected %d, %d and %v", s.Name, s.Tests, s.Failures, s.Time, len(w.cases), w.failures, w.time)
+		}
+		if s.Timestamp != "2024-01-02T03:04:05Z" {
+			t.Errorf("testsuite %s has timestamp %s", s.Name, s.Timestamp)
+		}
+
+		for j, c := range s.Cases {
+			if j >= len(w.cases) || c.Name != w.cases[j] {
+				t.Errorf("testsuite %s case %d is %s, expected %v", s.Name, j, c.Name, w.cases)
+				continue
+			}
+			if c.Classname != w.name {
+				t.Errorf("case %s has classname %s, expected %s", c.Name, c.Classname, w.name)
+			}
+			if failed := c.Failure != nil; failed != (w.failures > 0) {
+				t.Errorf("case %s of %s failed is %t", c.Name, s.Name, failed)
+			}
+		}
+	}
+
+	if f := root.Suites[1].Cases[0].Failure; f != nil && f.Message != "record not created" {
+		t.Errorf("failure message is %q, expected the test error", f.Message)
+	}
+}
+
+func TestByInfra(t *testing.T) {
+	results := Results{
+		{Infra: "basic", Status: Passed},
+		{Infra: "private", Status: Failed},
+		{Infra: "basic", Status: Failed},
+		{Infra: "basic", Status: Passed},
+	}
+
+	got := results.ByInfra()
+	want := []InfraSummary{
+		{Infra: "basic", Tests: 3, Failed: 1},
+		{Infra: "private", Tests: 1, Failed: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, expected %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("summary %d is %+v, expected %+v", i, got[i], want[i])
+		}
+	}
+	if failed := results.Failed(); failed != 2 {
+		t.Errorf("%d failed, expected 2", failed)
+	}
+}
@@ -2,79 +2,51 @@ package tests
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
-	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
 	"github.com/go-logr/logr"
-	corev1 "k8s.io/api/core/v1"
+	"golang.org/x/sync/errgroup"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/Azure/azure-provider-external-dns-e2e/infra"
 	"github.com/Azure/azure-provider-external-dns-e2e/logger"
 )
 
-// global exported vars used by tests
-var (
-	ClusterName   *string
-	Ipv4Service   *corev1.Service
-	Ipv6Service   *corev1.Service
-	PublicZone    string
-	PrivateZone   string
-	ResourceGroup string
-	SubId         string
-)
-
 func init() {
 	log.SetLogger(logr.New(log.NullLogSink{})) // without this controller-runtime panics. We use it solely for the client so we can ignore logs
 
 }
 
-// Sets variables used by all tests
-func SetObjectsForTesting(ctx context.Context, infra infra.Provisioned) error {
+// Checks the infrastructure has what every test relies on, the nginx services and a public and private zone. Tests
+// read everything else they need from the infrastructure they're given so several can be tested at once
+func CheckObjectsForTesting(ctx context.Context, infra infra.Provisioned) error {
 	lgr := logger.FromContext(ctx)
-	lgr.Info("Setting objects for testing")
-
-	//setting exported vars used by all tests
-	cluster, err := infra.Cluster.GetCluster(ctx)
-	if err != nil {
-		lgr.Error("Error getting name from cluster")
-		return fmt.Errorf("error getting name from cluster")
-	}
-	ClusterName = cluster.Name
-
-	ipv4Svc, err := getServiceObj(ctx, infra.SubscriptionId, infra.ResourceGroup.GetName(), *ClusterName, infra.Ipv4ServiceName)
-	if err != nil {
-		lgr.Error("Error getting service object")
-		return fmt.Errorf("error getting service object")
-	}
-	Ipv4Service = ipv4Svc
+	lgr.Info("checking objects for testing")
 
-	ipv6Svc, err := getServiceObj(ctx, infra.SubscriptionId, infra.ResourceGroup.GetName(), *ClusterName, infra.Ipv6ServiceName)
-	if err != nil {
-		lgr.Error("Error getting service object")
-		return fmt.Errorf("error getting service object")
+	for _, name := range []string{infra.Ipv4ServiceName, infra.Ipv6ServiceName} {
+		if _, err := infra.Cluster.GetService(ctx, namespace, name); err != nil {
+			return fmt.Errorf("getting service %s: %w", name, err)
+		}
 	}
-	Ipv6Service = ipv6Svc
 
-	for _, zone := range infra.Zones {
-		PublicZone = zone.GetName()
+	if len(infra.Zones) == 0 {
+		return fmt.Errorf("infrastructure %s has no public zone", infra.Name)
 	}
-
-	for _, zone := range infra.PrivateZones {
-		PrivateZone = zone.GetName()
+	if len(infra.PrivateZones) == 0 {
+		return fmt.Errorf("infrastructure %s has no private zone", infra.Name)
 	}
 
-	ResourceGroup = infra.ResourceGroup.GetName()
-	SubId = infra.SubscriptionId
-
 	return nil
 }
 
-func (allTests Ts) Run(ctx context.Context, infra infra.Provisioned) error {
+// Runs every test of every suite with at most workers tests at a time and returns a result for each in suite order.
+// Tests own their services and hostnames so they can run concurrently, a failed test doesn't stop the others. Exclusive
+// tests run one at a time after every other test has finished, since they change what the other tests rely on
+func RunSuites(ctx context.Context, infra infra.Provisioned, suites []Suite, workers int) Results {
 	lgr := logger.FromContext(ctx)
-	lgr.Info("Starting to run all tests in suite")
+	lgr.Info("Starting to run all tests in suites", "workers", workers)
+	defer lgr.Info("finished running all tests in suites")
 
 	runTestFn := func(t test, ctx context.Context) *logger.LoggedError {
 		lgr := logger.FromContext(ctx).With("test", t.GetName())
@@ -89,43 +61,61 @@ func (allTests Ts) Run(ctx context.Context, infra infra.Provisioned) error {
 		return nil
 	}
 
-	//Loop to run ALL Tests
-	lgr.Info("starting to run tests")
-
-	for _, t := range allTests {
-		func(t test) error {
-			if err := runTestFn(t, ctx); err != nil {
-				return fmt.Errorf("running test: %w", err)
-			}
-			return nil
-		}(t)
+	var results Results
+	for _, s := range suites {
+		for _, t := range s.Tests {
+			results = append(results, Result{
+				Name:  t.GetName(),
+				Suite: s.Name,
+				Infra: infra.Name,
+			})
+		}
 	}
-	return nil
-}
 
-func getServiceObj(ctx context.Context, subId, rg, clusterName, serviceName string) (*corev1.Service, error) {
-	lgr := logger.FromContext(ctx).With("name", clusterName, "resourceGroup", rg)
-	ctx = logger.WithContext(ctx, lgr)
-	lgr.Info("retrieving service object")
-	defer lgr.Info("finished getting service")
-
-	cmd := fmt.Sprintf("kubectl get service %s -n kube-system -o json", serviceName)
-	resultProperties, err := RunCommand(ctx, subId, rg, clusterName, armcontainerservice.RunCommandRequest{
-		Command: to.Ptr(cmd),
-	}, runCommandOpts{})
-
-	if err != nil {
-		return nil, fmt.Errorf("error getting service %s", serviceName)
+	runFn := func(ctx context.Context, result *Result, t test) {
+		l := &latencies{}
+		result.Start = time.Now()
+		result.Status = Passed
+		if err := runTestFn(t, withLatencies(ctx, l)); err != nil {
+			result.Status = Failed
+			result.Error = err.Error()
+		}
+		result.Duration = time.Since(result.Start)
+		result.Latencies = l.latencies
 	}
-	responseLog := *resultProperties.Logs
 
-	svcObj := &corev1.Service{}
-	err = json.Unmarshal([]byte(responseLog), svcObj)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling json for service: %s", err)
+	if workers < 1 {
+		workers = 1
 	}
+	var eg errgroup.Group
+	eg.SetLimit(workers)
+
+	var exclusive []func()
+	i := 0
+	for _, s := range suites {
+		ctx := logger.WithContext(ctx, lgr.With("suite", s.Name))
+		for _, t := range s.Tests {
+			result, t := &results[i], t
+			i++
+
+			if s.exclusive(t) {
+				exclusive = append(exclusive, func() { runFn(ctx, result, t) })
+				continue
+			}
+			eg.Go(func() error {
+				runFn(ctx, result, t)
+				return nil
+			})
+		}
+	}
+	eg.Wait()
 
-	//success
-	return svcObj, nil
+	if len(exclusive) > 0 {
+		lgr.Info("running exclusive tests one at a time", "tests", len(exclusive))
+	}
+	for _, run := range exclusive {
+		run()
+	}
 
+	return results
 }
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-provider-external-dns-e2e/infra"
+)
+
+// fakeTest records when it ran and how many tests were running alongside it
+type fakeTest struct {
+	name      string
+	exclusive bool
+	fail      bool
+	tracker   *runTracker
+}
+
+type runTracker struct {
+	mu      sync.Mutex
+	running int
+	order   []string
+	overlap map[string]int // most other tests running at once with each test
+}
+
+func (f fakeTest) GetName() string { return f.name }
+
+func (f fakeTest) Exclusive() bool { return f.exclusive }
+
+func (f fakeTest) Run(ctx context.Context) error {
+	tr := f.tracker
+	tr.mu.Lock()
+	tr.running++
+	tr.order = append(tr.order, f.name)
+	tr.mu.Unlock()
+
+	// long enough for concurrent tests to overlap
+	for i := 0; i < 5; i++ {
+		tr.mu.Lock()
+		if others := tr.running - 1; others > tr.overlap[f.name] {
+			tr.overlap[f.name] = others
+		}
+		tr.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	tr.mu.Lock()
+	tr.running--
+	tr.mu.Unlock()
+
+	if f.fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func TestRunSuitesExclusive(t *testing.T) {
+	tr := &runTracker{overlap: map[string]int{}}
+	suites := []Suite{
+		{Name: "concurrent", Tests: Ts{
+			fakeTest{name: "a", tracker: tr},
+			fakeTest{name: "rotate", exclusive: true, tracker: tr},
+			fakeTest{name: "b", tracker: tr, fail: true},
+		}},
+		{Name: "upgrade", Exclusive: true, Tests: Ts{
+			fakeTest{name: "upgrade", tracker: tr},
+		}},
+		{Name: "more", Tests: Ts{
+			fakeTest{name: "c", tracker: tr},
+			fakeTest{name: "d", tracker: tr},
+		}},
+	}
+
+	results := RunSuites(context.Background(), infra.Provisioned{Name: "fake"}, suites, 4)
+
+	for _, name := range []string{"rotate", "upgrade"} {
+		if tr.overlap[name] != 0 {
+			t.Errorf("exclusive test %s ran alongside %d others", name, tr.overlap[name])
+		}
+	}
+	if tr.overlap["a"] == 0 {
+		t.Error("concurrent tests didn't run concurrently")
+	}
+
+	// exclusive tests run after the others, in suite order
+	if n := len(tr.order); n != 6 || tr.order[n-2] != "rotate" || tr.order[n-1] != "upgrade" {
+		t.Errorf("tests ran in order %v, expected rotate then upgrade last", tr.order)
+	}
+
+	// results stay in suite order whenever the tests ran
+	wantNames := []string{"a", "rotate", "b", "upgrade", "c", "d"}
+	if len(results) != len(wantNames) {
+		t.Fatalf("got %d results, expected %d", len(results), len(wantNames))
+	}
+	for i, name := range wantNames {
+		if results[i].Name != name {
+			t.Errorf("result %d is %s, expected %s", i, results[i].Name, name)
+		}
+		wantStatus := Passed
+		if name == "b" {
+			wantStatus = Failed
+		}
+		if results[i].Status != wantStatus {
+			t.Errorf("result %s is %s, expected %s", name, results[i].Status, wantStatus)
+		}
+	}
+}
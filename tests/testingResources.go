@@ -3,20 +3,18 @@ package tests
 import (
 	"context"
 	_ "embed"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"os"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
-	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
-	appsv1 "k8s.io/api/apps/v1"
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/Azure/azure-provider-external-dns-e2e/clients"
 	"github.com/Azure/azure-provider-external-dns-e2e/logger"
+	"github.com/Azure/azure-provider-external-dns-e2e/manifests"
 )
 
 type IpFamily string
@@ -30,206 +28,209 @@ const (
 	Txt   IpFamily = "TXT"
 )
 
-var nonZeroExitCode = errors.New("non-zero exit code")
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
 
-type runCommandOpts struct {
-	// outputFile is the file to write the output of the command to. Useful for saving logs from a job or something similar
-	// where there's lots of logs that are extremely important and shouldn't be muddled up in the rest of the logs.
-	outputFile string
-}
+// namespace every test object lives in
+const namespace = "kube-system"
 
-func AnnotateService(ctx context.Context, subId, clusterName, rg, serviceName string, annMap map[string]string) error {
-	lgr := logger.FromContext(ctx).With("name", clusterName, "resourceGroup", rg)
+func AnnotateService(ctx context.Context, c Cluster, serviceName string, annMap map[string]string) error {
+	lgr := logger.FromContext(ctx).With("name", c.GetName())
 	ctx = logger.WithContext(ctx, lgr)
 	lgr.Info("starting to Annotate service")
 	defer lgr.Info("finished annotating service")
 
-	for key, value := range annMap {
-		cmd := fmt.Sprintf("kubectl annotate service --overwrite %s %s=%s -n kube-system", serviceName, key, value)
-
-		if _, err := RunCommand(ctx, subId, rg, clusterName, armcontainerservice.RunCommandRequest{
-			Command: to.Ptr(cmd),
-		}, runCommandOpts{}); err != nil {
-			return fmt.Errorf("running kubectl apply: %w", err)
-		}
+	if err := c.AnnotateService(ctx, namespace, serviceName, annMap); err != nil {
+		return fmt.Errorf("annotating service %s: %w", serviceName, err)
 	}
 
 	return nil
 
 }
 
+// Removes the annotations with keys from a service, leaving the rest in place
+func RemoveAnnotations(ctx context.Context, c Cluster, serviceName string, keys ...string) error {
+	lgr := logger.FromContext(ctx).With("name", c.GetName())
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to remove annotations from service")
+	defer lgr.Info("finished removing annotations from service")
+
+	if err := c.RemoveServiceAnnotations(ctx, namespace, serviceName, keys); err != nil {
+		return fmt.Errorf("removing annotations from service %s: %w", serviceName, err)
+	}
+
+	return nil
+}
+
 // Removes all annotations except for last-applied-configuration which is needed by kubectl apply
 // Called before test exits to clean up resources
-func ClearAnnotations(ctx context.Context, subId, clusterName, rg, serviceName string) error {
-	lgr := logger.FromContext(ctx).With("name", clusterName, "resourceGroup", rg)
+func ClearAnnotations(ctx context.Context, c Cluster, serviceName string) error {
+	lgr := logger.FromContext(ctx).With("name", c.GetName())
 	ctx = logger.WithContext(ctx, lgr)
 	lgr.Info("starting to clear annotations")
 	defer lgr.Info("finished removing all annotations on service")
 
-	serviceObj, err := getServiceObj(ctx, subId, rg, clusterName, serviceName)
+	serviceObj, err := c.GetService(ctx, namespace, serviceName)
 	if err != nil {
-		return fmt.Errorf("error getting service object before clearing annotations")
+		return fmt.Errorf("error getting service object before clearing annotations: %w", err)
 	}
 
-	annotations := serviceObj.Annotations
-	for key := range annotations {
-		if key != "kubectl.kubernetes.io/last-applied-configuration" {
-			cmd := fmt.Sprintf("kubectl annotate service %s %s -n kube-system", serviceName, key+"-")
+	keys := []string{}
+	for key := range serviceObj.Annotations {
+		if key != lastAppliedAnnotation {
+			keys = append(keys, key)
+		}
+	}
 
-			if _, err := RunCommand(ctx, subId, rg, clusterName, armcontainerservice.RunCommandRequest{
-				Command: to.Ptr(cmd),
-			}, runCommandOpts{}); err != nil {
-				return fmt.Errorf("running kubectl apply: %w", err)
-			}
+	if len(keys) > 0 {
+		if err := c.RemoveServiceAnnotations(ctx, namespace, serviceName, keys); err != nil {
+			return fmt.Errorf("removing annotations from service %s: %w", serviceName, err)
 		}
 	}
 
-	serviceObj, err = getServiceObj(ctx, subId, rg, clusterName, serviceName)
+	serviceObj, err = c.GetService(ctx, namespace, serviceName)
 	if err != nil {
-		return fmt.Errorf("error getting service object after annotating")
+		return fmt.Errorf("error getting service object after annotating: %w", err)
 	}
 
 	//check that only last-applied-configuration annotation is left
-	if len(serviceObj.Annotations) == 1 {
-		lgr.Info("Cleared annotations successfully")
-		return nil
-	} else {
-		return fmt.Errorf("service annotations not cleared")
+	for key := range serviceObj.Annotations {
+		if key != lastAppliedAnnotation {
+			return fmt.Errorf("service annotations not cleared")
+		}
 	}
 
+	lgr.Info("Cleared annotations successfully")
+	return nil
 }
 
 // Checks to see that external dns pod is running
-func WaitForExternalDns(ctx context.Context, numSeconds time.Duration, subId, rg, clusterName, provider string) error {
-	lgr := logger.FromContext(ctx).With("name", clusterName, "resourceGroup", rg)
+func WaitForExternalDns(ctx context.Context, c Cluster, numSeconds time.Duration, provider string) error {
+	lgr := logger.FromContext(ctx).With("name", c.GetName())
 	ctx = logger.WithContext(ctx, lgr)
 	lgr.Info("Checking/ Waiting for external dns pod to run")
 	defer lgr.Info("Done waiting for external dns pod")
 
-	cmd := fmt.Sprintf("kubectl get deploy %s -n kube-system -o json", provider)
-	resultProperties, err := RunCommand(ctx, subId, rg, clusterName, armcontainerservice.RunCommandRequest{
-		Command: to.Ptr(cmd),
-	}, runCommandOpts{})
-
-	if err != nil {
-		return fmt.Errorf("unable to get pod for %s deployment", provider)
-	}
-
-	responseLog := *resultProperties.Logs
-	deploy := &appsv1.Deployment{}
-	err = json.Unmarshal([]byte(responseLog), deploy)
-	if err != nil {
-
-		return fmt.Errorf("error with unmarshaling json")
-	}
-
-	var extDNSReady bool = true
 	timeout := time.Now().Add(numSeconds * time.Second)
-	if deploy.Status.AvailableReplicas < 1 {
-		var i int = 0
-		for deploy.Status.AvailableReplicas < 1 {
-			if time.Now().After(timeout) {
-				return fmt.Errorf("external DNS deployment not ready after %s seconds", numSeconds)
-			}
-			lgr.Info("======= ExternalDNS not available, checking again in %s seconds ====", timeout)
-			time.Sleep(2 * time.Second)
-			i++
-
-			if i >= 5 {
-				extDNSReady = false
-			}
+	for {
+		deploy, err := c.GetDeployment(ctx, namespace, provider)
+		if err != nil {
+			return fmt.Errorf("unable to get %s deployment: %w", provider, err)
 		}
-	}
 
-	if extDNSReady {
-		lgr.Info("External Dns deployment is running and ready")
-		return nil
-	} else {
-		return fmt.Errorf("external dns deployment is not running in pod, check logs")
-	}
+		if deploy.Status.AvailableReplicas >= 1 {
+			lgr.Info("External Dns deployment is running and ready")
+			return nil
+		}
 
+		if time.Now().After(timeout) {
+			return fmt.Errorf("external DNS deployment not ready after %d seconds", numSeconds)
+		}
+		lgr.Info("======= ExternalDNS not available, checking again in 2 seconds ====")
+		time.Sleep(2 * time.Second)
+	}
 }
 
-// Adds annotations needed specifically for private dns tests
-func PrivateDnsAnnotations(ctx context.Context, subId, clusterName, rg, serviceName string) error {
-	lgr := logger.FromContext(ctx)
-	lgr.Info("Adding annotations for private dns")
-
-	annotationMap := map[string]string{
-		"external-dns.alpha.kubernetes.io/hostname":               PrivateZone,
+// Returns annotations needed specifically for private dns tests, publishing hostname through an internal load balancer
+func PrivateDnsAnnotations(hostname string) map[string]string {
+	return map[string]string{
+		"external-dns.alpha.kubernetes.io/hostname":               hostname,
 		"service.beta.kubernetes.io/azure-load-balancer-internal": "true",
 		"external-dns.alpha.kubernetes.io/internal-hostname":      "server-clusterip.example.com",
 	}
-	err := AnnotateService(ctx, subId, clusterName, rg, serviceName, annotationMap)
-	if err != nil {
-		lgr.Error("Error annotating service to create internal load balancer ", err)
-		return fmt.Errorf("error: %s", err)
-	}
+}
 
-	return nil
+// UniqueName returns prefix with a random suffix. Tests use it to name their own service and record so they can run concurrently
+func UniqueName(prefix string) string {
+	return prefix + "-" + uuid.NewString()[:8]
+}
 
+// Creates a load balancer service in front of nginx for a single test and waits for it to be assigned an ip
+func NewTestService(ctx context.Context, c Cluster, name string, ipFamily IpFamily, annotations map[string]string, numSeconds time.Duration) (*corev1.Service, error) {
+	svc, _, err := NewTimedTestService(ctx, c, name, ipFamily, annotations, numSeconds)
+	return svc, err
 }
 
-func RunCommand(ctx context.Context, subId, rg, clusterName string, request armcontainerservice.RunCommandRequest, opt runCommandOpts) (armcontainerservice.CommandResultProperties, error) {
-	lgr := logger.FromContext(ctx)
+// Creates a test service like NewTestService and also returns the local time it was applied, which record latencies are
+// measured from
+func NewTimedTestService(ctx context.Context, c Cluster, name string, ipFamily IpFamily, annotations map[string]string, numSeconds time.Duration) (*corev1.Service, time.Time, error) {
+	lgr := logger.FromContext(ctx).With("name", c.GetName(), "service", name)
 	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to create test service")
+	defer lgr.Info("finished creating test service")
 
-	lgr.Info("starting to run command")
-	defer lgr.Info("finished running command for testing")
-
-	emptyResp := &armcontainerservice.CommandResultProperties{}
-	cred, err := clients.GetAzCred()
-	if err != nil {
-		return *emptyResp, fmt.Errorf("getting az credentials: %w", err)
+	svc := clients.NewNginxService(name, corev1.IPFamily(ipFamily), annotations)
+	if err := c.Deploy(ctx, []client.Object{svc}); err != nil {
+		return nil, time.Time{}, fmt.Errorf("deploying service %s: %w", name, err)
 	}
+	applied := time.Now()
 
-	client, err := armcontainerservice.NewManagedClustersClient(subId, cred, nil)
-	if err != nil {
-		return *emptyResp, fmt.Errorf("creating aks client: %w", err)
-	}
+	svc, err := WaitForServiceIp(ctx, c, name, numSeconds)
+	return svc, applied, err
+}
 
-	poller, err := client.BeginRunCommand(ctx, rg, clusterName, request, nil)
-	if err != nil {
-		return *emptyResp, fmt.Errorf("starting run command: %w", err)
-	}
+// Waits for a load balancer service to be assigned an ip and returns it
+func WaitForServiceIp(ctx context.Context, c Cluster, name string, numSeconds time.Duration) (*corev1.Service, error) {
+	timeout := time.Now().Add(numSeconds * time.Second)
+	for {
+		svc, err := c.GetService(ctx, namespace, name)
+		if err != nil {
+			return nil, fmt.Errorf("getting service %s: %w", name, err)
+		}
 
-	result, err := poller.PollUntilDone(ctx, nil)
-	if err != nil {
-		return *emptyResp, fmt.Errorf("running command: %w", err)
+		if len(svc.Status.LoadBalancer.Ingress) > 0 && svc.Status.LoadBalancer.Ingress[0].IP != "" {
+			return svc, nil
+		}
+
+		if time.Now().After(timeout) {
+			return nil, fmt.Errorf("service %s not assigned an ip after %d seconds", name, numSeconds)
+		}
+		time.Sleep(5 * time.Second)
 	}
+}
 
-	logs := ""
-	if result.Properties != nil && result.Properties.Logs != nil {
-		logs = *result.Properties.Logs
+// Deletes a service created with NewTestService, called before a test exits
+func DeleteTestService(ctx context.Context, c Cluster, name string) error {
+	if err := c.Delete(ctx, clients.NewNginxService(name, "", nil)); err != nil {
+		return fmt.Errorf("deleting test service %s: %w", name, err)
 	}
 
-	if opt.outputFile != "" {
+	return nil
+}
 
-		outputFile, err := os.OpenFile(opt.outputFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-		if err != nil {
+// Runs the embedded client as a job in the cluster, resolving the host of url through nameserver and sending a request to
+// it. Returns an error when the job fails, its logs are written to job-<name>.log. The job is deleted before returning
+func RunClientJob(ctx context.Context, c Cluster, name, nameserver, url string) error {
+	lgr := logger.FromContext(ctx).With("name", c.GetName(), "job", name, "nameserver", nameserver, "url", url)
+	ctx = logger.WithContext(ctx, lgr)
+	lgr.Info("starting to run client job")
+	defer lgr.Info("finished running client job")
 
-			return *result.Properties, fmt.Errorf("creating output file %s: %w", opt.outputFile, err)
+	objs := manifests.ClientJob(name, namespace, nameserver, url)
+	defer func() {
+		for _, obj := range objs {
+			c.Delete(ctx, obj)
 		}
-		defer outputFile.Close()
-		_, err = outputFile.WriteString(logs)
-		if err != nil {
-			return *result.Properties, fmt.Errorf("writing output file %s: %w", opt.outputFile, err)
-		}
-	} else {
-		lgr.Info("using command logs, no output file specified")
+	}()
+
+	if err := c.Deploy(ctx, objs); err != nil {
+		return fmt.Errorf("running client job %s: %w", name, err)
 	}
 
-	if *result.Properties.ExitCode != 0 {
-		lgr.Info(fmt.Sprintf("command failed with exit code %d", *result.Properties.ExitCode))
-		return *result.Properties, nonZeroExitCode
+	return nil
+}
+
+// Deletes an ingress created by a test, called before a test exits
+func DeleteTestIngress(ctx context.Context, c Cluster, name string) error {
+	if err := c.Delete(ctx, clients.NewNginxIngress(name, "", nil, nil)); err != nil {
+		return fmt.Errorf("deleting test ingress %s: %w", name, err)
 	}
 
-	return *result.Properties, nil
+	return nil
 }
 
 // Deletes a record set in a public dns zone or private dns zone in Azure DNS
-// Called after each test to allow subsequent test to run properly
-func DeleteRecordSet(ctx context.Context, clusterName, subId, rg, zoneName string, recordType armdns.RecordType, privateRecordType armprivatedns.RecordType) error {
+// Called after each test to clean up the records it created
+func DeleteRecordSet(ctx context.Context, clusterName, subId, rg, zoneName, recordName string, recordType armdns.RecordType, privateRecordType armprivatedns.RecordType) error {
 	lgr := logger.FromContext(ctx)
 
 	lgr.Info("Starting to delete record set")
@@ -242,23 +243,23 @@ func DeleteRecordSet(ctx context.Context, clusterName, subId, rg, zoneName strin
 	}
 
 	if recordType != "" {
-		clientFactory, err := armdns.NewClientFactory(subId, cred, nil)
+		clientFactory, err := armdns.NewClientFactory(subId, cred, clients.GetClientOptions())
 		if err != nil {
 			lgr.Error("failed to create client ", err)
 			return err
 		}
-		_, err = clientFactory.NewRecordSetsClient().Delete(ctx, rg, zoneName, "@", recordType, &armdns.RecordSetsClientDeleteOptions{IfMatch: nil})
+		_, err = clientFactory.NewRecordSetsClient().Delete(ctx, rg, zoneName, recordName, recordType, &armdns.RecordSetsClientDeleteOptions{IfMatch: nil})
 		if err != nil {
 			lgr.Error("failed to delete record set in public dns zone ", err)
 			return err
 		}
 	} else { //delete a private record set
-		privateClientFactory, err := armprivatedns.NewClientFactory(subId, cred, nil)
+		privateClientFactory, err := armprivatedns.NewClientFactory(subId, cred, clients.GetClientOptions())
 		if err != nil {
 			lgr.Error("failed to create client", err)
 			return err
 		}
-		_, err = privateClientFactory.NewRecordSetsClient().Delete(ctx, rg, zoneName, privateRecordType, "@", &armprivatedns.RecordSetsClientDeleteOptions{IfMatch: nil})
+		_, err = privateClientFactory.NewRecordSetsClient().Delete(ctx, rg, zoneName, privateRecordType, recordName, &armprivatedns.RecordSetsClientDeleteOptions{IfMatch: nil})
 		if err != nil {
 			lgr.Error("failed to delete record set in private dns zone ", err)
 			return err
@@ -2,8 +2,24 @@ package tests
 
 import (
 	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// Cluster is the part of a provisioned cluster test helpers use to read and annotate objects
+type Cluster interface {
+	GetName() string
+	Deploy(ctx context.Context, objs []client.Object) error
+	Delete(ctx context.Context, obj client.Object) error
+	DeleteAllOf(ctx context.Context, obj client.Object, labels map[string]string) error
+	GetService(ctx context.Context, namespace, name string) (*corev1.Service, error)
+	GetDeployment(ctx context.Context, namespace, name string) (*appsv1.Deployment, error)
+	AnnotateService(ctx context.Context, namespace, name string, annotations map[string]string) error
+	RemoveServiceAnnotations(ctx context.Context, namespace, name string, keys []string) error
+}
+
 type test interface {
 	GetName() string
 	Run(ctx context.Context) error
@@ -16,3 +32,25 @@ type T interface {
 
 // Ts is a slice of T
 type Ts []T
+
+// Exclusive is implemented by tests that change something other tests rely on, like the deployed external-dns. Those
+// run alone once every other test has finished
+type Exclusive interface {
+	Exclusive() bool
+}
+
+// Suite is a named group of tests, the name is used in results. Every test of an Exclusive suite runs alone
+type Suite struct {
+	Name      string
+	Tests     Ts
+	Exclusive bool
+}
+
+// exclusive returns whether t of s must run alone
+func (s Suite) exclusive(t test) bool {
+	if s.Exclusive {
+		return true
+	}
+	e, ok := t.(Exclusive)
+	return ok && e.Exclusive()
+}